@@ -36,6 +36,7 @@ export type AdminConnectRequest = {
 	signature: string;
 	clientInfo?: {
 		displayName?: string;
+		appVersion?: string;
 	};
 };
 
@@ -50,6 +51,7 @@ export type ConnectFinishRequest = {
 	signature: string;
 	clientInfo?: {
 		displayName?: string;
+		appVersion?: string;
 	};
 };
 
@@ -203,6 +205,10 @@ export function connectBegin(inviteId: string, baseUrl?: string): Promise<Connec
 	});
 }
 
+// Reported at connect so servers with MIN_CLIENT_VERSION can turn away outdated clients.
+// Keep in sync with package.json.
+export const CLIENT_APP_VERSION = '0.1.0';
+
 export function connectFinish(
 	request: ConnectFinishRequest,
 	baseUrl?: string
@@ -211,7 +217,7 @@ export function connectFinish(
 		baseUrl,
 		path: '/api/connect/finish',
 		method: 'POST',
-		body: request
+		body: { ...request, clientInfo: { appVersion: CLIENT_APP_VERSION, ...request.clientInfo } }
 	});
 }
 
@@ -223,7 +229,7 @@ export function connectAdmin(
 		baseUrl,
 		path: '/api/connect/admin',
 		method: 'POST',
-		body: request
+		body: { ...request, clientInfo: { appVersion: CLIENT_APP_VERSION, ...request.clientInfo } }
 	});
 }
 
@@ -77,10 +77,14 @@ export async function fingerprintFromPublicKey(publicKeyBase64: string): Promise
 		throw new Error('public key must be 32 bytes (Ed25519)');
 	}
 
+	// Mirrors the server: each emoji is reduced from its own 8-byte slice of the hash.
 	const hash = await sha256Bytes(publicKey);
-	const parts = Array.from(hash.slice(0, 4)).map(
-		(value) => fingerprintEmojis[value % fingerprintEmojis.length]
-	);
+	const view = new DataView(hash.buffer, hash.byteOffset, hash.byteLength);
+	const parts: string[] = [];
+	for (let i = 0; i < 4; i += 1) {
+		const value = view.getBigUint64(i * 8);
+		parts.push(fingerprintEmojis[Number(value % BigInt(fingerprintEmojis.length))]);
+	}
 	return parts.join('');
 }
 
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"fosscord/apps/server/internal/config"
@@ -13,7 +16,13 @@ import (
 
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	cfg := config.Load()
+	slog.SetDefault(logger)
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
 	state, err := serverstate.New(cfg)
 	if err != nil {
 		logger.Error("failed to initialize server state", "error", err)
@@ -25,12 +34,19 @@ func main() {
 		"data_dir", cfg.DataDir,
 		"db_path_set", cfg.DatabasePath != "",
 		"web_dist_dir_set", cfg.WebDistDir != "",
-		"admin_token_set", cfg.AdminToken != "",
+		"admin_tokens", len(cfg.AdminTokens),
 		"livekit_url_set", cfg.LiveKitURL != "",
 		"livekit_api_key_set", cfg.LiveKitAPIKey != "",
 		"livekit_api_secret_set", cfg.LiveKitAPISecret != "",
 	)
 
+	sqliteSettings := state.SQLiteSettings()
+	logger.Info("sqlite settings",
+		"busy_timeout_ms", sqliteSettings.BusyTimeoutMS,
+		"cache_size", sqliteSettings.CacheSize,
+		"mmap_size", sqliteSettings.MMapSize,
+	)
+
 	router := httpapi.NewRouter(cfg, state)
 	srv := &http.Server{
 		Addr:              cfg.Addr,
@@ -38,8 +54,38 @@ func main() {
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logger.Error("server exited", "error", err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("server exited", "error", err)
+			os.Exit(1)
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+	// Channel streams are hijacked connections that srv.Shutdown does not track,
+	// so they are told to close before the listener stops.
+	state.CloseAllStreams()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("graceful shutdown failed", "error", err)
+	}
+	if err := state.WaitForStreams(shutdownCtx); err != nil {
+		logger.Error("channel streams did not finish", "error", err)
+	}
+	if err := state.Close(); err != nil {
+		logger.Error("failed to close database", "error", err)
 	}
 }
@@ -30,6 +30,7 @@ type serverInfoResponse struct {
 	ServerPublicKey           string   `json:"serverPublicKey"`
 	LiveKitURL                string   `json:"livekitUrl"`
 	AdminPublicKeys           []string `json:"adminPublicKeys"`
+	DefaultChannelID          string   `json:"defaultChannelId"`
 }
 
 type createInviteRequest struct {
@@ -112,8 +113,9 @@ type mutateMessageResponse struct {
 }
 
 type apiErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId"`
 }
 
 type liveKitTokenRequest struct {
@@ -134,6 +136,7 @@ type voiceTouchRequest struct {
 	CameraEnabled      bool   `json:"cameraEnabled"`
 	ScreenEnabled      bool   `json:"screenEnabled"`
 	ScreenAudioEnabled bool   `json:"screenAudioEnabled"`
+	Status             string `json:"status,omitempty"`
 }
 
 type voiceParticipant struct {
@@ -147,6 +150,7 @@ type voiceParticipant struct {
 	CameraEnabled      bool   `json:"cameraEnabled"`
 	ScreenEnabled      bool   `json:"screenEnabled"`
 	ScreenAudioEnabled bool   `json:"screenAudioEnabled"`
+	Status             string `json:"status"`
 }
 
 type voiceStateResponse struct {
@@ -199,6 +203,9 @@ func TestServerInfo(t *testing.T) {
 	if parsed.AdminPublicKeys == nil {
 		t.Fatal("expected 'adminPublicKeys' to be present (possibly empty array)")
 	}
+	if strings.TrimSpace(parsed.DefaultChannelID) == "" {
+		t.Fatal("expected non-empty 'defaultChannelId'")
+	}
 }
 
 func TestConnectHandshakeSuccess(t *testing.T) {
@@ -330,6 +337,54 @@ func TestConnectInvalidSignature(t *testing.T) {
 	}
 }
 
+func TestInvalidRequestFields(t *testing.T) {
+	t.Parallel()
+
+	baseURL := apiBaseURL()
+
+	body := requestJSON(t, http.MethodPost, baseURL+"/api/connect/begin", nil, map[string]string{
+		"inviteId": "missing",
+		"extra":    "value",
+	}, http.StatusBadRequest)
+
+	var unknownField struct {
+		Error   string `json:"error"`
+		Details struct {
+			Field  string `json:"field"`
+			Reason string `json:"reason"`
+		} `json:"details"`
+	}
+	mustParseJSON(t, body, &unknownField)
+	if unknownField.Error != "invalid_field" || unknownField.Details.Field != "extra" || unknownField.Details.Reason != "unknown_field" {
+		t.Fatalf("unexpected unknown field error: %s", string(body))
+	}
+
+	body = requestJSON(t, http.MethodPost, baseURL+"/api/connect/begin", nil, map[string]int{
+		"inviteId": 42,
+	}, http.StatusBadRequest)
+
+	var typeMismatch struct {
+		Error   string `json:"error"`
+		Details struct {
+			Field    string `json:"field"`
+			Reason   string `json:"reason"`
+			Expected string `json:"expected"`
+		} `json:"details"`
+	}
+	mustParseJSON(t, body, &typeMismatch)
+	if typeMismatch.Error != "invalid_field" || typeMismatch.Details.Field != "inviteId" || typeMismatch.Details.Reason != "type_mismatch" || typeMismatch.Details.Expected != "string" {
+		t.Fatalf("unexpected type mismatch error: %s", string(body))
+	}
+
+	body = requestJSON(t, http.MethodPost, baseURL+"/api/connect/begin", nil, []string{"not", "an", "object"}, http.StatusBadRequest)
+
+	var malformed apiErrorResponse
+	mustParseJSON(t, body, &malformed)
+	if malformed.Error != "invalid_json" {
+		t.Fatalf("unexpected malformed body error: got=%q want=%q", malformed.Error, "invalid_json")
+	}
+}
+
 func TestTextMessagesCreateListEdit(t *testing.T) {
 	t.Parallel()
 
@@ -403,6 +458,56 @@ func TestTextMessagesCreateListEdit(t *testing.T) {
 	if edited.Message.UpdatedAt < edited.Message.CreatedAt {
 		t.Fatalf("updatedAt must not be earlier than createdAt: createdAt=%q updatedAt=%q", edited.Message.CreatedAt, edited.Message.UpdatedAt)
 	}
+
+	getBody := requestJSON(t, http.MethodGet, baseURL+"/api/channels/"+textChannelID+"/messages/"+created.Message.ID, map[string]string{
+		"Authorization": "Bearer " + finish.SessionToken,
+	}, nil, http.StatusOK)
+
+	var fetched mutateMessageResponse
+	mustParseJSON(t, getBody, &fetched)
+	if fetched.Message.ID != created.Message.ID || fetched.Message.ContentMarkdown != "Edited message" {
+		t.Fatalf("unexpected fetched message: %+v", fetched.Message)
+	}
+
+	requestJSON(t, http.MethodGet, baseURL+"/api/channels/"+textChannelID+"/messages/missing", map[string]string{
+		"Authorization": "Bearer " + finish.SessionToken,
+	}, nil, http.StatusNotFound)
+}
+
+func TestChannelLongPoll(t *testing.T) {
+	t.Parallel()
+
+	baseURL := apiBaseURL()
+	session := createConnectedClientSession(t, baseURL)
+	headers := map[string]string{"Authorization": "Bearer " + session.Finish.SessionToken}
+
+	var first, second mutateMessageResponse
+	mustParseJSON(t, requestJSON(t, http.MethodPost, baseURL+"/api/channels/general/messages", headers,
+		mutateMessageRequest{ContentMarkdown: "poll anchor"}, http.StatusOK), &first)
+	mustParseJSON(t, requestJSON(t, http.MethodPost, baseURL+"/api/channels/general/messages", headers,
+		mutateMessageRequest{ContentMarkdown: "poll missed"}, http.StatusOK), &second)
+
+	pollBody := requestJSON(t, http.MethodGet, baseURL+"/api/channels/general/poll?timeout=0&since="+first.Message.ID, headers, nil, http.StatusOK)
+
+	var polled struct {
+		Events []struct {
+			Type    string `json:"type"`
+			Message struct {
+				ID string `json:"id"`
+			} `json:"message"`
+		} `json:"events"`
+	}
+	mustParseJSON(t, pollBody, &polled)
+	if len(polled.Events) == 0 || polled.Events[0].Type != "message.created" || polled.Events[0].Message.ID != second.Message.ID {
+		t.Fatalf("expected missed message as first event: %s", string(pollBody))
+	}
+
+	timeoutBody := requestJSON(t, http.MethodGet, baseURL+"/api/channels/general/poll?timeout=0", headers, nil, http.StatusOK)
+	if !strings.Contains(string(timeoutBody), `"events":[`) {
+		t.Fatalf("expected events array on timeout: %s", string(timeoutBody))
+	}
+
+	requestJSON(t, http.MethodGet, baseURL+"/api/channels/general/poll?timeout=soon", headers, nil, http.StatusBadRequest)
 }
 
 func TestVoiceTokenAndPresence(t *testing.T) {
@@ -463,6 +568,23 @@ func TestVoiceTokenAndPresence(t *testing.T) {
 		t.Fatal("expected at least one participant in voice state")
 	}
 
+	minimalBody := requestJSON(t, http.MethodGet, baseURL+"/api/livekit/voice/channels/"+voiceChannelID+"/state?fields=minimal", map[string]string{
+		"Authorization": "Bearer " + finish.SessionToken,
+	}, nil, http.StatusOK)
+
+	var minimal struct {
+		Participants []map[string]any `json:"participants"`
+	}
+	mustParseJSON(t, minimalBody, &minimal)
+	for _, participant := range minimal.Participants {
+		if _, ok := participant["audioStreams"]; ok {
+			t.Fatalf("minimal voice state must not include stream counts: %s", string(minimalBody))
+		}
+		if participant["publicKey"] == session.ClientPublicKey && participant["muted"] != false {
+			t.Fatalf("expected participant with audio to be unmuted: %s", string(minimalBody))
+		}
+	}
+
 	found := false
 	for _, participant := range state.Participants {
 		if participant.PublicKey != session.ClientPublicKey {
@@ -484,6 +606,216 @@ func TestVoiceTokenAndPresence(t *testing.T) {
 	}
 }
 
+func TestVoiceTokenRejectsInvalidChannels(t *testing.T) {
+	t.Parallel()
+
+	baseURL := apiBaseURL()
+	session := createConnectedClientSession(t, baseURL)
+	authHeaders := map[string]string{"Authorization": "Bearer " + session.Finish.SessionToken}
+
+	cases := []struct {
+		channelID string
+		status    int
+		code      string
+	}{
+		{channelID: "general", status: http.StatusBadRequest, code: "invalid_channel_type"},
+		{channelID: "does-not-exist", status: http.StatusNotFound, code: "channel_not_found"},
+	}
+	for _, tc := range cases {
+		body := requestJSON(t, http.MethodPost, baseURL+"/api/livekit/token", authHeaders, map[string]string{"channelId": tc.channelID}, tc.status)
+
+		var apiErr apiErrorResponse
+		mustParseJSON(t, body, &apiErr)
+		if apiErr.Error != tc.code {
+			t.Fatalf("channel %q: unexpected error code: got=%q want=%q", tc.channelID, apiErr.Error, tc.code)
+		}
+		if strings.Contains(string(body), `"token"`) {
+			t.Fatalf("channel %q: token must not be issued: %s", tc.channelID, string(body))
+		}
+	}
+}
+
+func TestVoiceOwnState(t *testing.T) {
+	t.Parallel()
+
+	baseURL := apiBaseURL()
+	session := createConnectedClientSession(t, baseURL)
+	finish := session.Finish
+	authHeaders := map[string]string{"Authorization": "Bearer " + finish.SessionToken}
+
+	voiceChannelID := ""
+	for _, ch := range finish.Channels {
+		if ch.Type == "voice" {
+			voiceChannelID = ch.ID
+			break
+		}
+	}
+	if voiceChannelID == "" {
+		t.Fatal("expected at least one voice channel")
+	}
+
+	missingBody := requestJSON(t, http.MethodGet, baseURL+"/api/livekit/voice/me", authHeaders, nil, http.StatusNotFound)
+
+	var apiErr apiErrorResponse
+	mustParseJSON(t, missingBody, &apiErr)
+	if apiErr.Error != "not_in_voice" {
+		t.Fatalf("unexpected error code: got=%q want=%q", apiErr.Error, "not_in_voice")
+	}
+
+	_ = requestJSON(t, http.MethodPost, baseURL+"/api/livekit/voice/touch", authHeaders, voiceTouchRequest{
+		ChannelID:     voiceChannelID,
+		AudioStreams:  1,
+		CameraEnabled: true,
+		Status:        "  brb  ",
+	}, http.StatusOK)
+
+	meBody := requestJSON(t, http.MethodGet, baseURL+"/api/livekit/voice/me", authHeaders, nil, http.StatusOK)
+
+	var me struct {
+		Participant voiceParticipant `json:"participant"`
+	}
+	mustParseJSON(t, meBody, &me)
+	if me.Participant.PublicKey != session.ClientPublicKey {
+		t.Fatalf("unexpected participant: got=%q want=%q", me.Participant.PublicKey, session.ClientPublicKey)
+	}
+	if me.Participant.ChannelID != voiceChannelID {
+		t.Fatalf("unexpected channel id: got=%q want=%q", me.Participant.ChannelID, voiceChannelID)
+	}
+	if !me.Participant.CameraEnabled {
+		t.Fatal("expected cameraEnabled=true")
+	}
+	if me.Participant.Status != "brb" {
+		t.Fatalf("expected trimmed status, got %q", me.Participant.Status)
+	}
+}
+
+func TestMemberLeaveInvalidatesSession(t *testing.T) {
+	t.Parallel()
+
+	baseURL := apiBaseURL()
+	session := createConnectedClientSession(t, baseURL)
+	authHeaders := map[string]string{"Authorization": "Bearer " + session.Finish.SessionToken}
+
+	body := requestJSON(t, http.MethodPost, baseURL+"/api/members/me/leave", authHeaders, map[string]bool{"purgeMessages": true}, http.StatusOK)
+
+	var left struct {
+		Status string `json:"status"`
+	}
+	mustParseJSON(t, body, &left)
+	if left.Status != "left" {
+		t.Fatalf("unexpected leave status: got=%q want=%q", left.Status, "left")
+	}
+
+	textChannelID := ""
+	for _, ch := range session.Finish.Channels {
+		if ch.Type == "text" {
+			textChannelID = ch.ID
+			break
+		}
+	}
+
+	errBody := requestJSON(t, http.MethodGet, baseURL+"/api/channels/"+textChannelID+"/messages", authHeaders, nil, http.StatusUnauthorized)
+
+	var apiErr apiErrorResponse
+	mustParseJSON(t, errBody, &apiErr)
+	if apiErr.Error != "invalid_session_token" {
+		t.Fatalf("unexpected error code: got=%q want=%q", apiErr.Error, "invalid_session_token")
+	}
+	if apiErr.RequestID == "" {
+		t.Fatal("expected error response to carry requestId")
+	}
+}
+
+func TestAdminCreateMember(t *testing.T) {
+	t.Parallel()
+
+	baseURL := apiBaseURL()
+	adminHeaders := map[string]string{"Authorization": "Bearer " + adminToken()}
+	clientPublicB64, _ := generateClientKeypair(t)
+
+	createReq := map[string]any{
+		"publicKey":         clientPublicB64,
+		"displayName":       "imported-member",
+		"issueSessionToken": true,
+	}
+	body := requestJSON(t, http.MethodPost, baseURL+"/api/admin/members", adminHeaders, createReq, http.StatusOK)
+
+	var created struct {
+		Member struct {
+			PublicKey   string `json:"publicKey"`
+			DisplayName string `json:"displayName"`
+		} `json:"member"`
+		SessionToken string `json:"sessionToken"`
+	}
+	mustParseJSON(t, body, &created)
+	if created.Member.PublicKey != clientPublicB64 || created.Member.DisplayName != "imported-member" {
+		t.Fatalf("unexpected created member: %s", string(body))
+	}
+	if strings.TrimSpace(created.SessionToken) == "" {
+		t.Fatal("expected session token for imported member")
+	}
+
+	_ = requestJSON(t, http.MethodGet, baseURL+"/api/channels/general/messages", map[string]string{
+		"Authorization": "Bearer " + created.SessionToken,
+	}, nil, http.StatusOK)
+
+	body = requestJSON(t, http.MethodPost, baseURL+"/api/admin/members", adminHeaders, createReq, http.StatusConflict)
+
+	var apiErr apiErrorResponse
+	mustParseJSON(t, body, &apiErr)
+	if apiErr.Error != "member_exists" {
+		t.Fatalf("unexpected error code: got=%q want=%q", apiErr.Error, "member_exists")
+	}
+}
+
+func TestMessageReports(t *testing.T) {
+	t.Parallel()
+
+	baseURL := apiBaseURL()
+	author := createConnectedClientSession(t, baseURL)
+	reporter := createConnectedClientSession(t, baseURL)
+	reporterHeaders := map[string]string{"Authorization": "Bearer " + reporter.Finish.SessionToken}
+
+	var created mutateMessageResponse
+	mustParseJSON(t, requestJSON(t, http.MethodPost, baseURL+"/api/channels/general/messages", map[string]string{
+		"Authorization": "Bearer " + author.Finish.SessionToken,
+	}, mutateMessageRequest{ContentMarkdown: "reportable content"}, http.StatusOK), &created)
+
+	reportURL := baseURL + "/api/channels/general/messages/" + created.Message.ID + "/report"
+	for i := 0; i < 2; i++ {
+		requestJSON(t, http.MethodPost, reportURL, reporterHeaders, map[string]string{"reason": "spam"}, http.StatusOK)
+	}
+	requestJSON(t, http.MethodPost, baseURL+"/api/channels/general/messages/missing/report", reporterHeaders, nil, http.StatusNotFound)
+
+	body := requestJSON(t, http.MethodGet, baseURL+"/api/admin/reports?limit=200", map[string]string{
+		"Authorization": "Bearer " + adminToken(),
+	}, nil, http.StatusOK)
+
+	var listed struct {
+		Reports []struct {
+			MessageID         string `json:"messageId"`
+			ReporterPublicKey string `json:"reporterPublicKey"`
+			Reason            string `json:"reason"`
+			ContentSnapshot   string `json:"contentSnapshot"`
+		} `json:"reports"`
+	}
+	mustParseJSON(t, body, &listed)
+
+	matches := 0
+	for _, report := range listed.Reports {
+		if report.MessageID != created.Message.ID {
+			continue
+		}
+		matches++
+		if report.ReporterPublicKey != reporter.ClientPublicKey || report.Reason != "spam" || report.ContentSnapshot != "reportable content" {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+	}
+	if matches != 1 {
+		t.Fatalf("expected exactly one deduplicated report, got %d: %s", matches, string(body))
+	}
+}
+
 func createConnectedClientSession(t *testing.T, baseURL string) connectedSession {
 	t.Helper()
 
@@ -1,6 +1,16 @@
 package config
 
-import "os"
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
 
 type Config struct {
 	Addr                      string
@@ -9,30 +19,378 @@ type Config struct {
 	DataDir                   string
 	DatabasePath              string
 	WebDistDir                string
+	WebAssetPath              string
+	WebAssetMaxAgeSec         int
 	ServerPublicBaseURL       string
-	AdminToken                string
+	AdminTokens               []string
 	LiveKitURL                string
 	LiveKitPublicURL          string
 	LiveKitAPIKey             string
 	LiveKitAPISecret          string
+	LiveKitRoomPrefix         string
+	SQLiteBusyTimeoutMS       int
+	SQLiteCacheSize           int
+	SQLiteMMapSize            int64
+	Maintenance               bool
+	ChannelStreamBuffer       int
+	PublicPreview             bool
+	ICEServers                []ICEServer
+	MaxWSConnections          int
+	WSReadBuffer              int
+	WSWriteBuffer             int
+	AnonymizeOnLeave          bool
+	WelcomeChannelID          string
+	DefaultChannelID          string
+	WelcomeTemplate           string
+	IdentityKeyFile           string
+	IdentityPrivateKey        string
+	AllowMassMention          bool
+	SessionTokenPrefix        string
+	SessionTokenFormat        string
+	SessionTokenBytes         int
+	MaxAudioStreams           int
+	MaxVideoStreams           int
+	MinClientVersion          string
+	ClientVersionMissing      string
+	AutoDeleteUsedInvites     bool
+	RequireInviteLabel        bool
+	MaxMessagesPerChannel     int
+	MaxChannels               int
+	MessageRatePerMinute      int
+	MessageBurst              int
+	SessionSweepIntervalSec   int
+	VoiceJoinGraceSec         int
+	CORSAllowedOrigins        []string
+	CORSAllowCredentials      bool
+	TrustedProxies            []netip.Prefix
+	MessageWebhookURL         string
+	MessageWebhookSecret      string
+	MessageWebhookEvents      []string
+	MessageWebhookChannels    []string
 }
 
-func Load() Config {
+const (
+	maxSQLiteBusyTimeoutMS = 10 * 60 * 1000
+	maxSQLiteCacheSize     = 1 << 21
+	maxSQLiteMMapSize      = 1 << 36
+	maxChannelStreamBuffer = 4096
+	maxWSConnections       = 1 << 20
+	minWSBuffer            = 256
+	maxWSBuffer            = 1 << 20
+	minSessionTokenBytes   = 32
+	maxSessionTokenBytes   = 64
+	maxVoiceStreams        = 1024
+	maxMessagesPerChannel  = 1 << 30
+	maxChannels            = 1 << 16
+	maxMessageRate         = 600
+	maxMessageBurst        = 100
+	maxSessionSweepSec     = 7 * 24 * 60 * 60
+	maxVoiceJoinGraceSec   = 60
+	maxWebAssetMaxAgeSec   = 365 * 24 * 60 * 60
+)
+
+// defaultCORSAllowedOrigins covers the dev servers, the edge proxy and Tauri.
+var defaultCORSAllowedOrigins = []string{
+	"http://localhost:1420",
+	"http://127.0.0.1:1420",
+	"http://localhost:5173",
+	"http://127.0.0.1:5173",
+	"http://localhost:8088",
+	"http://127.0.0.1:8088",
+	"http://localhost:3000",
+	"http://127.0.0.1:3000",
+	"tauri://localhost",
+	"https://tauri.localhost",
+}
+
+var (
+	sessionTokenPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_]{0,16}$`)
+	liveKitRoomPrefixPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]{0,32}$`)
+	clientVersionPattern      = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+)
+
+func Load() (Config, error) {
 	liveKitURL := getEnv("LIVEKIT_URL", "http://localhost:7880")
-	return Config{
+	cfg := Config{
 		Addr:                      getEnv("SERVER_ADDR", ":8080"),
 		ServerName:                getEnv("SERVER_NAME", "Local Server"),
 		PublicKeyFingerprintEmoji: getEnv("SERVER_PUBLIC_KEY_FINGERPRINT_EMOJI", ":lock::satellite:"),
 		DataDir:                   getEnv("DATA_DIR", "data"),
 		DatabasePath:              os.Getenv("DB_PATH"),
 		WebDistDir:                os.Getenv("WEB_DIST_DIR"),
+		WebAssetPath:              strings.Trim(strings.TrimSpace(getEnv("WEB_ASSET_PATH", "assets")), "/"),
 		ServerPublicBaseURL:       getEnv("SERVER_PUBLIC_BASE_URL", "http://localhost:8080"),
-		AdminToken:                os.Getenv("ADMIN_TOKEN"),
 		LiveKitURL:                liveKitURL,
 		LiveKitPublicURL:          getEnv("LIVEKIT_PUBLIC_URL", liveKitURL),
-		LiveKitAPIKey:             os.Getenv("LIVEKIT_API_KEY"),
-		LiveKitAPISecret:          os.Getenv("LIVEKIT_API_SECRET"),
+		LiveKitRoomPrefix:         strings.TrimSpace(os.Getenv("LIVEKIT_ROOM_PREFIX")),
+		WelcomeChannelID:          os.Getenv("WELCOME_CHANNEL_ID"),
+		WelcomeTemplate:           os.Getenv("WELCOME_TEMPLATE"),
+		DefaultChannelID:          strings.TrimSpace(os.Getenv("DEFAULT_CHANNEL_ID")),
+		IdentityKeyFile:           strings.TrimSpace(os.Getenv("IDENTITY_KEY_FILE")),
+		IdentityPrivateKey:        strings.TrimSpace(os.Getenv("IDENTITY_PRIVATE_KEY")),
+		SessionTokenPrefix:        strings.TrimSpace(os.Getenv("SESSION_TOKEN_PREFIX")),
+		SessionTokenFormat:        strings.ToLower(strings.TrimSpace(getEnv("SESSION_TOKEN_FORMAT", "hex"))),
+		MinClientVersion:          strings.TrimSpace(os.Getenv("MIN_CLIENT_VERSION")),
+		ClientVersionMissing:      strings.ToLower(strings.TrimSpace(getEnv("CLIENT_VERSION_MISSING", "allow"))),
+		MessageWebhookURL:         strings.TrimSpace(os.Getenv("MESSAGE_WEBHOOK_URL")),
+		MessageWebhookSecret:      os.Getenv("MESSAGE_WEBHOOK_SECRET"),
+		MessageWebhookEvents:      splitList(getEnv("MESSAGE_WEBHOOK_EVENTS", "message.created")),
+		MessageWebhookChannels:    splitList(os.Getenv("MESSAGE_WEBHOOK_CHANNELS")),
+	}
+	if cfg.IdentityKeyFile != "" && cfg.IdentityPrivateKey != "" {
+		return Config{}, errors.New("IDENTITY_KEY_FILE and IDENTITY_PRIVATE_KEY are mutually exclusive")
+	}
+
+	var err error
+	if cfg.AdminTokens, err = parseSecretList("ADMIN_TOKEN", "ADMIN_TOKENS"); err != nil {
+		return Config{}, err
+	}
+	if cfg.LiveKitAPIKey, cfg.LiveKitAPISecret, err = parseLiveKitKeys(); err != nil {
+		return Config{}, err
+	}
+	if cfg.SQLiteBusyTimeoutMS, err = getEnvInt("SQLITE_BUSY_TIMEOUT", 5000, 0, maxSQLiteBusyTimeoutMS); err != nil {
+		return Config{}, err
+	}
+	// Negative cache sizes are KiB, positive ones are pages, matching PRAGMA cache_size.
+	if cfg.SQLiteCacheSize, err = getEnvInt("SQLITE_CACHE_SIZE", -2000, -maxSQLiteCacheSize, maxSQLiteCacheSize); err != nil {
+		return Config{}, err
+	}
+	if cfg.SQLiteMMapSize, err = getEnvInt64("SQLITE_MMAP_SIZE", 0, 0, maxSQLiteMMapSize); err != nil {
+		return Config{}, err
+	}
+	if cfg.Maintenance, err = getEnvBool("MAINTENANCE", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.PublicPreview, err = getEnvBool("PUBLIC_PREVIEW", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.AnonymizeOnLeave, err = getEnvBool("ANONYMIZE_ON_LEAVE", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.AllowMassMention, err = getEnvBool("ALLOW_MASS_MENTION", true); err != nil {
+		return Config{}, err
+	}
+	if cfg.AutoDeleteUsedInvites, err = getEnvBool("AUTO_DELETE_USED_INVITES", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.RequireInviteLabel, err = getEnvBool("REQUIRE_INVITE_LABEL", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.ChannelStreamBuffer, err = getEnvInt("CHANNEL_STREAM_BUFFER", 32, 1, maxChannelStreamBuffer); err != nil {
+		return Config{}, err
+	}
+	// 0 disables the cap on concurrently upgraded websocket connections.
+	if cfg.MaxWSConnections, err = getEnvInt("MAX_WS_CONNECTIONS", 1024, 0, maxWSConnections); err != nil {
+		return Config{}, err
+	}
+	// Websocket frames larger than a buffer still work; they just take more syscalls.
+	if cfg.WSReadBuffer, err = getEnvInt("WS_READ_BUFFER", 4096, minWSBuffer, maxWSBuffer); err != nil {
+		return Config{}, err
+	}
+	if cfg.WSWriteBuffer, err = getEnvInt("WS_WRITE_BUFFER", 4096, minWSBuffer, maxWSBuffer); err != nil {
+		return Config{}, err
+	}
+	// 0 leaves expired sessions to the lazy cleanup on authentication.
+	if cfg.SessionSweepIntervalSec, err = getEnvInt("SESSION_SWEEP_INTERVAL", 3600, 0, maxSessionSweepSec); err != nil {
+		return Config{}, err
+	}
+	// The byte count is the token's entropy, independent of prefix and encoding.
+	if cfg.SessionTokenBytes, err = getEnvInt("SESSION_TOKEN_BYTES", minSessionTokenBytes, minSessionTokenBytes, maxSessionTokenBytes); err != nil {
+		return Config{}, err
+	}
+	if !sessionTokenPrefixPattern.MatchString(cfg.SessionTokenPrefix) {
+		return Config{}, errors.New("SESSION_TOKEN_PREFIX must be up to 16 letters, digits or underscores")
 	}
+	if cfg.SessionTokenFormat != "hex" && cfg.SessionTokenFormat != "base64url" {
+		return Config{}, fmt.Errorf("SESSION_TOKEN_FORMAT must be hex or base64url, got %q", cfg.SessionTokenFormat)
+	}
+	if cfg.MaxAudioStreams, err = getEnvInt("MAX_AUDIO_STREAMS", 16, 0, maxVoiceStreams); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxVideoStreams, err = getEnvInt("MAX_VIDEO_STREAMS", 16, 0, maxVoiceStreams); err != nil {
+		return Config{}, err
+	}
+	if cfg.VoiceJoinGraceSec, err = getEnvInt("VOICE_JOIN_GRACE", 0, 0, maxVoiceJoinGraceSec); err != nil {
+		return Config{}, err
+	}
+	// 0 serves fingerprinted assets with default caching, like any other file.
+	if cfg.WebAssetMaxAgeSec, err = getEnvInt("WEB_ASSET_MAX_AGE", maxWebAssetMaxAgeSec, 0, maxWebAssetMaxAgeSec); err != nil {
+		return Config{}, err
+	}
+	if cfg.MinClientVersion != "" && !clientVersionPattern.MatchString(cfg.MinClientVersion) {
+		return Config{}, fmt.Errorf("MIN_CLIENT_VERSION must be a semantic version like 1.4.0, got %q", cfg.MinClientVersion)
+	}
+	if cfg.ClientVersionMissing != "allow" && cfg.ClientVersionMissing != "deny" {
+		return Config{}, fmt.Errorf("CLIENT_VERSION_MISSING must be allow or deny, got %q", cfg.ClientVersionMissing)
+	}
+	// 0 keeps every message; otherwise the oldest messages beyond the cap are evicted on post.
+	if cfg.MaxMessagesPerChannel, err = getEnvInt("MAX_MESSAGES_PER_CHANNEL", 0, 0, maxMessagesPerChannel); err != nil {
+		return Config{}, err
+	}
+	// 0 leaves channel creation uncapped.
+	if cfg.MaxChannels, err = getEnvInt("MAX_CHANNELS", 0, 0, maxChannels); err != nil {
+		return Config{}, err
+	}
+	// 0 leaves members unthrottled apart from per-channel slow mode.
+	if cfg.MessageRatePerMinute, err = getEnvInt("MESSAGE_RATE", 0, 0, maxMessageRate); err != nil {
+		return Config{}, err
+	}
+	if cfg.MessageBurst, err = getEnvInt("MESSAGE_BURST", 5, 1, maxMessageBurst); err != nil {
+		return Config{}, err
+	}
+	if !liveKitRoomPrefixPattern.MatchString(cfg.LiveKitRoomPrefix) {
+		return Config{}, errors.New("LIVEKIT_ROOM_PREFIX must be up to 32 letters, digits, dashes or underscores")
+	}
+	if cfg.CORSAllowedOrigins, err = parseCORSOrigins(os.Getenv("CORS_ALLOWED_ORIGINS")); err != nil {
+		return Config{}, err
+	}
+	if cfg.CORSAllowCredentials, err = getEnvBool("CORS_ALLOW_CREDENTIALS", false); err != nil {
+		return Config{}, err
+	}
+	// Browsers refuse credentialed responses with a wildcard origin, and reflecting
+	// every origin instead would let any site make authenticated requests.
+	if cfg.CORSAllowCredentials && slices.Contains(cfg.CORSAllowedOrigins, "*") {
+		return Config{}, errors.New("CORS_ALLOW_CREDENTIALS cannot be combined with a * entry in CORS_ALLOWED_ORIGINS")
+	}
+	if cfg.TrustedProxies, err = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES")); err != nil {
+		return Config{}, err
+	}
+	if err := validateMessageWebhook(cfg); err != nil {
+		return Config{}, err
+	}
+	if cfg.ICEServers, err = parseICEServers(os.Getenv("ICE_SERVERS_JSON")); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// parseCORSOrigins reads a comma-separated origin list, falling back to the
+// built-in development origins when unset.
+func parseCORSOrigins(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return slices.Clone(defaultCORSAllowedOrigins), nil
+	}
+
+	var origins []string
+	for _, origin := range strings.Split(raw, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return nil, errors.New("CORS_ALLOWED_ORIGINS must list at least one origin")
+	}
+	return origins, nil
+}
+
+// parseTrustedProxies reads a comma-separated list of CIDRs or single
+// addresses whose forwarded headers are honored. Unset trusts no proxy.
+func parseTrustedProxies(raw string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range splitList(raw) {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or CIDR", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+var messageWebhookEvents = []string{"message.created", "message.updated", "message.deleted"}
+
+func validateMessageWebhook(cfg Config) error {
+	if cfg.MessageWebhookURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(cfg.MessageWebhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("MESSAGE_WEBHOOK_URL must be an http(s) URL, got %q", cfg.MessageWebhookURL)
+	}
+	if cfg.MessageWebhookSecret == "" {
+		return errors.New("MESSAGE_WEBHOOK_SECRET is required when MESSAGE_WEBHOOK_URL is set")
+	}
+	if len(cfg.MessageWebhookEvents) == 0 {
+		return errors.New("MESSAGE_WEBHOOK_EVENTS must list at least one event")
+	}
+	for _, event := range cfg.MessageWebhookEvents {
+		if !slices.Contains(messageWebhookEvents, event) {
+			return fmt.Errorf("MESSAGE_WEBHOOK_EVENTS: unsupported event %q", event)
+		}
+	}
+	return nil
+}
+
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// parseLiveKitKeys resolves the key pair that signs LiveKit tokens, either from
+// LIVEKIT_API_KEY and LIVEKIT_API_SECRET or from the first pair of LIVEKIT_KEYS.
+// LIVEKIT_KEYS uses LiveKit's own "key: secret, key2: secret2" format, so one
+// value can configure both servers while LiveKit accepts several keys during a
+// rotation.
+func parseLiveKitKeys() (string, string, error) {
+	apiKey := strings.TrimSpace(os.Getenv("LIVEKIT_API_KEY"))
+	apiSecret := strings.TrimSpace(os.Getenv("LIVEKIT_API_SECRET"))
+	keys := os.Getenv("LIVEKIT_KEYS")
+	if strings.TrimSpace(keys) == "" {
+		return apiKey, apiSecret, nil
+	}
+	if apiKey != "" || apiSecret != "" {
+		return "", "", errors.New("LIVEKIT_KEYS cannot be combined with LIVEKIT_API_KEY or LIVEKIT_API_SECRET")
+	}
+
+	var primaryKey, primarySecret string
+	for _, pair := range splitList(keys) {
+		key, secret, ok := strings.Cut(pair, ":")
+		key, secret = strings.TrimSpace(key), strings.TrimSpace(secret)
+		if !ok || key == "" || secret == "" {
+			return "", "", errors.New(`LIVEKIT_KEYS entries must be "key: secret"`)
+		}
+		if primaryKey == "" {
+			primaryKey, primarySecret = key, secret
+		}
+	}
+	if primaryKey == "" {
+		return "", "", errors.New("LIVEKIT_KEYS must list at least one key")
+	}
+	return primaryKey, primarySecret, nil
+}
+
+// parseSecretList resolves a comma-separated secret list such as ADMIN_TOKENS,
+// or its single-entry variable. Lists allow rotation: the new secret is added
+// before the old one is removed.
+func parseSecretList(singleKey, listKey string) ([]string, error) {
+	single := strings.TrimSpace(os.Getenv(singleKey))
+	list := os.Getenv(listKey)
+	if strings.TrimSpace(list) == "" {
+		if single == "" {
+			return nil, nil
+		}
+		return []string{single}, nil
+	}
+	if single != "" {
+		return nil, fmt.Errorf("%s and %s are mutually exclusive", singleKey, listKey)
+	}
+
+	secrets := splitList(list)
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("%s must list at least one secret", listKey)
+	}
+	return secrets, nil
 }
 
 func getEnv(key, fallback string) string {
@@ -41,3 +399,49 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback, min, max int) (int, error) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	if value < min || value > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", key, min, max)
+	}
+	return value, nil
+}
+
+// getEnvInt64 is getEnvInt for settings whose range exceeds int on 32-bit targets.
+func getEnvInt64(key string, fallback, min, max int64) (int64, error) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	if value < min || value > max {
+		return 0, fmt.Errorf("%s must be between %d and %d", key, min, max)
+	}
+	return value, nil
+}
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean: %w", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadCORSOrigins(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if !slices.Equal(cfg.CORSAllowedOrigins, defaultCORSAllowedOrigins) || cfg.CORSAllowCredentials {
+		t.Fatalf("unexpected CORS defaults: %v, credentials=%v", cfg.CORSAllowedOrigins, cfg.CORSAllowCredentials)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", " https://chat.example.org/ ,https://admin.example.org")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if want := []string{"https://chat.example.org", "https://admin.example.org"}; !slices.Equal(cfg.CORSAllowedOrigins, want) {
+		t.Fatalf("unexpected origins: %v", cfg.CORSAllowedOrigins)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://chat.example.org,*")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected credentials with a wildcard origin to be rejected")
+	}
+}
+
+func TestLoadWebsocketBuffers(t *testing.T) {
+	t.Setenv("WS_READ_BUFFER", "1024")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.WSReadBuffer != 1024 || cfg.WSWriteBuffer != 4096 {
+		t.Fatalf("unexpected buffer sizes: read=%d write=%d", cfg.WSReadBuffer, cfg.WSWriteBuffer)
+	}
+
+	t.Setenv("WS_WRITE_BUFFER", "16")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected a write buffer below the minimum to be rejected")
+	}
+}
+
+func TestLoadSQLiteMMapSize(t *testing.T) {
+	t.Setenv("SQLITE_MMAP_SIZE", "68719476736")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.SQLiteMMapSize != 1<<36 {
+		t.Fatalf("unexpected mmap size: %d", cfg.SQLiteMMapSize)
+	}
+
+	t.Setenv("SQLITE_MMAP_SIZE", "68719476737")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an mmap size above the maximum to be rejected")
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(cfg.TrustedProxies) != 0 {
+		t.Fatalf("expected no trusted proxies by default, got %v", cfg.TrustedProxies)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 127.0.0.1 ,::1")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("127.0.0.1/32"),
+		netip.MustParsePrefix("::1/128"),
+	}
+	if !slices.Equal(cfg.TrustedProxies, want) {
+		t.Fatalf("unexpected trusted proxies: %v", cfg.TrustedProxies)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected a hostname to be rejected")
+	}
+}
+
+func TestLoadAdminTokens(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "single")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if !slices.Equal(cfg.AdminTokens, []string{"single"}) {
+		t.Fatalf("unexpected admin tokens: %v", cfg.AdminTokens)
+	}
+
+	t.Setenv("ADMIN_TOKENS", "alice-token, bob-token")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected ADMIN_TOKEN and ADMIN_TOKENS together to be rejected")
+	}
+
+	t.Setenv("ADMIN_TOKEN", "")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if !slices.Equal(cfg.AdminTokens, []string{"alice-token", "bob-token"}) {
+		t.Fatalf("unexpected admin tokens: %v", cfg.AdminTokens)
+	}
+}
+
+func TestLoadLiveKitKeys(t *testing.T) {
+	t.Setenv("LIVEKIT_KEYS", "newkey: new-secret, oldkey: old-secret")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.LiveKitAPIKey != "newkey" || cfg.LiveKitAPISecret != "new-secret" {
+		t.Fatalf("expected the first pair to sign, got %q %q", cfg.LiveKitAPIKey, cfg.LiveKitAPISecret)
+	}
+
+	t.Setenv("LIVEKIT_KEYS", "newkey new-secret")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an entry without a secret to be rejected")
+	}
+
+	t.Setenv("LIVEKIT_KEYS", "newkey: new-secret")
+	t.Setenv("LIVEKIT_API_SECRET", "single")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected LIVEKIT_KEYS and LIVEKIT_API_SECRET together to be rejected")
+	}
+}
+
+func TestRedactedMasksSecrets(t *testing.T) {
+	cfg := Config{
+		ServerName:           "Local Server",
+		DatabasePath:         "/srv/fosscord/server.db",
+		AdminTokens:          []string{"admin-one", "admin-two"},
+		LiveKitAPISecret:     "livekit-secret",
+		IdentityPrivateKey:   "identity-key",
+		MessageWebhookURL:    "https://hooks.example.org/services/T000/B000/webhook-path-secret?token=query-secret",
+		MessageWebhookSecret: "webhook-secret",
+		ICEServers:           []ICEServer{{URLs: []string{"turn:turn.example.org"}, Username: "user", Credential: "turn-secret"}},
+	}
+
+	redacted := cfg.Redacted()
+	encoded, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	for _, secret := range []string{"admin-one", "admin-two", "livekit-secret", "identity-key", "webhook-path-secret", "query-secret", "webhook-secret", "turn-secret", "/srv/fosscord"} {
+		if strings.Contains(string(encoded), secret) {
+			t.Fatalf("redacted config leaks %q: %s", secret, encoded)
+		}
+	}
+	if len(redacted.AdminTokens) != 2 || redacted.ServerName != "Local Server" || redacted.ICEServers[0].Username != "user" {
+		t.Fatalf("redaction dropped non-secret values: %+v", redacted)
+	}
+	if redacted.DatabasePath != redactedValue {
+		t.Fatalf("DB_PATH must be masked, got %q", redacted.DatabasePath)
+	}
+	if redacted.MessageWebhookURL != "https://hooks.example.org/[redacted]" {
+		t.Fatalf("webhook URL must keep only its scheme and host, got %q", redacted.MessageWebhookURL)
+	}
+	if cfg.ICEServers[0].Credential != "turn-secret" || cfg.AdminTokens[0] != "admin-one" {
+		t.Fatal("redaction must not modify the original config")
+	}
+	if (Config{}).Redacted().IdentityPrivateKey != "" {
+		t.Fatal("unset secrets must stay empty")
+	}
+}
+
+// Every Config field must be listed in RedactedConfig, so adding one forces a
+// decision on whether it needs masking.
+func TestRedactedConfigListsEveryField(t *testing.T) {
+	listed := reflect.TypeOf(RedactedConfig{})
+	fields := reflect.TypeOf(Config{})
+	for i := range fields.NumField() {
+		if _, ok := listed.FieldByName(fields.Field(i).Name); !ok {
+			t.Errorf("RedactedConfig is missing %s", fields.Field(i).Name)
+		}
+	}
+}
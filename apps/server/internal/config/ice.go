@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ICEServer mirrors the RTCIceServer dictionary clients pass to RTCPeerConnection.
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// UnmarshalJSON accepts `urls` as a single string or a list, like RTCIceServer.
+func (s *ICEServer) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		URLs       json.RawMessage `json:"urls"`
+		Username   string          `json:"username"`
+		Credential string          `json:"credential"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var single string
+	if err := json.Unmarshal(raw.URLs, &single); err == nil {
+		s.URLs = []string{single}
+	} else if err := json.Unmarshal(raw.URLs, &s.URLs); err != nil {
+		return errors.New("urls must be a string or an array of strings")
+	}
+	s.Username = raw.Username
+	s.Credential = raw.Credential
+	return nil
+}
+
+func parseICEServers(raw string) ([]ICEServer, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var servers []ICEServer
+	if err := json.Unmarshal([]byte(raw), &servers); err != nil {
+		return nil, fmt.Errorf("ICE_SERVERS_JSON must be a JSON array of {urls, username, credential}: %w", err)
+	}
+	for i, server := range servers {
+		if len(server.URLs) == 0 {
+			return nil, fmt.Errorf("ICE_SERVERS_JSON[%d]: urls is required", i)
+		}
+		for _, url := range server.URLs {
+			if !hasICEScheme(url) {
+				return nil, fmt.Errorf("ICE_SERVERS_JSON[%d]: %q must use stun:, stuns:, turn: or turns:", i, url)
+			}
+			if strings.HasPrefix(url, "turn") && (server.Username == "" || server.Credential == "") {
+				return nil, fmt.Errorf("ICE_SERVERS_JSON[%d]: TURN servers require username and credential", i)
+			}
+		}
+	}
+	return servers, nil
+}
+
+func hasICEScheme(url string) bool {
+	for _, scheme := range []string{"stun:", "stuns:", "turn:", "turns:"} {
+		if strings.HasPrefix(url, scheme) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestParseICEServers(t *testing.T) {
+	servers, err := parseICEServers(`[
+		{"urls": "stun:stun.example.org:3478"},
+		{"urls": ["turn:turn.example.org:3478?transport=udp", "turns:turn.example.org:5349"], "username": "u", "credential": "p"}
+	]`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(servers) != 2 || len(servers[0].URLs) != 1 || len(servers[1].URLs) != 2 {
+		t.Fatalf("unexpected servers: %+v", servers)
+	}
+
+	if servers, err := parseICEServers(""); err != nil || servers != nil {
+		t.Fatalf("expected unset value to yield no servers, got %+v, %v", servers, err)
+	}
+
+	for _, raw := range []string{
+		`{"urls": "stun:a"}`,
+		`[{"urls": []}]`,
+		`[{"urls": "http://a"}]`,
+		`[{"urls": "turn:a"}]`,
+		`[{"urls": 5}]`,
+	} {
+		if _, err := parseICEServers(raw); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import "net/url"
+
+// redactedValue replaces secrets in RedactedConfig; unset secrets stay empty so
+// operators can still tell whether one is configured.
+const redactedValue = "[redacted]"
+
+// RedactedConfig is the view of Config shown to operators. Fields are copied
+// one by one, and TestRedactedConfigListsEveryField fails until a new Config
+// field is listed here, so each one gets an explicit decision on masking. Admin
+// tokens, the LiveKit secret, the identity key, TURN credentials, the webhook
+// secret and DB_PATH are masked, and the webhook URL keeps only its scheme and
+// host, since Slack and Discord style URLs carry their secret in the path.
+type RedactedConfig struct {
+	Addr                      string      `json:"addr"`
+	ServerName                string      `json:"serverName"`
+	PublicKeyFingerprintEmoji string      `json:"publicKeyFingerprintEmoji"`
+	DataDir                   string      `json:"dataDir"`
+	DatabasePath              string      `json:"databasePath"`
+	WebDistDir                string      `json:"webDistDir"`
+	WebAssetPath              string      `json:"webAssetPath"`
+	WebAssetMaxAgeSec         int         `json:"webAssetMaxAgeSec"`
+	ServerPublicBaseURL       string      `json:"serverPublicBaseUrl"`
+	AdminTokens               []string    `json:"adminTokens"`
+	LiveKitURL                string      `json:"livekitUrl"`
+	LiveKitPublicURL          string      `json:"livekitPublicUrl"`
+	LiveKitAPIKey             string      `json:"livekitApiKey"`
+	LiveKitAPISecret          string      `json:"livekitApiSecret"`
+	LiveKitRoomPrefix         string      `json:"livekitRoomPrefix"`
+	SQLiteBusyTimeoutMS       int         `json:"sqliteBusyTimeoutMs"`
+	SQLiteCacheSize           int         `json:"sqliteCacheSize"`
+	SQLiteMMapSize            int64       `json:"sqliteMmapSize"`
+	Maintenance               bool        `json:"maintenance"`
+	ChannelStreamBuffer       int         `json:"channelStreamBuffer"`
+	PublicPreview             bool        `json:"publicPreview"`
+	ICEServers                []ICEServer `json:"iceServers"`
+	MaxWSConnections          int         `json:"maxWsConnections"`
+	WSReadBuffer              int         `json:"wsReadBuffer"`
+	WSWriteBuffer             int         `json:"wsWriteBuffer"`
+	AnonymizeOnLeave          bool        `json:"anonymizeOnLeave"`
+	WelcomeChannelID          string      `json:"welcomeChannelId"`
+	DefaultChannelID          string      `json:"defaultChannelId"`
+	WelcomeTemplate           string      `json:"welcomeTemplate"`
+	IdentityKeyFile           string      `json:"identityKeyFile"`
+	IdentityPrivateKey        string      `json:"identityPrivateKey"`
+	AllowMassMention          bool        `json:"allowMassMention"`
+	SessionTokenPrefix        string      `json:"sessionTokenPrefix"`
+	SessionTokenFormat        string      `json:"sessionTokenFormat"`
+	SessionTokenBytes         int         `json:"sessionTokenBytes"`
+	MaxAudioStreams           int         `json:"maxAudioStreams"`
+	MaxVideoStreams           int         `json:"maxVideoStreams"`
+	MinClientVersion          string      `json:"minClientVersion"`
+	ClientVersionMissing      string      `json:"clientVersionMissing"`
+	AutoDeleteUsedInvites     bool        `json:"autoDeleteUsedInvites"`
+	RequireInviteLabel        bool        `json:"requireInviteLabel"`
+	MaxMessagesPerChannel     int         `json:"maxMessagesPerChannel"`
+	MaxChannels               int         `json:"maxChannels"`
+	MessageRatePerMinute      int         `json:"messageRatePerMinute"`
+	MessageBurst              int         `json:"messageBurst"`
+	SessionSweepIntervalSec   int         `json:"sessionSweepIntervalSec"`
+	VoiceJoinGraceSec         int         `json:"voiceJoinGraceSec"`
+	CORSAllowedOrigins        []string    `json:"corsAllowedOrigins"`
+	CORSAllowCredentials      bool        `json:"corsAllowCredentials"`
+	TrustedProxies            []string    `json:"trustedProxies"`
+	MessageWebhookURL         string      `json:"messageWebhookUrl"`
+	MessageWebhookSecret      string      `json:"messageWebhookSecret"`
+	MessageWebhookEvents      []string    `json:"messageWebhookEvents"`
+	MessageWebhookChannels    []string    `json:"messageWebhookChannels"`
+}
+
+// Redacted returns the operator view of c; see RedactedConfig.
+func (c Config) Redacted() RedactedConfig {
+	iceServers := make([]ICEServer, len(c.ICEServers))
+	for i, server := range c.ICEServers {
+		server.Credential = redactSecret(server.Credential)
+		iceServers[i] = server
+	}
+	trustedProxies := make([]string, len(c.TrustedProxies))
+	for i, prefix := range c.TrustedProxies {
+		trustedProxies[i] = prefix.String()
+	}
+
+	return RedactedConfig{
+		Addr:                      c.Addr,
+		ServerName:                c.ServerName,
+		PublicKeyFingerprintEmoji: c.PublicKeyFingerprintEmoji,
+		DataDir:                   c.DataDir,
+		DatabasePath:              redactSecret(c.DatabasePath),
+		WebDistDir:                c.WebDistDir,
+		WebAssetPath:              c.WebAssetPath,
+		WebAssetMaxAgeSec:         c.WebAssetMaxAgeSec,
+		ServerPublicBaseURL:       c.ServerPublicBaseURL,
+		AdminTokens:               redactSecrets(c.AdminTokens),
+		LiveKitURL:                c.LiveKitURL,
+		LiveKitPublicURL:          c.LiveKitPublicURL,
+		LiveKitAPIKey:             c.LiveKitAPIKey,
+		LiveKitAPISecret:          redactSecret(c.LiveKitAPISecret),
+		LiveKitRoomPrefix:         c.LiveKitRoomPrefix,
+		SQLiteBusyTimeoutMS:       c.SQLiteBusyTimeoutMS,
+		SQLiteCacheSize:           c.SQLiteCacheSize,
+		SQLiteMMapSize:            c.SQLiteMMapSize,
+		Maintenance:               c.Maintenance,
+		ChannelStreamBuffer:       c.ChannelStreamBuffer,
+		PublicPreview:             c.PublicPreview,
+		ICEServers:                iceServers,
+		MaxWSConnections:          c.MaxWSConnections,
+		WSReadBuffer:              c.WSReadBuffer,
+		WSWriteBuffer:             c.WSWriteBuffer,
+		AnonymizeOnLeave:          c.AnonymizeOnLeave,
+		WelcomeChannelID:          c.WelcomeChannelID,
+		DefaultChannelID:          c.DefaultChannelID,
+		WelcomeTemplate:           c.WelcomeTemplate,
+		IdentityKeyFile:           c.IdentityKeyFile,
+		IdentityPrivateKey:        redactSecret(c.IdentityPrivateKey),
+		AllowMassMention:          c.AllowMassMention,
+		SessionTokenPrefix:        c.SessionTokenPrefix,
+		SessionTokenFormat:        c.SessionTokenFormat,
+		SessionTokenBytes:         c.SessionTokenBytes,
+		MaxAudioStreams:           c.MaxAudioStreams,
+		MaxVideoStreams:           c.MaxVideoStreams,
+		MinClientVersion:          c.MinClientVersion,
+		ClientVersionMissing:      c.ClientVersionMissing,
+		AutoDeleteUsedInvites:     c.AutoDeleteUsedInvites,
+		RequireInviteLabel:        c.RequireInviteLabel,
+		MaxMessagesPerChannel:     c.MaxMessagesPerChannel,
+		MaxChannels:               c.MaxChannels,
+		MessageRatePerMinute:      c.MessageRatePerMinute,
+		MessageBurst:              c.MessageBurst,
+		SessionSweepIntervalSec:   c.SessionSweepIntervalSec,
+		VoiceJoinGraceSec:         c.VoiceJoinGraceSec,
+		CORSAllowedOrigins:        c.CORSAllowedOrigins,
+		CORSAllowCredentials:      c.CORSAllowCredentials,
+		TrustedProxies:            trustedProxies,
+		MessageWebhookURL:         redactURL(c.MessageWebhookURL),
+		MessageWebhookSecret:      redactSecret(c.MessageWebhookSecret),
+		MessageWebhookEvents:      c.MessageWebhookEvents,
+		MessageWebhookChannels:    c.MessageWebhookChannels,
+	}
+}
+
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+func redactSecrets(values []string) []string {
+	masked := make([]string, len(values))
+	for i := range values {
+		masked[i] = redactedValue
+	}
+	return masked
+}
+
+// redactURL keeps the scheme and host of raw and masks everything else.
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return redactedValue
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/" + redactedValue
+}
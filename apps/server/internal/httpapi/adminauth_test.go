@@ -0,0 +1,113 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+	"fosscord/apps/server/internal/serverstate"
+)
+
+// withAdminKeys seeds server_config.json with adminKeys; ADMIN_TOKEN stays unset.
+func withAdminKeys(t *testing.T, adminKeys []string) func(*config.Config) {
+	return func(cfg *config.Config) {
+		serverCfg, _ := json.Marshal(map[string]any{
+			"serverName":      "Test Server",
+			"channels":        []map[string]string{{"id": "general", "type": "text", "name": "general"}},
+			"adminPublicKeys": adminKeys,
+		})
+		if err := os.WriteFile(filepath.Join(cfg.DataDir, "server_config.json"), serverCfg, 0o600); err != nil {
+			t.Fatalf("write server config failed: %v", err)
+		}
+	}
+}
+
+func TestSignedAdminPathsWorkWithoutAdminToken(t *testing.T) {
+	adminPub, adminPriv, _ := ed25519.GenerateKey(nil)
+	clientPub, _, _ := ed25519.GenerateKey(nil)
+	adminKey := base64.StdEncoding.EncodeToString(adminPub)
+	clientKey := base64.StdEncoding.EncodeToString(clientPub)
+
+	sign := func(hash [32]byte) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(adminPriv, hash[:]))
+	}
+	signedInvite := func() []byte {
+		issuedAt := time.Now().UTC().Format(time.RFC3339)
+		body, _ := json.Marshal(map[string]string{
+			"adminPublicKey":  adminKey,
+			"clientPublicKey": clientKey,
+			"issuedAt":        issuedAt,
+			"signature":       sign(serverstate.AdminInvitePayloadHash(adminKey, clientKey, issuedAt)),
+		})
+		return body
+	}
+	signedList := func() []byte {
+		issuedAt := time.Now().UTC().Format(time.RFC3339)
+		body, _ := json.Marshal(map[string]string{
+			"adminPublicKey": adminKey,
+			"issuedAt":       issuedAt,
+			"signature":      sign(serverstate.AdminListInvitesPayloadHash(adminKey, issuedAt)),
+		})
+		return body
+	}
+	signedConnect := func(router http.Handler) []byte {
+		var info struct {
+			ServerFingerprint string `json:"serverFingerprint"`
+		}
+		getServerInfo(t, router, &info)
+		issuedAt := time.Now().UTC().Format(time.RFC3339)
+		body, _ := json.Marshal(map[string]any{
+			"adminPublicKey": adminKey,
+			"issuedAt":       issuedAt,
+			"signature":      sign(serverstate.AdminConnectPayloadHash(adminKey, issuedAt, info.ServerFingerprint)),
+			"clientInfo":     map[string]string{"displayName": "Admin"},
+		})
+		return body
+	}
+	post := func(router http.Handler, path string, body []byte, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	requireOK := func(recorder *httptest.ResponseRecorder, path string) {
+		t.Helper()
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s must work without ADMIN_TOKEN: %d %s", path, recorder.Code, recorder.Body.String())
+		}
+	}
+	requireCode := func(recorder *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+		if recorder.Code != status || body.Error != code {
+			t.Fatalf("unexpected response: %d %s", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	withKeys, _ := newTestRouter(t, withAdminKeys(t, []string{adminKey}))
+	tokenBody, _ := json.Marshal(map[string]string{"clientPublicKey": clientKey})
+	requireCode(post(withKeys, "/api/admin/invites", tokenBody, "Bearer anything"), http.StatusServiceUnavailable, "admin_disabled")
+	requireOK(post(withKeys, "/api/admin/invites/client-signed", signedInvite(), ""), "/api/admin/invites/client-signed")
+	requireOK(post(withKeys, "/api/admin/invites/list/client-signed", signedList(), ""), "/api/admin/invites/list/client-signed")
+	requireOK(post(withKeys, "/api/connect/admin", signedConnect(withKeys), ""), "/api/connect/admin")
+
+	withoutKeys, _ := newTestRouter(t, withAdminKeys(t, nil))
+	requireCode(post(withoutKeys, "/api/admin/invites/client-signed", signedInvite(), ""), http.StatusServiceUnavailable, "admin_disabled")
+	requireCode(post(withoutKeys, "/api/admin/invites/list/client-signed", signedList(), ""), http.StatusServiceUnavailable, "admin_disabled")
+	requireCode(post(withoutKeys, "/api/connect/admin", signedConnect(withoutKeys), ""), http.StatusServiceUnavailable, "admin_disabled")
+}
@@ -1,53 +1,87 @@
 package httpapi
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"fosscord/apps/server/internal/config"
 	livekittoken "fosscord/apps/server/internal/livekit"
 	"fosscord/apps/server/internal/serverstate"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	defaultPollTimeoutSeconds = 25
+	maxPollTimeoutSeconds     = 30
+	maxSignRequestBytes       = 4096
+	defaultMessagePageSize    = 50
+	defaultMessageContext     = 25
+)
+
 type handlers struct {
-	cfg   config.Config
-	state *serverstate.State
+	cfg           config.Config
+	state         *serverstate.State
+	liveKitHealth *livekittoken.HealthChecker
+	wsUpgrader    *websocket.Upgrader
 }
 
 type healthResponse struct {
-	Status string `json:"status"`
+	Status string       `json:"status"`
+	Checks healthChecks `json:"checks"`
+}
+
+type healthChecks struct {
+	LiveKit string `json:"livekit"`
 }
 
 type serverInfoResponse struct {
-	ServerID                  string   `json:"serverId"`
-	Name                      string   `json:"name"`
-	PublicKeyFingerprintEmoji string   `json:"publicKeyFingerprintEmoji"`
-	ServerFingerprint         string   `json:"serverFingerprint"`
-	ServerPublicKey           string   `json:"serverPublicKey"`
-	LiveKitURL                string   `json:"livekitUrl"`
-	AdminPublicKeys           []string `json:"adminPublicKeys"`
+	ServerID                  string                  `json:"serverId"`
+	Name                      string                  `json:"name"`
+	PublicKeyFingerprintEmoji string                  `json:"publicKeyFingerprintEmoji"`
+	ServerFingerprint         string                  `json:"serverFingerprint"`
+	ServerPublicKey           string                  `json:"serverPublicKey"`
+	LiveKitURL                string                  `json:"livekitUrl"`
+	AdminPublicKeys           []string                `json:"adminPublicKeys"`
+	Maintenance               bool                    `json:"maintenance"`
+	VoiceLimits               serverstate.VoiceLimits `json:"voiceLimits"`
+	Motd                      string                  `json:"motd,omitempty"`
+	MotdUpdatedAt             string                  `json:"motdUpdatedAt,omitempty"`
+	DefaultChannelID          string                  `json:"defaultChannelId,omitempty"`
+	Capabilities              map[string]bool         `json:"capabilities"`
 }
 
 type createInviteRequest struct {
-	ClientPublicKey string `json:"clientPublicKey"`
-	Label           string `json:"label"`
+	ClientPublicKey string          `json:"clientPublicKey"`
+	Unbound         bool            `json:"unbound"`
+	Label           string          `json:"label"`
+	Metadata        json.RawMessage `json:"metadata"`
+	AdminOnly       bool            `json:"adminOnly"`
 }
 
 type createInviteByClientRequest struct {
-	AdminPublicKey  string `json:"adminPublicKey"`
-	ClientPublicKey string `json:"clientPublicKey"`
-	Label           string `json:"label"`
-	IssuedAt        string `json:"issuedAt"`
-	Signature       string `json:"signature"`
+	AdminPublicKey  string          `json:"adminPublicKey"`
+	ClientPublicKey string          `json:"clientPublicKey"`
+	Label           string          `json:"label"`
+	Metadata        json.RawMessage `json:"metadata"`
+	AdminOnly       bool            `json:"adminOnly"`
+	IssuedAt        string          `json:"issuedAt"`
+	Signature       string          `json:"signature"`
 }
 
 type listInvitesByClientRequest struct {
@@ -60,6 +94,14 @@ type connectBeginRequest struct {
 	InviteID string `json:"inviteId"`
 }
 
+type parseLinkRequest struct {
+	Link string `json:"link"`
+}
+
+type serverSignRequest struct {
+	Challenge string `json:"challenge"`
+}
+
 type connectAdminRequest struct {
 	AdminPublicKey string                 `json:"adminPublicKey"`
 	IssuedAt       string                 `json:"issuedAt"`
@@ -68,22 +110,40 @@ type connectAdminRequest struct {
 }
 
 type createMessageRequest struct {
-	ContentMarkdown string `json:"contentMarkdown"`
+	ContentMarkdown  string `json:"contentMarkdown"`
+	ContentEncrypted string `json:"contentEncrypted"`
+	MessageType      string `json:"messageType"`
 }
 
 type editMessageRequest struct {
+	ContentMarkdown  string `json:"contentMarkdown"`
+	ContentEncrypted string `json:"contentEncrypted"`
+}
+
+var errMixedMessageContent = &serverstate.APIError{
+	Status:  http.StatusBadRequest,
+	Code:    "invalid_message",
+	Message: "send either contentMarkdown or contentEncrypted, not both",
+}
+
+type saveDraftRequest struct {
 	ContentMarkdown string `json:"contentMarkdown"`
 }
 
+type reportMessageRequest struct {
+	Reason string `json:"reason"`
+}
+
 type liveKitTokenRequest struct {
 	ChannelID string `json:"channelId"`
 }
 
 type liveKitTokenResponse struct {
-	Token         string `json:"token"`
-	RoomName      string `json:"roomName"`
-	ChannelID     string `json:"channelId"`
-	ParticipantID string `json:"participantId"`
+	Token         string             `json:"token"`
+	RoomName      string             `json:"roomName"`
+	ChannelID     string             `json:"channelId"`
+	ParticipantID string             `json:"participantId"`
+	ICEServers    []config.ICEServer `json:"iceServers,omitempty"`
 }
 
 type voiceTouchRequest struct {
@@ -93,257 +153,1238 @@ type voiceTouchRequest struct {
 	CameraEnabled      bool   `json:"cameraEnabled"`
 	ScreenEnabled      bool   `json:"screenEnabled"`
 	ScreenAudioEnabled bool   `json:"screenAudioEnabled"`
+	Status             string `json:"status"`
+}
+
+type createChannelRequest struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Topic string `json:"topic"`
+}
+
+type updateChannelRequest struct {
+	Name             *string   `json:"name"`
+	Topic            *string   `json:"topic"`
+	SlowModeSeconds  *int      `json:"slowModeSeconds"`
+	MaxMessageLength *int      `json:"maxMessageLength"`
+	PublicPreview    *bool     `json:"publicPreview"`
+	E2EE             *bool     `json:"e2ee"`
+	TypingIndicators *bool     `json:"typingIndicators"`
+	ReadRoles        *[]string `json:"readRoles"`
+	WriteRoles       *[]string `json:"writeRoles"`
+	AdminRoles       *[]string `json:"adminRoles"`
+}
+
+func (req updateChannelRequest) toUpdate() serverstate.ChannelUpdate {
+	return serverstate.ChannelUpdate{
+		Name:             req.Name,
+		Topic:            req.Topic,
+		SlowModeSeconds:  req.SlowModeSeconds,
+		MaxMessageLength: req.MaxMessageLength,
+		PublicPreview:    req.PublicPreview,
+		E2EE:             req.E2EE,
+		TypingIndicators: req.TypingIndicators,
+		ReadRoles:        req.ReadRoles,
+		WriteRoles:       req.WriteRoles,
+		AdminRoles:       req.AdminRoles,
+	}
+}
+
+type importMessagesRequest struct {
+	Messages []serverstate.ImportedMessage `json:"messages"`
+}
+
+type setMemberRolesRequest struct {
+	PublicKey string   `json:"publicKey"`
+	Roles     []string `json:"roles"`
+}
+
+type kickMemberRequest struct {
+	PublicKey           string `json:"publicKey"`
+	DeleteMessagesSince string `json:"deleteMessagesSince"`
+}
+
+type createMemberRequest struct {
+	PublicKey         string `json:"publicKey"`
+	DisplayName       string `json:"displayName"`
+	IssueSessionToken bool   `json:"issueSessionToken"`
+}
+
+type maintenanceRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type motdRequest struct {
+	Motd string `json:"motd"`
+}
+
+type updateServerRequest struct {
+	Name string `json:"name"`
+}
+
+type motdAckRequest struct {
+	MotdUpdatedAt string `json:"motdUpdatedAt"`
+}
+
+type leaveServerRequest struct {
+	PurgeMessages bool `json:"purgeMessages"`
+}
+
+type channelPollResponse struct {
+	Events []serverstate.ChannelEvent `json:"events"`
+	// Reset tells the client its since message is gone and it must reload history.
+	Reset bool `json:"reset,omitempty"`
 }
 
 type errorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
+	Error     string         `json:"error"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"requestId,omitempty"`
+}
+
+// newWSUpgrader sizes the connection buffers from config. Write buffers come from
+// a shared pool and are only held while a message is being written, so idle
+// subscribers do not each pin one.
+func newWSUpgrader(cfg config.Config) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  cfg.WSReadBuffer,
+		WriteBufferSize: cfg.WSWriteBuffer,
+		WriteBufferPool: &sync.Pool{},
+		CheckOrigin:     func(_ *http.Request) bool { return true },
+	}
+}
+
+func (h handlers) getHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{
+		Status: "ok",
+		Checks: healthChecks{
+			LiveKit: h.liveKitHealth.Status(r.Context()),
+		},
+	})
+}
+
+// getLimits needs no auth: limits are not secret and clients read them before
+// connecting.
+func (h handlers) getLimits(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.state.Limits())
+}
+
+func (h handlers) getServerInfo(w http.ResponseWriter, _ *http.Request) {
+	info := h.state.ServerInfo()
+	writeJSON(w, http.StatusOK, serverInfoResponse{
+		ServerID:                  info.ServerID,
+		Name:                      info.Name,
+		PublicKeyFingerprintEmoji: info.ServerFingerprint,
+		ServerFingerprint:         info.ServerFingerprint,
+		ServerPublicKey:           info.ServerPublicKey,
+		LiveKitURL:                info.LiveKitURL,
+		AdminPublicKeys:           info.AdminPublicKeys,
+		Maintenance:               info.Maintenance,
+		VoiceLimits:               info.VoiceLimits,
+		Motd:                      info.Motd,
+		MotdUpdatedAt:             info.MotdUpdatedAt,
+		DefaultChannelID:          info.DefaultChannelID,
+		Capabilities:              info.Capabilities,
+	})
+}
+
+// getServerPublicKey serves the key alone for external verifiers; PEM gets its
+// own media type, base64 and hex are plain text.
+func (h handlers) getServerPublicKey(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
+	encoded, err := h.state.ServerPublicKey(format)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if format == "pem" {
+		contentType = "application/x-pem-file"
+	} else {
+		encoded += "\n"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, encoded)
+}
+
+func (h handlers) getChannels(w http.ResponseWriter, r *http.Request) {
+	// Anonymous callers only see channels without read roles.
+	var sessionToken string
+	if strings.TrimSpace(r.Header.Get("Authorization")) != "" {
+		token, err := bearerTokenFromHeader(r)
+		if err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+		sessionToken = token
+	}
+
+	channels, err := h.state.Channels(sessionToken)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"channels": channels,
+	})
+}
+
+func (h handlers) postAdminInvites(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req createInviteRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	clientPublicKey := strings.TrimSpace(req.ClientPublicKey)
+	if req.Unbound && clientPublicKey != "" {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_client_public_key", Message: "clientPublicKey must be empty for an unbound invite"})
+		return
+	}
+
+	var result serverstate.CreateInviteResult
+	var err error
+	if req.Unbound {
+		result, err = h.state.CreateUnboundInvite(req.Label, req.Metadata, req.AdminOnly)
+	} else {
+		result, err = h.state.CreateInvite(clientPublicKey, req.Label, req.Metadata, req.AdminOnly)
+	}
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postAdminChannels(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req createChannelRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	channel, err := h.state.CreateChannel(serverstate.Channel{ID: req.ID, Type: req.Type, Name: req.Name, Topic: req.Topic})
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"channel": channel})
+}
+
+func (h handlers) deleteAdminChannel(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.DeleteChannel(chi.URLParam(r, "channelID"))
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
 }
 
-var wsUpgrader = websocket.Upgrader{
-	CheckOrigin: func(_ *http.Request) bool { return true },
+func (h handlers) patchAdminChannel(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req updateChannelRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	channel, err := h.state.UpdateChannel(chi.URLParam(r, "channelID"), req.toUpdate())
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"channel": channel})
+}
+
+func (h handlers) patchChannel(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req updateChannelRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	channel, err := h.state.UpdateChannelAsMember(sessionToken, chi.URLParam(r, "channelID"), req.toUpdate())
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"channel": channel})
+}
+
+func (h handlers) postAdminVoiceChannelClear(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	cleared, err := h.state.ClearVoiceChannel(chi.URLParam(r, "channelID"))
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"cleared": cleared})
+}
+
+func (h handlers) postAdminChannelImport(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req importMessagesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.ImportMessages(chi.URLParam(r, "channelID"), req.Messages)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) getAdminMembers(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	query := serverstate.ListMembersQuery{
+		Query:     r.URL.Query().Get("q"),
+		KeyPrefix: r.URL.Query().Get("keyPrefix"),
+	}
+
+	var err error
+	if query.Limit, err = queryInt(r, "limit", 0); err != nil {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_limit", Message: "limit must be an integer"})
+		return
+	}
+	if query.Offset, err = queryInt(r, "offset", 0); err != nil {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_offset", Message: "offset must be an integer"})
+		return
+	}
+	if raw := strings.TrimSpace(r.URL.Query().Get("online")); raw != "" {
+		online, parseErr := strconv.ParseBool(raw)
+		if parseErr != nil {
+			writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_online", Message: "online must be a boolean"})
+			return
+		}
+		query.Online = &online
+	}
+
+	result, err := h.state.ListMembers(query)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// getAdminMembersExport streams all members as NDJSON. Once the first line is
+// written the status can no longer change, so later failures are only logged.
+func (h handlers) getAdminMembersExport(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="members.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	if err := h.state.ExportMembers(func(member serverstate.MemberExport) error {
+		return encoder.Encode(member)
+	}); err != nil {
+		slog.Error("member export failed", "request_id", middleware.GetReqID(r.Context()), "error", err)
+	}
+}
+
+// getAdminInvitesExport streams all invites as NDJSON, in the format accepted
+// by postAdminInvitesImport.
+func (h handlers) getAdminInvitesExport(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="invites.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	if err := h.state.ExportInvites(func(invite serverstate.InviteSummary) error {
+		return encoder.Encode(invite)
+	}); err != nil {
+		slog.Error("invite export failed", "request_id", middleware.GetReqID(r.Context()), "error", err)
+	}
+}
+
+// postAdminInvitesImport reads an NDJSON invite export, one invite per line.
+func (h handlers) postAdminInvitesImport(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	defer r.Body.Close()
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	invites := []serverstate.InviteSummary{}
+	for decoder.More() {
+		var invite serverstate.InviteSummary
+		if err := decoder.Decode(&invite); err != nil {
+			writeAPIError(w, r, decodeError(err))
+			return
+		}
+		invites = append(invites, invite)
+	}
+
+	result, err := h.state.ImportInvites(invites)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postAdminMembers(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req createMemberRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.CreateMember(req.PublicKey, req.DisplayName, req.IssueSessionToken)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
 }
 
-func (h handlers) getHealth(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
-}
+func (h handlers) postAdminMemberRoles(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req setMemberRolesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	roles, err := h.state.SetMemberRoles(req.PublicKey, req.Roles)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"publicKey": strings.TrimSpace(req.PublicKey), "roles": roles})
+}
+
+func (h handlers) postAdminMemberKick(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req kickMemberRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var deleteMessagesSince *time.Time
+	if raw := strings.TrimSpace(req.DeleteMessagesSince); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_delete_messages_since", Message: "deleteMessagesSince must be an RFC3339 timestamp"})
+			return
+		}
+		deleteMessagesSince = &since
+	}
+
+	result, err := h.state.KickMember(req.PublicKey, deleteMessagesSince)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) getAdminConnectDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.ConnectDiagnostics(r.URL.Query().Get("inviteId"))
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) getAdminReports(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	limit, err := queryInt(r, "limit", 0)
+	if err != nil {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_limit", Message: "limit must be an integer"})
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_offset", Message: "offset must be an integer"})
+		return
+	}
+
+	result, err := h.state.ListReports(limit, offset)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) getAdminStats(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.state.Stats())
+}
+
+func (h handlers) getAdminChannelStats(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	channels, err := h.state.ChannelActivity()
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	count, limit := h.state.ChannelCapacity()
+	writeJSON(w, http.StatusOK, map[string]any{"channels": channels, "channelCount": count, "maxChannels": limit})
+}
+
+func (h handlers) postAdminMaintenance(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req maintenanceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	h.state.SetMaintenance(req.Enabled)
+	writeJSON(w, http.StatusOK, map[string]bool{"maintenance": req.Enabled})
+}
+
+func (h handlers) patchAdminServerMOTD(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req motdRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	motd, err := h.state.SetMOTD(req.Motd)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, motd)
+}
+
+func (h handlers) patchAdminServer(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req updateServerRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	name, err := h.state.UpdateServerName(req.Name)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"name": name})
+}
+
+func (h handlers) getAdminConfigExport(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.state.ExportConfig())
+}
+
+func (h handlers) getAdminConfigEffective(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.state.EffectiveConfig())
+}
+
+func (h handlers) postAdminConfigImport(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeAdmin(r); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req serverstate.ConfigExport
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.ImportConfig(req)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postAdminInvitesClientSigned(w http.ResponseWriter, r *http.Request) {
+	var req createInviteByClientRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.CreateInviteByAdminClient(serverstate.CreateInviteByAdminClientRequest{
+		AdminPublicKey:  req.AdminPublicKey,
+		ClientPublicKey: req.ClientPublicKey,
+		Label:           req.Label,
+		Metadata:        req.Metadata,
+		AdminOnly:       req.AdminOnly,
+		IssuedAt:        req.IssuedAt,
+		Signature:       req.Signature,
+	})
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postAdminInvitesListClientSigned(w http.ResponseWriter, r *http.Request) {
+	var req listInvitesByClientRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.ListInvitesByAdminClient(serverstate.ListInvitesByAdminClientRequest{
+		AdminPublicKey: req.AdminPublicKey,
+		IssuedAt:       req.IssuedAt,
+		Signature:      req.Signature,
+	})
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postConnectBegin(w http.ResponseWriter, r *http.Request) {
+	var req connectBeginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.BeginConnect(req.InviteID)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postConnectParseLink(w http.ResponseWriter, r *http.Request) {
+	var req parseLinkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	link, err := h.state.ParseInviteLink(req.Link)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, link)
+}
+
+func (h handlers) getConnectInvites(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	result, err := h.state.ListClientInvites(serverstate.ListClientInvitesRequest{
+		ClientPublicKey: query.Get("clientPublicKey"),
+		IssuedAt:        query.Get("issuedAt"),
+		Signature:       query.Get("signature"),
+	})
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postServerSign(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSignRequestBytes)
+	var req serverSignRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	clientKey := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		clientKey = host
+	}
+	signature, limit, err := h.state.SignChallenge(clientKey, req.Challenge)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	setRateLimitHeaders(w, limit, http.StatusOK)
+	writeJSON(w, http.StatusOK, signature)
+}
+
+func (h handlers) postConnectFinish(w http.ResponseWriter, r *http.Request) {
+	var req serverstate.FinishRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.FinishConnect(req)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postConnectAdmin(w http.ResponseWriter, r *http.Request) {
+	var req connectAdminRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	result, err := h.state.AdminConnect(serverstate.AdminConnectRequest{
+		AdminPublicKey: req.AdminPublicKey,
+		IssuedAt:       req.IssuedAt,
+		Signature:      req.Signature,
+		ClientInfo:     req.ClientInfo,
+	})
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) putChannelsReadAll(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	markers, err := h.state.MarkAllChannelsRead(sessionToken)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"markers": markers})
+}
+
+func (h handlers) getChannelMessage(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	message, err := h.state.GetMessage(sessionToken, chi.URLParam(r, "channelID"), chi.URLParam(r, "messageID"))
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"message": message})
+}
+
+// getChannelMessageContext serves jump-to-message views; like the history
+// endpoints it accepts anonymous reads on public preview channels.
+func (h handlers) getChannelMessageContext(w http.ResponseWriter, r *http.Request) {
+	var sessionToken string
+	if strings.TrimSpace(r.Header.Get("Authorization")) != "" {
+		token, err := bearerTokenFromHeader(r)
+		if err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+		sessionToken = token
+	}
+
+	before, err := queryInt(r, "before", defaultMessageContext)
+	if err != nil {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_request", Message: "before must be an integer"})
+		return
+	}
+	after, err := queryInt(r, "after", defaultMessageContext)
+	if err != nil {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_request", Message: "after must be an integer"})
+		return
+	}
+
+	result, err := h.state.GetMessageContext(sessionToken, chi.URLParam(r, "channelID"), chi.URLParam(r, "messageID"), before, after)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) getChannelMessages(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "channelID")
+
+	// The session token is optional here: public preview channels allow anonymous reads.
+	var sessionToken string
+	if strings.TrimSpace(r.Header.Get("Authorization")) != "" {
+		token, err := bearerTokenFromHeader(r)
+		if err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+		sessionToken = token
+	}
+
+	query := r.URL.Query()
+	if query.Has("page") || query.Has("pageSize") {
+		if query.Has("limit") || query.Has("before") {
+			writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_request", Message: "page and pageSize cannot be combined with limit or before"})
+			return
+		}
+		page, err := queryInt(r, "page", 1)
+		if err != nil {
+			writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_page", Message: "page must be an integer"})
+			return
+		}
+		pageSize, err := queryInt(r, "pageSize", defaultMessagePageSize)
+		if err != nil {
+			writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_page_size", Message: "pageSize must be an integer"})
+			return
+		}
+
+		result, err := h.state.ListMessagesPage(sessionToken, channelID, page, pageSize)
+		if err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	limit := 100
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, parseErr := strconv.Atoi(raw)
+		if parseErr != nil {
+			writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_limit", Message: "limit must be an integer"})
+			return
+		}
+		limit = parsed
+	}
 
-func (h handlers) getServerInfo(w http.ResponseWriter, _ *http.Request) {
-	info := h.state.ServerInfo()
-	writeJSON(w, http.StatusOK, serverInfoResponse{
-		ServerID:                  info.ServerID,
-		Name:                      info.Name,
-		PublicKeyFingerprintEmoji: info.ServerFingerprint,
-		ServerFingerprint:         info.ServerFingerprint,
-		ServerPublicKey:           info.ServerPublicKey,
-		LiveKitURL:                info.LiveKitURL,
-		AdminPublicKeys:           info.AdminPublicKeys,
-	})
-}
+	var result serverstate.ListMessagesResult
+	var err error
+	if query.Has("before") {
+		result, err = h.state.ListMessagesBefore(sessionToken, channelID, query.Get("before"), limit)
+	} else {
+		result, err = h.state.ListMessages(sessionToken, channelID, limit)
+	}
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
 
-func (h handlers) getChannels(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]any{
-		"channels": h.state.Channels(),
-	})
+	writeJSON(w, http.StatusOK, result)
 }
 
-func (h handlers) postAdminInvites(w http.ResponseWriter, r *http.Request) {
-	if err := h.authorizeAdmin(r); err != nil {
-		writeAPIError(w, err)
+func (h handlers) postChannelMessage(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "channelID")
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	var req createInviteRequest
+	var req createMessageRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+		writeAPIError(w, r, err)
 		return
 	}
 
-	result, err := h.state.CreateInvite(strings.TrimSpace(req.ClientPublicKey), req.Label)
+	var message serverstate.ChannelMessage
+	if req.ContentEncrypted != "" && req.ContentMarkdown != "" {
+		err = errMixedMessageContent
+	} else {
+		message, err = h.state.PostMessage(sessionToken, channelID, serverstate.MessageInput{
+			ContentMarkdown:  req.ContentMarkdown,
+			ContentEncrypted: req.ContentEncrypted,
+			MessageType:      req.MessageType,
+		})
+	}
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
+	if limit, err := h.state.ChannelRateLimit(sessionToken, channelID); err == nil {
+		setRateLimitHeaders(w, limit, http.StatusOK)
+	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, map[string]any{"message": message})
 }
 
-func (h handlers) postAdminInvitesClientSigned(w http.ResponseWriter, r *http.Request) {
-	var req createInviteByClientRequest
+func (h handlers) patchChannelMessage(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "channelID")
+	messageID := chi.URLParam(r, "messageID")
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req editMessageRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+		writeAPIError(w, r, err)
 		return
 	}
 
-	result, err := h.state.CreateInviteByAdminClient(serverstate.CreateInviteByAdminClientRequest{
-		AdminPublicKey:  req.AdminPublicKey,
-		ClientPublicKey: req.ClientPublicKey,
-		Label:           req.Label,
-		IssuedAt:        req.IssuedAt,
-		Signature:       req.Signature,
-	})
+	var message serverstate.ChannelMessage
+	switch {
+	case req.ContentEncrypted == "":
+		message, err = h.state.EditMessage(sessionToken, channelID, messageID, req.ContentMarkdown)
+	case req.ContentMarkdown == "":
+		message, err = h.state.EditEncryptedMessage(sessionToken, channelID, messageID, req.ContentEncrypted)
+	default:
+		err = errMixedMessageContent
+	}
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, map[string]any{"message": message})
 }
 
-func (h handlers) postAdminInvitesListClientSigned(w http.ResponseWriter, r *http.Request) {
-	var req listInvitesByClientRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+func (h handlers) deleteChannelMessage(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	result, err := h.state.ListInvitesByAdminClient(serverstate.ListInvitesByAdminClientRequest{
-		AdminPublicKey: req.AdminPublicKey,
-		IssuedAt:       req.IssuedAt,
-		Signature:      req.Signature,
-	})
-	if err != nil {
-		writeAPIError(w, err)
+	if err := h.state.DeleteMessage(sessionToken, chi.URLParam(r, "channelID"), chi.URLParam(r, "messageID")); err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-func (h handlers) postConnectBegin(w http.ResponseWriter, r *http.Request) {
-	var req connectBeginRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+func (h handlers) getChannelDraft(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	result, err := h.state.BeginConnect(req.InviteID)
+	draft, err := h.state.GetDraft(sessionToken, chi.URLParam(r, "channelID"))
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, map[string]any{"draft": draft})
 }
 
-func (h handlers) postConnectFinish(w http.ResponseWriter, r *http.Request) {
-	var req serverstate.FinishRequest
+func (h handlers) putChannelDraft(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	var req saveDraftRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+		writeAPIError(w, r, err)
 		return
 	}
 
-	result, err := h.state.FinishConnect(req)
+	draft, err := h.state.SaveDraft(sessionToken, chi.URLParam(r, "channelID"), req.ContentMarkdown)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, map[string]any{"draft": draft})
 }
 
-func (h handlers) postConnectAdmin(w http.ResponseWriter, r *http.Request) {
-	var req connectAdminRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+func (h handlers) deleteChannelDraft(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	result, err := h.state.AdminConnect(serverstate.AdminConnectRequest{
-		AdminPublicKey: req.AdminPublicKey,
-		IssuedAt:       req.IssuedAt,
-		Signature:      req.Signature,
-		ClientInfo:     req.ClientInfo,
-	})
+	if err := h.state.DeleteDraft(sessionToken, chi.URLParam(r, "channelID")); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h handlers) postChannelTyping(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	if err := h.state.NotifyTyping(sessionToken, chi.URLParam(r, "channelID")); err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
-func (h handlers) getChannelMessages(w http.ResponseWriter, r *http.Request) {
+func (h handlers) postChannelMessageReport(w http.ResponseWriter, r *http.Request) {
 	channelID := chi.URLParam(r, "channelID")
+	messageID := chi.URLParam(r, "messageID")
 	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	limit := 100
-	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
-		parsed, parseErr := strconv.Atoi(raw)
-		if parseErr != nil {
-			writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_limit", Message: "limit must be an integer"})
+	// The reason is optional, so an empty body is accepted.
+	var req reportMessageRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeAPIError(w, r, err)
 			return
 		}
-		limit = parsed
 	}
 
-	result, err := h.state.ListMessages(sessionToken, channelID, limit)
+	result, err := h.state.ReportMessage(sessionToken, channelID, messageID, req.Reason)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
 	writeJSON(w, http.StatusOK, result)
 }
 
-func (h handlers) postChannelMessage(w http.ResponseWriter, r *http.Request) {
-	channelID := chi.URLParam(r, "channelID")
+func (h handlers) postMembersMeLeave(w http.ResponseWriter, r *http.Request) {
 	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	var req createMessageRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
-		return
+	var req leaveServerRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
 	}
 
-	message, err := h.state.CreateMessage(sessionToken, channelID, req.ContentMarkdown)
+	result, err := h.state.LeaveServer(sessionToken, req.PurgeMessages)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"message": message})
+	writeJSON(w, http.StatusOK, result)
 }
 
-func (h handlers) patchChannelMessage(w http.ResponseWriter, r *http.Request) {
-	channelID := chi.URLParam(r, "channelID")
-	messageID := chi.URLParam(r, "messageID")
+func (h handlers) getServerInfoMOTD(w http.ResponseWriter, r *http.Request) {
 	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	var req editMessageRequest
-	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+	result, err := h.state.GetMOTD(sessionToken)
+	if err != nil {
+		writeAPIError(w, r, err)
 		return
 	}
 
-	message, err := h.state.EditMessage(sessionToken, channelID, messageID, req.ContentMarkdown)
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h handlers) postServerInfoMOTDAck(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{"message": message})
+	var req motdAckRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			writeAPIError(w, r, err)
+			return
+		}
+	}
+
+	result, err := h.state.AcknowledgeMOTD(sessionToken, req.MotdUpdatedAt)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
 }
 
 func (h handlers) getChannelStream(w http.ResponseWriter, r *http.Request) {
 	channelID := chi.URLParam(r, "channelID")
 	token := strings.TrimSpace(r.URL.Query().Get("token"))
 	if token == "" {
-		writeAPIError(w, &serverstate.APIError{
+		writeAPIError(w, r, &serverstate.APIError{
 			Status:  http.StatusUnauthorized,
 			Code:    "missing_session_token",
 			Message: "session token is required",
 		})
 		return
 	}
+	codec, err := streamCodecFromRequest(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
 
 	stream, cancel, err := h.state.SubscribeChannelEvents(token, channelID)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 	defer cancel()
 
-	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	release, err := h.state.AcquireWebsocketSlot()
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	defer release()
+
+	conn, err := h.wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
-		writeAPIError(w, fmt.Errorf("upgrade websocket: %w", err))
+		writeAPIError(w, r, fmt.Errorf("upgrade websocket: %w", err))
 		return
 	}
 	defer conn.Close()
 
-	if err := conn.WriteJSON(serverstate.ChannelEvent{Type: "ready"}); err != nil {
+	if err := codec.write(conn, serverstate.ChannelEvent{Type: "ready"}); err != nil {
 		return
 	}
 
@@ -357,43 +1398,134 @@ func (h handlers) getChannelStream(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	revalidate := time.NewTicker(streamRevalidateInterval)
+	defer revalidate.Stop()
+
 	for {
 		select {
 		case <-done:
 			return
+		case <-revalidate.C:
+			if code, reason, ok := streamCloseCode(h.state.ValidateChannelStream(token, channelID)); ok {
+				writeCloseFrame(conn, code, reason)
+				return
+			}
 		case event, ok := <-stream:
 			if !ok {
+				// The server closes a stream when it shuts down or removes the member;
+				// the latter leaves no session behind.
+				if code, reason, ok := streamCloseCode(h.state.ValidateChannelStream(token, channelID)); ok {
+					writeCloseFrame(conn, code, reason)
+				} else {
+					writeCloseFrame(conn, wsCloseServerShutdown, "server_shutdown")
+				}
 				return
 			}
-			if err := conn.WriteJSON(event); err != nil {
+			if err := codec.write(conn, event); err != nil {
 				return
 			}
 		}
 	}
 }
 
+// getChannelPoll is the long-poll transport: it returns messages newer than
+// `since` right away, otherwise blocks until the next channel event or timeout.
+func (h handlers) getChannelPoll(w http.ResponseWriter, r *http.Request) {
+	channelID := chi.URLParam(r, "channelID")
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	timeoutSeconds, err := queryInt(r, "timeout", defaultPollTimeoutSeconds)
+	if err != nil || timeoutSeconds < 0 {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_timeout", Message: "timeout must be a non-negative integer"})
+		return
+	}
+	if timeoutSeconds > maxPollTimeoutSeconds {
+		timeoutSeconds = maxPollTimeoutSeconds
+	}
+
+	// Subscribe before the catch-up query so nothing posted in between is lost.
+	stream, cancel, err := h.state.SubscribeChannelEvents(sessionToken, channelID)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	defer cancel()
+
+	missed, reset, err := h.state.ListMessagesSince(sessionToken, channelID, r.URL.Query().Get("since"))
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	if reset {
+		writeJSON(w, http.StatusOK, channelPollResponse{Events: []serverstate.ChannelEvent{}, Reset: true})
+		return
+	}
+
+	events := make([]serverstate.ChannelEvent, 0, len(missed))
+	for i := range missed {
+		events = append(events, serverstate.ChannelEvent{Type: "message.created", Message: &missed[i]})
+	}
+	if len(events) > 0 {
+		writeJSON(w, http.StatusOK, channelPollResponse{Events: events})
+		return
+	}
+
+	timer := time.NewTimer(time.Duration(timeoutSeconds) * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-r.Context().Done():
+		return
+	case <-timer.C:
+	case event, ok := <-stream:
+		if ok {
+			events = append(events, event)
+		}
+	drain:
+		for {
+			select {
+			case event, ok := <-stream:
+				if !ok {
+					break drain
+				}
+				events = append(events, event)
+			default:
+				break drain
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, channelPollResponse{Events: events})
+}
+
 func (h handlers) postLiveKitToken(w http.ResponseWriter, r *http.Request) {
 	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
 	var req liveKitTokenRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+		writeAPIError(w, r, err)
 		return
 	}
 
+	// BeginVoiceJoin rejects missing, non-voice and inaccessible channels, so a
+	// token is never minted for a room the member may not join.
 	joinCtx, err := h.state.BeginVoiceJoin(sessionToken, req.ChannelID)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
 	issuer := livekittoken.NewTokenIssuer(h.cfg.LiveKitAPIKey, h.cfg.LiveKitAPISecret)
 	if !issuer.Enabled() {
-		writeAPIError(w, &serverstate.APIError{
+		writeAPIError(w, r, &serverstate.APIError{
 			Status:  http.StatusServiceUnavailable,
 			Code:    "livekit_unavailable",
 			Message: "livekit credentials are not configured on server",
@@ -406,7 +1538,7 @@ func (h handlers) postLiveKitToken(w http.ResponseWriter, r *http.Request) {
 		"channelId": joinCtx.ChannelID,
 	})
 	if err != nil {
-		writeAPIError(w, fmt.Errorf("encode livekit metadata: %w", err))
+		writeAPIError(w, r, fmt.Errorf("encode livekit metadata: %w", err))
 		return
 	}
 
@@ -417,7 +1549,7 @@ func (h handlers) postLiveKitToken(w http.ResponseWriter, r *http.Request) {
 		Metadata: string(metadataJSON),
 	})
 	if err != nil {
-		writeAPIError(w, fmt.Errorf("issue livekit token: %w", err))
+		writeAPIError(w, r, fmt.Errorf("issue livekit token: %w", err))
 		return
 	}
 
@@ -426,19 +1558,20 @@ func (h handlers) postLiveKitToken(w http.ResponseWriter, r *http.Request) {
 		RoomName:      joinCtx.RoomName,
 		ChannelID:     joinCtx.ChannelID,
 		ParticipantID: joinCtx.Identity.PublicKey,
+		ICEServers:    h.cfg.ICEServers,
 	})
 }
 
 func (h handlers) postLiveKitVoiceTouch(w http.ResponseWriter, r *http.Request) {
 	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
 	var req voiceTouchRequest
 	if err := decodeJSON(r, &req); err != nil {
-		writeAPIError(w, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()})
+		writeAPIError(w, r, err)
 		return
 	}
 
@@ -448,8 +1581,9 @@ func (h handlers) postLiveKitVoiceTouch(w http.ResponseWriter, r *http.Request)
 		CameraEnabled:      req.CameraEnabled,
 		ScreenEnabled:      req.ScreenEnabled,
 		ScreenAudioEnabled: req.ScreenAudioEnabled,
+		Status:             req.Status,
 	}); err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
@@ -459,12 +1593,12 @@ func (h handlers) postLiveKitVoiceTouch(w http.ResponseWriter, r *http.Request)
 func (h handlers) postLiveKitVoiceLeave(w http.ResponseWriter, r *http.Request) {
 	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
 	if err := h.state.LeaveVoiceChannel(sessionToken); err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
@@ -474,20 +1608,70 @@ func (h handlers) postLiveKitVoiceLeave(w http.ResponseWriter, r *http.Request)
 func (h handlers) getLiveKitVoiceChannelState(w http.ResponseWriter, r *http.Request) {
 	sessionToken, err := bearerTokenFromHeader(r)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
+	fields := strings.TrimSpace(r.URL.Query().Get("fields"))
+	if fields != "" && fields != "full" && fields != "minimal" {
+		writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_fields", Message: "fields must be minimal or full"})
+		return
+	}
+
+	var since time.Time
+	if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+		since, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeAPIError(w, r, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_since", Message: "since must be an RFC3339 timestamp"})
+			return
+		}
+	}
+
 	channelID := chi.URLParam(r, "channelID")
-	state, err := h.state.GetVoiceChannelState(sessionToken, channelID)
+	state, err := h.state.GetVoiceChannelState(sessionToken, channelID, since)
 	if err != nil {
-		writeAPIError(w, err)
+		writeAPIError(w, r, err)
 		return
 	}
 
+	if fields == "minimal" {
+		writeJSON(w, http.StatusOK, state.Summary())
+		return
+	}
 	writeJSON(w, http.StatusOK, state)
 }
 
+func (h handlers) getLiveKitVoiceStates(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	states, err := h.state.ListVoiceChannelStates(sessionToken)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, states)
+}
+
+func (h handlers) getLiveKitVoiceMe(w http.ResponseWriter, r *http.Request) {
+	sessionToken, err := bearerTokenFromHeader(r)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	participant, err := h.state.GetOwnVoiceState(sessionToken)
+	if err != nil {
+		writeAPIError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"participant": participant})
+}
+
 func (h handlers) serveWebApp(w http.ResponseWriter, r *http.Request) {
 	webDist := strings.TrimSpace(h.cfg.WebDistDir)
 	if webDist == "" {
@@ -507,6 +1691,9 @@ func (h handlers) serveWebApp(w http.ResponseWriter, r *http.Request) {
 
 	assetPath := filepath.Join(webDist, filepath.FromSlash(relPath))
 	if info, err := os.Stat(assetPath); err == nil && !info.IsDir() {
+		if cacheControl := h.webAssetCacheControl(relPath); cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
 		http.ServeFile(w, r, assetPath)
 		return
 	}
@@ -517,12 +1704,30 @@ func (h handlers) serveWebApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("Cache-Control", "no-cache")
 	http.ServeFile(w, r, indexPath)
 }
 
+// webAssetCacheControl picks the caching policy for a web app file. Files under
+// WEB_ASSET_PATH carry a content hash in their name, so they can be cached for
+// WEB_ASSET_MAX_AGE without revalidation; index.html must always be revalidated
+// so clients pick up new asset names after a deploy. Other files keep the
+// default caching.
+func (h handlers) webAssetCacheControl(relPath string) string {
+	if relPath == "index.html" {
+		return "no-cache"
+	}
+	assetDir := h.cfg.WebAssetPath
+	if assetDir != "" && h.cfg.WebAssetMaxAgeSec > 0 && strings.HasPrefix(relPath, assetDir+"/") {
+		return fmt.Sprintf("public, max-age=%d, immutable", h.cfg.WebAssetMaxAgeSec)
+	}
+	return ""
+}
+
 func (h handlers) authorizeAdmin(r *http.Request) error {
-	token := strings.TrimSpace(h.cfg.AdminToken)
-	if token == "" {
+	if len(h.cfg.AdminTokens) == 0 {
+		// Only the token paths are disabled; client-signed admin endpoints keep
+		// working for adminPublicKeys.
 		return &serverstate.APIError{Status: http.StatusServiceUnavailable, Code: "admin_disabled", Message: "ADMIN_TOKEN is not configured"}
 	}
 
@@ -532,13 +1737,23 @@ func (h handlers) authorizeAdmin(r *http.Request) error {
 		return &serverstate.APIError{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "missing bearer token"}
 	}
 
-	if strings.TrimSpace(strings.TrimPrefix(header, prefix)) != token {
+	if !matchesAdminToken(h.cfg.AdminTokens, strings.TrimSpace(strings.TrimPrefix(header, prefix))) {
 		return &serverstate.APIError{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "invalid admin token"}
 	}
 
 	return nil
 }
 
+// matchesAdminToken compares against every configured token in constant time,
+// so neither the match position nor a shared prefix shows in the timing.
+func matchesAdminToken(tokens []string, candidate string) bool {
+	matched := 0
+	for _, token := range tokens {
+		matched |= subtle.ConstantTimeCompare([]byte(token), []byte(candidate))
+	}
+	return matched == 1
+}
+
 func bearerTokenFromHeader(r *http.Request) (string, error) {
 	header := strings.TrimSpace(r.Header.Get("Authorization"))
 	prefix := "Bearer "
@@ -553,26 +1768,116 @@ func bearerTokenFromHeader(r *http.Request) (string, error) {
 	return token, nil
 }
 
+func queryInt(r *http.Request, key string, fallback int) (int, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(raw)
+}
+
 func decodeJSON(r *http.Request, out any) error {
 	defer r.Body.Close()
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(out); err != nil {
-		return err
+		return decodeError(err)
 	}
 	return nil
 }
 
-func writeAPIError(w http.ResponseWriter, err error) {
+// decodeError distinguishes well-formed bodies with a bad field (unknown or of the
+// wrong type) from bodies that are not valid JSON at all.
+func decodeError(err error) *serverstate.APIError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field := typeErr.Field
+		if field == "" {
+			return &serverstate.APIError{
+				Status:  http.StatusBadRequest,
+				Code:    "invalid_json",
+				Message: fmt.Sprintf("request body must be a JSON object, got %s", typeErr.Value),
+			}
+		}
+		return &serverstate.APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_field",
+			Message: fmt.Sprintf("field %q must be %s, got %s", field, jsonTypeName(typeErr.Type.Kind()), typeErr.Value),
+			Details: map[string]any{
+				"field":    field,
+				"reason":   "type_mismatch",
+				"expected": jsonTypeName(typeErr.Type.Kind()),
+				"actual":   typeErr.Value,
+			},
+		}
+	}
+
+	// encoding/json reports unknown fields with an untyped error.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return &serverstate.APIError{
+			Status:  http.StatusBadRequest,
+			Code:    "invalid_field",
+			Message: fmt.Sprintf("unknown field %q", field),
+			Details: map[string]any{
+				"field":  field,
+				"reason": "unknown_field",
+			},
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		return &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: "request body is empty"}
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &serverstate.APIError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    "payload_too_large",
+			Message: fmt.Sprintf("request body must be at most %d bytes", maxBytesErr.Limit),
+		}
+	}
+	return &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_json", Message: err.Error()}
+}
+
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// writeAPIError renders err with the request ID set by middleware.RequestID, so
+// an error code reported by a client can be matched to the server log line.
+func writeAPIError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := middleware.GetReqID(r.Context())
+	err = serverstate.MapStorageError(err)
+
 	var apiErr *serverstate.APIError
 	if errors.As(err, &apiErr) {
-		writeJSON(w, apiErr.Status, errorResponse{Error: apiErr.Code, Message: apiErr.Message})
+		setRateLimitHeaders(w, apiErr.RateLimit, apiErr.Status)
+		if apiErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((apiErr.RetryAfter+time.Second-1)/time.Second)))
+		}
+		writeJSON(w, apiErr.Status, errorResponse{Error: apiErr.Code, Message: apiErr.Message, Details: apiErr.Details, RequestID: requestID})
 		return
 	}
 
+	slog.Error("request failed", "request_id", requestID, "method", r.Method, "path", r.URL.Path, "error", err)
 	writeJSON(w, http.StatusInternalServerError, errorResponse{
-		Error:   "internal_error",
-		Message: fmt.Sprintf("internal error: %v", err),
+		Error:     "internal_error",
+		Message:   fmt.Sprintf("internal error: %v", err),
+		RequestID: requestID,
 	})
 }
 
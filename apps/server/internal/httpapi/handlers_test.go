@@ -0,0 +1,110 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestServeWebAppSetsCacheHeaders(t *testing.T) {
+	dist := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dist, "assets"), 0o755); err != nil {
+		t.Fatalf("create assets dir failed: %v", err)
+	}
+	for name, content := range map[string]string{
+		"index.html":             "<html></html>",
+		"favicon.ico":            "icon",
+		"assets/index-3f9a1c.js": "console.log(1)",
+	} {
+		if err := os.WriteFile(filepath.Join(dist, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s failed: %v", name, err)
+		}
+	}
+
+	h := handlers{cfg: config.Config{WebDistDir: dist, WebAssetPath: "assets", WebAssetMaxAgeSec: 3600}}
+	cases := map[string]string{
+		"/":                       "no-cache",
+		"/channels/general":       "no-cache",
+		"/assets/index-3f9a1c.js": "public, max-age=3600, immutable",
+		"/favicon.ico":            "",
+	}
+	for target, want := range cases {
+		recorder := httptest.NewRecorder()
+		h.serveWebApp(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status %d", target, recorder.Code)
+		}
+		if got := recorder.Header().Get("Cache-Control"); got != want {
+			t.Fatalf("%s: Cache-Control got=%q want=%q", target, got, want)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	h.serveWebApp(recorder, httptest.NewRequest(http.MethodGet, "/api/unknown", nil))
+	if recorder.Code != http.StatusNotFound || recorder.Header().Get("Cache-Control") != "" {
+		t.Fatalf("api paths must not be served by the web app, got %d %q", recorder.Code, recorder.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAuthorizeAdminAcceptsEveryConfiguredToken(t *testing.T) {
+	h := handlers{cfg: config.Config{AdminTokens: []string{"old-token", "new-token"}}}
+	cases := map[string]bool{
+		"Bearer old-token":  true,
+		"Bearer new-token":  true,
+		"Bearer new-token2": false,
+		"Bearer new":        false,
+		"Bearer ":           false,
+		"":                  false,
+	}
+	for header, allowed := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		if err := h.authorizeAdmin(req); (err == nil) != allowed {
+			t.Fatalf("%q: unexpected result %v", header, err)
+		}
+	}
+
+	h.cfg.AdminTokens = nil
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	if err := h.authorizeAdmin(req); err == nil {
+		t.Fatal("expected admin endpoints to be disabled without tokens")
+	}
+}
+
+func TestAdminInvitesRequireKeyUnlessUnbound(t *testing.T) {
+	router, _ := newTestRouter(t, func(cfg *config.Config) { cfg.AdminTokens = []string{"admin-token"} })
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/invites", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer admin-token")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	requireCode := func(recorder *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+		if recorder.Code != status || body.Error != code {
+			t.Fatalf("unexpected response: %d %s", recorder.Code, recorder.Body.String())
+		}
+	}
+
+	requireCode(post(`{"label": "missing"}`), http.StatusBadRequest, "invalid_client_public_key")
+	requireCode(post(`{"clientPublicKey": "  "}`), http.StatusBadRequest, "invalid_client_public_key")
+	requireCode(post(`{"clientPublicKey": "AAAA", "unbound": true}`), http.StatusBadRequest, "invalid_client_public_key")
+	if recorder := post(`{"unbound": true, "label": "open"}`); recorder.Code != http.StatusOK {
+		t.Fatalf("unbound invite must be created: %d %s", recorder.Code, recorder.Body.String())
+	}
+}
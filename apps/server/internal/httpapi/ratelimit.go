@@ -0,0 +1,30 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"fosscord/apps/server/internal/serverstate"
+)
+
+// setRateLimitHeaders emits X-RateLimit-Limit/Remaining/Reset (Unix seconds) for
+// throttled routes, plus Retry-After when the request was rejected with 429.
+func setRateLimitHeaders(w http.ResponseWriter, limit *serverstate.RateLimit, status int) {
+	if limit == nil {
+		return
+	}
+
+	header := w.Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(limit.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(limit.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(limit.Reset.Unix(), 10))
+
+	if status == http.StatusTooManyRequests {
+		retryAfter := int((time.Until(limit.Reset) + time.Second - 1) / time.Second)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		header.Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+}
@@ -0,0 +1,78 @@
+package httpapi
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// realIP replaces middleware.RealIP, which trusts X-Forwarded-For and
+// X-Real-IP from anyone. Forwarded headers are only honored when the
+// immediate peer is one of the trusted proxies; otherwise RemoteAddr is left
+// as the connection's own address so clients cannot spoof it.
+func realIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer, ok := remoteAddrIP(r.RemoteAddr); ok && isTrustedProxy(trusted, peer) {
+				if client, ok := forwardedClientIP(r.Header, trusted); ok {
+					r.RemoteAddr = client.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClientIP walks X-Forwarded-For from the nearest hop outwards and
+// returns the first address that is not itself a trusted proxy, falling back
+// to X-Real-IP when no usable X-Forwarded-For is present.
+func forwardedClientIP(header http.Header, trusted []netip.Prefix) (netip.Addr, bool) {
+	var hops []string
+	for _, value := range header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	var outermost netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Anything beyond an unparseable hop was written by the client.
+			break
+		}
+		addr = addr.Unmap()
+		if !isTrustedProxy(trusted, addr) {
+			return addr, true
+		}
+		outermost = addr
+	}
+	if outermost.IsValid() {
+		return outermost, true
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+func remoteAddrIP(remoteAddr string) (netip.Addr, bool) {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrustedProxy(trusted []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
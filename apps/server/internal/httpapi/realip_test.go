@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIPOnlyTrustsConfiguredProxies(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	var seen string
+	handler := realIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.RemoteAddr
+	}))
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		header     map[string]string
+		want       string
+	}{
+		{
+			name:       "spoofed forwarded-for from untrusted peer",
+			remoteAddr: "203.0.113.7:51000",
+			header:     map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "203.0.113.7:51000",
+		},
+		{
+			name:       "spoofed real-ip from untrusted peer",
+			remoteAddr: "203.0.113.7:51000",
+			header:     map[string]string{"X-Real-IP": "198.51.100.1"},
+			want:       "203.0.113.7:51000",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.2:40000",
+			header:     map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "client-prepended hop behind trusted proxies",
+			remoteAddr: "10.0.0.2:40000",
+			header:     map[string]string{"X-Forwarded-For": "192.0.2.99, 198.51.100.1, 10.0.0.3"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "trusted proxy with real-ip only",
+			remoteAddr: "10.0.0.2:40000",
+			header:     map[string]string{"X-Real-IP": "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "10.0.0.2:40000",
+			want:       "10.0.0.2:40000",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for key, value := range tc.header {
+				req.Header.Set(key, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if seen != tc.want {
+				t.Fatalf("unexpected remote address: got=%q want=%q", seen, tc.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"fosscord/apps/server/internal/config"
+	livekittoken "fosscord/apps/server/internal/livekit"
 	"fosscord/apps/server/internal/serverstate"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -12,52 +13,95 @@ import (
 )
 
 func NewRouter(cfg config.Config, state *serverstate.State) http.Handler {
-	h := handlers{cfg: cfg, state: state}
+	h := handlers{
+		cfg:   cfg,
+		state: state,
+		liveKitHealth: livekittoken.NewHealthChecker(
+			cfg.LiveKitURL,
+			livekittoken.NewTokenIssuer(cfg.LiveKitAPIKey, cfg.LiveKitAPISecret).Enabled(),
+		),
+		wsUpgrader: newWSUpgrader(cfg),
+	}
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(requestIDHeader)
+	r.Use(realIP(cfg.TrustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins: []string{
-			"http://localhost:1420",
-			"http://127.0.0.1:1420",
-			"http://localhost:5173",
-			"http://127.0.0.1:5173",
-			"http://localhost:8088",
-			"http://127.0.0.1:8088",
-			"http://localhost:3000",
-			"http://127.0.0.1:3000",
-			"tauri://localhost",
-			"https://tauri.localhost",
-		},
-		AllowedMethods: []string{"GET", "POST", "PATCH", "OPTIONS"},
-		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type"},
-		MaxAge:         300,
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Request-Id"},
+		MaxAge:           300,
 	}))
 
 	r.Get("/health", h.getHealth)
 	r.Route("/api", func(api chi.Router) {
 		api.Get("/server-info", h.getServerInfo)
+		api.Get("/server-info/public-key", h.getServerPublicKey)
+		api.Get("/limits", h.getLimits)
+		api.Get("/server-info/motd", h.getServerInfoMOTD)
+		api.Post("/server-info/motd/ack", h.postServerInfoMOTDAck)
+		api.Post("/server/sign", h.postServerSign)
 		api.Get("/channels", h.getChannels)
+		api.Put("/channels/read-all", h.putChannelsReadAll)
 		api.Route("/channels/{channelID}", func(channel chi.Router) {
+			channel.Patch("/", h.patchChannel)
 			channel.Get("/messages", h.getChannelMessages)
 			channel.Post("/messages", h.postChannelMessage)
+			channel.Post("/typing", h.postChannelTyping)
+			channel.Get("/messages/{messageID}", h.getChannelMessage)
+			channel.Get("/messages/{messageID}/context", h.getChannelMessageContext)
 			channel.Patch("/messages/{messageID}", h.patchChannelMessage)
+			channel.Delete("/messages/{messageID}", h.deleteChannelMessage)
+			channel.Post("/messages/{messageID}/report", h.postChannelMessageReport)
 			channel.Get("/stream", h.getChannelStream)
+			channel.Get("/poll", h.getChannelPoll)
+			channel.Get("/draft", h.getChannelDraft)
+			channel.Put("/draft", h.putChannelDraft)
+			channel.Delete("/draft", h.deleteChannelDraft)
 		})
+		api.Post("/members/me/leave", h.postMembersMeLeave)
 		api.Post("/connect/begin", h.postConnectBegin)
 		api.Post("/connect/finish", h.postConnectFinish)
+		api.Post("/connect/parse-link", h.postConnectParseLink)
+		api.Get("/connect/invites", h.getConnectInvites)
 		api.Post("/connect/admin", h.postConnectAdmin)
 		api.Route("/admin", func(admin chi.Router) {
 			admin.Post("/invites", h.postAdminInvites)
 			admin.Post("/invites/client-signed", h.postAdminInvitesClientSigned)
 			admin.Post("/invites/list/client-signed", h.postAdminInvitesListClientSigned)
+			admin.Get("/invites/export", h.getAdminInvitesExport)
+			admin.Post("/invites/import", h.postAdminInvitesImport)
+			admin.Get("/members", h.getAdminMembers)
+			admin.Post("/members", h.postAdminMembers)
+			admin.Get("/members/export", h.getAdminMembersExport)
+			admin.Post("/members/roles", h.postAdminMemberRoles)
+			admin.Post("/members/kick", h.postAdminMemberKick)
+			admin.Get("/connect/diagnostics", h.getAdminConnectDiagnostics)
+			admin.Post("/channels", h.postAdminChannels)
+			admin.Get("/channels/stats", h.getAdminChannelStats)
+			admin.Patch("/channels/{channelID}", h.patchAdminChannel)
+			admin.Delete("/channels/{channelID}", h.deleteAdminChannel)
+			admin.Post("/channels/{channelID}/import", h.postAdminChannelImport)
+			admin.Post("/voice/channels/{channelID}/clear", h.postAdminVoiceChannelClear)
+			admin.Get("/reports", h.getAdminReports)
+			admin.Get("/stats", h.getAdminStats)
+			admin.Post("/maintenance", h.postAdminMaintenance)
+			admin.Patch("/server", h.patchAdminServer)
+			admin.Patch("/server/motd", h.patchAdminServerMOTD)
+			admin.Get("/config/export", h.getAdminConfigExport)
+			admin.Get("/config/effective", h.getAdminConfigEffective)
+			admin.Post("/config/import", h.postAdminConfigImport)
 		})
 		api.Post("/livekit/token", h.postLiveKitToken)
 		api.Post("/livekit/voice/touch", h.postLiveKitVoiceTouch)
 		api.Post("/livekit/voice/leave", h.postLiveKitVoiceLeave)
+		api.Get("/livekit/voice/me", h.getLiveKitVoiceMe)
+		api.Get("/livekit/voice/state", h.getLiveKitVoiceStates)
 		api.Get("/livekit/voice/channels/{channelID}/state", h.getLiveKitVoiceChannelState)
 	})
 
@@ -68,3 +112,14 @@ func NewRouter(cfg config.Config, state *serverstate.State) http.Handler {
 
 	return r
 }
+
+// requestIDHeader echoes the ID assigned by middleware.RequestID on every
+// response, including successful ones, for correlation with server logs.
+func requestIDHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+			w.Header().Set(middleware.RequestIDHeader, requestID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
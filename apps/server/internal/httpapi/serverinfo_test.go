@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+	"fosscord/apps/server/internal/serverstate"
+)
+
+// newTestRouter serves a fresh state. configure runs before the state loads, so
+// it may also seed files such as server_config.json in cfg.DataDir.
+func newTestRouter(t *testing.T, configure func(*config.Config)) (http.Handler, *serverstate.State) {
+	t.Helper()
+
+	cfg := config.Config{
+		ServerName:          "Test Server",
+		DataDir:             t.TempDir(),
+		ServerPublicBaseURL: "http://localhost:8080",
+		SQLiteBusyTimeoutMS: 5000,
+		SQLiteCacheSize:     -2000,
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	state, err := serverstate.New(cfg)
+	if err != nil {
+		t.Fatalf("create state failed: %v", err)
+	}
+	t.Cleanup(func() { _ = state.Close() })
+	return NewRouter(cfg, state), state
+}
+
+func getServerInfo(t *testing.T, router http.Handler, out any) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/server-info", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected server info status: %d %s", recorder.Code, recorder.Body.String())
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+		t.Fatalf("decode server info failed: %v", err)
+	}
+}
+
+func TestServerInfoReportsVoiceLimits(t *testing.T) {
+	router, _ := newTestRouter(t, func(cfg *config.Config) {
+		cfg.MaxAudioStreams = 4
+		cfg.MaxVideoStreams = 2
+	})
+
+	var info struct {
+		VoiceLimits serverstate.VoiceLimits `json:"voiceLimits"`
+	}
+	getServerInfo(t, router, &info)
+	if info.VoiceLimits != (serverstate.VoiceLimits{MaxAudioStreams: 4, MaxVideoStreams: 2}) {
+		t.Fatalf("unexpected voice limits: %+v", info.VoiceLimits)
+	}
+}
+
+func TestServerInfoReportsMotd(t *testing.T) {
+	router, state := newTestRouter(t, nil)
+	motd, err := state.SetMOTD("Maintenance on Friday")
+	if err != nil {
+		t.Fatalf("set motd failed: %v", err)
+	}
+
+	var info struct {
+		Motd          string `json:"motd"`
+		MotdUpdatedAt string `json:"motdUpdatedAt"`
+	}
+	getServerInfo(t, router, &info)
+	if info.Motd != motd.Motd || info.MotdUpdatedAt != motd.MotdUpdatedAt {
+		t.Fatalf("unexpected motd in server info: %+v", info)
+	}
+}
+
+func TestServerInfoReportsDefaultChannel(t *testing.T) {
+	router, _ := newTestRouter(t, func(cfg *config.Config) { cfg.DefaultChannelID = "general" })
+
+	var info struct {
+		DefaultChannelID string `json:"defaultChannelId"`
+	}
+	getServerInfo(t, router, &info)
+	if info.DefaultChannelID != "general" {
+		t.Fatalf("unexpected default channel: %q", info.DefaultChannelID)
+	}
+}
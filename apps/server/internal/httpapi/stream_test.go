@@ -0,0 +1,115 @@
+package httpapi
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+	"fosscord/apps/server/internal/serverstate"
+	"github.com/gorilla/websocket"
+)
+
+// connectTestSession runs the invite handshake for a fresh key and returns the
+// key and its session token.
+func connectTestSession(t *testing.T, state *serverstate.State) (string, string) {
+	t.Helper()
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	invite, err := state.CreateInvite(publicKey, "test", nil, false)
+	if err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+	begin, err := state.BeginConnect(invite.InviteID)
+	if err != nil {
+		t.Fatalf("begin connect failed: %v", err)
+	}
+	challenge, _ := base64.StdEncoding.DecodeString(begin.Challenge)
+	hash := serverstate.SignaturePayloadHash(challenge, invite.InviteID, begin.ServerFingerprint)
+	finish, err := state.FinishConnect(serverstate.FinishRequest{
+		InviteID:        invite.InviteID,
+		ClientPublicKey: publicKey,
+		Challenge:       begin.Challenge,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(priv, hash[:])),
+		ClientInfo:      serverstate.ClientInfo{DisplayName: "member"},
+	})
+	if err != nil {
+		t.Fatalf("finish connect failed: %v", err)
+	}
+	return publicKey, finish.SessionToken
+}
+
+func TestChannelStreamEnforcesWebsocketLimit(t *testing.T) {
+	router, state := newTestRouter(t, func(cfg *config.Config) { cfg.MaxWSConnections = 1 })
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	_, token := connectTestSession(t, state)
+	streamURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/channels/general/stream?token=" + token
+	dial := func() (*websocket.Conn, *http.Response, error) {
+		return websocket.DefaultDialer.Dial(streamURL, nil)
+	}
+
+	first, _, err := dial()
+	if err != nil {
+		t.Fatalf("first stream failed: %v", err)
+	}
+	if _, _, err := first.ReadMessage(); err != nil {
+		t.Fatalf("read ready event failed: %v", err)
+	}
+
+	_, resp, err := dial()
+	if err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 over the limit, got %v %+v", err, resp)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "too_many_connections") {
+		t.Fatalf("unexpected limit response: %s", body)
+	}
+
+	_ = first.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for state.Stats().Websockets.Active != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("closing the stream must free its slot")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	again, _, err := dial()
+	if err != nil {
+		t.Fatalf("stream after release failed: %v", err)
+	}
+	_ = again.Close()
+}
+
+func TestChannelStreamClosesWhenMemberIsKicked(t *testing.T) {
+	router, state := newTestRouter(t, nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	publicKey, token := connectTestSession(t, state)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/channels/general/stream?token="+token, nil)
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	defer conn.Close()
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("read ready event failed: %v", err)
+	}
+
+	if _, err := state.KickMember(publicKey, nil); err != nil {
+		t.Fatalf("kick failed: %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, wsCloseSessionExpired) {
+		t.Fatalf("expected close code %d, got %v", wsCloseSessionExpired, err)
+	}
+}
@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"fosscord/apps/server/internal/serverstate"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// streamCodec encodes channel events for one websocket. JSON goes out as text
+// frames, MessagePack as binary frames carrying the same structure.
+type streamCodec struct {
+	messageType int
+	marshal     func(any) ([]byte, error)
+}
+
+var streamCodecs = map[string]streamCodec{
+	"json":    {messageType: websocket.TextMessage, marshal: json.Marshal},
+	"msgpack": {messageType: websocket.BinaryMessage, marshal: marshalMsgpack},
+}
+
+// marshalMsgpack encodes v with the keys and omitempty rules of its json tags,
+// so both encodings carry the same structure.
+func marshalMsgpack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// streamCodecFromRequest picks the codec from ?encoding=, JSON by default.
+func streamCodecFromRequest(r *http.Request) (streamCodec, error) {
+	encoding := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("encoding")))
+	if encoding == "" {
+		encoding = "json"
+	}
+	codec, ok := streamCodecs[encoding]
+	if !ok {
+		return streamCodec{}, &serverstate.APIError{Status: http.StatusBadRequest, Code: "invalid_encoding", Message: "encoding must be json or msgpack"}
+	}
+	return codec, nil
+}
+
+func (c streamCodec) write(conn *websocket.Conn, event serverstate.ChannelEvent) error {
+	payload, err := c.marshal(event)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(c.messageType, payload)
+}
@@ -0,0 +1,56 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"fosscord/apps/server/internal/serverstate"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMsgpackStreamCodecRoundTripsEvents(t *testing.T) {
+	codec, err := streamCodecFromRequest(httptest.NewRequest("GET", "/stream?encoding=msgpack", nil))
+	if err != nil || codec.messageType != websocket.BinaryMessage {
+		t.Fatalf("expected the binary codec, got %+v, %v", codec, err)
+	}
+
+	event := serverstate.ChannelEvent{
+		Type: "message.updated",
+		Message: &serverstate.ChannelMessage{
+			ID:              "m1",
+			ChannelID:       "general",
+			Author:          serverstate.MessageAuthor{DisplayName: "alice", PublicKey: "key"},
+			ContentMarkdown: "hello",
+			Edited:          true,
+			CreatedAt:       "2024-01-01T00:00:00Z",
+			UpdatedAt:       "2024-01-01T00:01:00Z",
+			MessageType:     "normal",
+		},
+	}
+	encoded, err := codec.marshal(event)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := msgpack.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	// Both encodings must carry the same structure: compare their canonical JSON.
+	fromMsgpack, _ := json.Marshal(decoded)
+	raw, _ := json.Marshal(event)
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		t.Fatalf("decode json failed: %v", err)
+	}
+	fromJSON, _ := json.Marshal(generic)
+	if string(fromMsgpack) != string(fromJSON) {
+		t.Fatalf("msgpack event differs from json:\n%s\n%s", fromMsgpack, fromJSON)
+	}
+
+	if _, err := streamCodecFromRequest(httptest.NewRequest("GET", "/stream?encoding=xml", nil)); err == nil {
+		t.Fatal("expected an unknown encoding to be rejected")
+	}
+}
@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"errors"
+	"time"
+
+	"fosscord/apps/server/internal/serverstate"
+	"github.com/gorilla/websocket"
+)
+
+// Application close codes sent on channel streams after the upgrade, when an
+// HTTP error response is no longer possible. Clients re-authenticate on 4001,
+// refresh their channel list on 4003/4004 and reconnect with backoff on 4503.
+const (
+	wsCloseSessionExpired   = 4001
+	wsCloseChannelForbidden = 4003
+	wsCloseChannelDeleted   = 4004
+	wsCloseServerShutdown   = 4503
+)
+
+const streamRevalidateInterval = 30 * time.Second
+
+// streamCloseCode maps a revalidation failure to a close code and reason.
+// Errors that are not API errors (e.g. database failures) keep the stream open.
+func streamCloseCode(err error) (int, string, bool) {
+	var apiErr *serverstate.APIError
+	if !errors.As(err, &apiErr) {
+		return 0, "", false
+	}
+
+	switch apiErr.Code {
+	case "missing_session_token", "invalid_session_token":
+		return wsCloseSessionExpired, "session_expired", true
+	case "channel_forbidden":
+		return wsCloseChannelForbidden, "channel_forbidden", true
+	case "channel_not_found", "invalid_channel_type":
+		return wsCloseChannelDeleted, "channel_deleted", true
+	default:
+		return 0, "", false
+	}
+}
+
+func writeCloseFrame(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second),
+	)
+}
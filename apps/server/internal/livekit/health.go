@@ -0,0 +1,103 @@
+package livekit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HealthOK          = "ok"
+	HealthUnreachable = "unreachable"
+	HealthDisabled    = "disabled"
+
+	healthCheckTimeout  = 2 * time.Second
+	healthCheckCacheTTL = 10 * time.Second
+)
+
+// HealthChecker probes the configured LiveKit server and caches the result briefly
+// so frequent health checks don't turn into a request per probe against LiveKit.
+type HealthChecker struct {
+	url     string
+	enabled bool
+	client  *http.Client
+
+	mu        sync.Mutex
+	status    string
+	checkedAt time.Time
+	// probing is closed when the probe in flight finishes; nil while idle.
+	probing chan struct{}
+}
+
+func NewHealthChecker(url string, enabled bool) *HealthChecker {
+	return &HealthChecker{
+		url:     strings.TrimSpace(url),
+		enabled: enabled,
+		client:  &http.Client{Timeout: healthCheckTimeout},
+	}
+}
+
+func (c *HealthChecker) Status(ctx context.Context) string {
+	if !c.enabled || c.url == "" {
+		return HealthDisabled
+	}
+
+	for {
+		c.mu.Lock()
+		if c.status != "" && time.Since(c.checkedAt) < healthCheckCacheTTL {
+			status := c.status
+			c.mu.Unlock()
+			return status
+		}
+		if c.probing == nil {
+			break
+		}
+		// Another request is probing; share its result instead of probing too.
+		probing := c.probing
+		c.mu.Unlock()
+		select {
+		case <-probing:
+		case <-ctx.Done():
+			return HealthUnreachable
+		}
+	}
+	probing := make(chan struct{})
+	c.probing = probing
+	c.mu.Unlock()
+
+	// The probe runs unlocked so one slow LiveKit call does not hold up
+	// requests that only need the cached status.
+	status := c.probe(ctx)
+
+	c.mu.Lock()
+	// A probe cut short by the caller's own context says nothing about LiveKit.
+	if ctx.Err() == nil {
+		c.status = status
+		c.checkedAt = time.Now()
+	}
+	c.probing = nil
+	close(probing)
+	c.mu.Unlock()
+	return status
+}
+
+func (c *HealthChecker) probe(ctx context.Context) string {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.url, nil)
+	if err != nil {
+		return HealthUnreachable
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return HealthUnreachable
+	}
+	_ = resp.Body.Close()
+
+	// Any HTTP response means the LiveKit server is up and answering.
+	return HealthOK
+}
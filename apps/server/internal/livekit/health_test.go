@@ -0,0 +1,52 @@
+package livekit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckerDoesNotCacheCancelledProbes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	checker := NewHealthChecker(server.URL, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if status := checker.Status(ctx); status != HealthUnreachable {
+		t.Fatalf("expected cancelled probe to report unreachable, got %q", status)
+	}
+	if status := checker.Status(context.Background()); status != HealthOK {
+		t.Fatalf("cancelled probe must not be cached, got %q", status)
+	}
+}
+
+func TestHealthCheckerDoesNotBlockOnSlowProbe(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	checker := NewHealthChecker(server.URL, true)
+
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		checker.Status(context.Background())
+	}()
+	<-started
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	begin := time.Now()
+	if status := checker.Status(ctx); status != HealthUnreachable {
+		t.Fatalf("expected waiting request to give up with its context, got %q", status)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("request waited %v behind the slow probe", elapsed)
+	}
+}
@@ -0,0 +1,34 @@
+package livekit
+
+import (
+	"testing"
+
+	livekitauth "github.com/livekit/protocol/auth"
+)
+
+func TestTokenIssuerSignsWithItsKeyPair(t *testing.T) {
+	token, err := NewTokenIssuer(" newkey ", " new-secret ").IssueVoiceToken(VoiceTokenInput{RoomName: "voice-main", Identity: "member"})
+	if err != nil {
+		t.Fatalf("issue token failed: %v", err)
+	}
+
+	verifier, err := livekitauth.ParseAPIToken(token)
+	if err != nil {
+		t.Fatalf("parse token failed: %v", err)
+	}
+	// LiveKit picks the secret by the token's key, so a rotated key must name itself.
+	if verifier.APIKey() != "newkey" {
+		t.Fatalf("unexpected api key: %q", verifier.APIKey())
+	}
+	_, grants, err := verifier.Verify("new-secret")
+	if err != nil {
+		t.Fatalf("token must verify with its key's secret: %v", err)
+	}
+	if grants.Identity != "member" || grants.Video == nil || grants.Video.Room != "voice-main" {
+		t.Fatalf("unexpected grants: %+v", grants)
+	}
+
+	if NewTokenIssuer("newkey", "").Enabled() {
+		t.Fatal("an issuer without a secret must be disabled")
+	}
+}
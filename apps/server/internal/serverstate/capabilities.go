@@ -0,0 +1,34 @@
+package serverstate
+
+import "fosscord/apps/server/internal/config"
+
+// capabilities lists every optional feature clients can discover through
+// /api/server-info. A feature adds its entry here when it lands, with a check
+// derived from config; features that are not listed are not supported.
+var capabilities = []struct {
+	name    string
+	enabled func(cfg config.Config) bool
+}{
+	{"voice", func(cfg config.Config) bool { return cfg.LiveKitAPIKey != "" && cfg.LiveKitAPISecret != "" }},
+	{"e2ee", func(config.Config) bool { return true }},
+	{"publicPreview", func(cfg config.Config) bool { return cfg.PublicPreview }},
+	{"drafts", func(config.Config) bool { return true }},
+	{"readMarkers", func(config.Config) bool { return true }},
+	{"motdAck", func(config.Config) bool { return true }},
+	{"typingIndicators", func(config.Config) bool { return true }},
+	{"historyCursors", func(config.Config) bool { return true }},
+	{"msgpackStreams", func(config.Config) bool { return true }},
+	{"bulkVoiceState", func(config.Config) bool { return true }},
+	{"messageDeletion", func(config.Config) bool { return true }},
+	{"reactions", func(config.Config) bool { return false }},
+	{"attachments", func(config.Config) bool { return false }},
+	{"search", func(config.Config) bool { return false }},
+}
+
+func (s *State) capabilitiesLocked() map[string]bool {
+	result := make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		result[capability.name] = capability.enabled(s.cfg)
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestServerInfoCapabilitiesFollowConfig(t *testing.T) {
+	s := newTestState(t, nil)
+	capabilities := s.ServerInfo().Capabilities
+	if capabilities["voice"] || capabilities["publicPreview"] || capabilities["reactions"] {
+		t.Fatalf("expected voice, public preview and reactions to be off, got %v", capabilities)
+	}
+	for _, name := range []string{"e2ee", "historyCursors", "msgpackStreams", "bulkVoiceState", "messageDeletion"} {
+		if !capabilities[name] {
+			t.Fatalf("expected %s to be advertised, got %v", name, capabilities)
+		}
+	}
+
+	configured := newTestState(t, func(cfg *config.Config) {
+		cfg.LiveKitAPIKey = "devkey"
+		cfg.LiveKitAPISecret = "secret"
+		cfg.PublicPreview = true
+	})
+	capabilities = configured.ServerInfo().Capabilities
+	if !capabilities["voice"] || !capabilities["publicPreview"] {
+		t.Fatalf("expected voice and public preview to follow config, got %v", capabilities)
+	}
+}
@@ -0,0 +1,461 @@
+package serverstate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxSlowModeSeconds = 6 * 60 * 60
+	// maxChannelMessageLength bounds per-channel overrides so that plaintext
+	// plus e2ee overhead still fits in maxEncryptedMessageBytes.
+	maxChannelMessageLength = 12000
+	maxChannelNameLength    = 100
+	maxChannelTopicLength   = 1024
+)
+
+type ChannelUpdate struct {
+	Name             *string
+	Topic            *string
+	SlowModeSeconds  *int
+	MaxMessageLength *int
+	PublicPreview    *bool
+	E2EE             *bool
+	TypingIndicators *bool
+	ReadRoles        *[]string
+	WriteRoles       *[]string
+	AdminRoles       *[]string
+}
+
+var channelIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type slowModeKey struct {
+	ChannelID string
+	PublicKey string
+}
+
+// CreateChannel adds a channel to the server config. MAX_CHANNELS, when set,
+// caps the total number of configured channels.
+func (s *State) CreateChannel(channel Channel) (Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel.ID = strings.TrimSpace(channel.ID)
+	if !channelIDPattern.MatchString(channel.ID) {
+		return Channel{}, newAPIError(400, "invalid_channel_id", "id must be 1-64 letters, digits, '-' or '_'")
+	}
+	if channel.Type != "text" && channel.Type != "voice" {
+		return Channel{}, newAPIError(400, "invalid_channel_type", "type must be text or voice")
+	}
+	if _, exists := s.channelLocked(channel.ID); exists {
+		return Channel{}, newAPIError(409, "channel_exists", "a channel with this id already exists")
+	}
+	if channel.Type == "voice" {
+		for _, existing := range s.serverCfg.Channels {
+			if existing.Type == "voice" && sanitizeRoomNamePart(existing.ID) == channel.ID {
+				return Channel{}, newAPIError(409, "channel_exists", fmt.Sprintf("voice channel %q already uses this LiveKit room", existing.ID))
+			}
+		}
+	}
+	if limit := s.cfg.MaxChannels; limit > 0 && len(s.serverCfg.Channels) >= limit {
+		apiErr := newAPIError(409, "channel_limit_reached", "the server has reached its channel limit")
+		apiErr.Details = map[string]any{"maxChannels": limit}
+		return Channel{}, apiErr
+	}
+
+	normalized, err := normalizeChannels([]Channel{channel})
+	if err != nil {
+		return Channel{}, newAPIError(400, "invalid_channel_settings", err.Error())
+	}
+	channel = normalized[0]
+
+	updated := s.serverCfg
+	updated.Channels = append(append(make([]Channel, 0, len(s.serverCfg.Channels)+1), s.serverCfg.Channels...), channel)
+	if err := writeJSON(s.serverCfgPath, updated, 0o600); err != nil {
+		return Channel{}, fmt.Errorf("persist server config: %w", err)
+	}
+	s.serverCfg = updated
+	s.channelActivity = nil
+
+	return channel, nil
+}
+
+type DeleteChannelResult struct {
+	ChannelID       string `json:"channelId"`
+	MessagesDeleted int64  `json:"messagesDeleted"`
+}
+
+// DeleteChannel removes a channel together with its messages, drafts, read
+// markers and voice presence. The last channel cannot be deleted, as the server
+// config requires at least one, and neither can DEFAULT_CHANNEL_ID, which startup
+// validates. Open streams of the channel get a channel.deleted event.
+func (s *State) DeleteChannel(channelID string) (DeleteChannelResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return DeleteChannelResult{}, err
+	}
+
+	channelID = strings.TrimSpace(channelID)
+	index := s.channelIndexLocked(channelID)
+	if index < 0 {
+		return DeleteChannelResult{}, newAPIError(404, "channel_not_found", "channel does not exist")
+	}
+	if len(s.serverCfg.Channels) == 1 {
+		return DeleteChannelResult{}, newAPIError(409, "last_channel", "the last channel cannot be deleted")
+	}
+	if channelID == s.cfg.DefaultChannelID {
+		return DeleteChannelResult{}, newAPIError(409, "default_channel", "DEFAULT_CHANNEL_ID cannot be deleted")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return DeleteChannelResult{}, fmt.Errorf("begin channel delete tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	deleted, err := deleteMessagesTx(tx, `channel_id = ?`, channelID)
+	if err != nil {
+		return DeleteChannelResult{}, err
+	}
+	for _, table := range []string{"drafts", "read_markers", "voice_presence"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE channel_id = ?`, channelID); err != nil {
+			return DeleteChannelResult{}, fmt.Errorf("delete channel %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return DeleteChannelResult{}, fmt.Errorf("commit channel delete tx: %w", err)
+	}
+
+	// The config is written only once the rows are gone: should the write fail,
+	// the channel stays listed, empty, instead of leaving orphaned rows behind.
+	updated := s.serverCfg
+	updated.Channels = make([]Channel, 0, len(s.serverCfg.Channels)-1)
+	updated.Channels = append(updated.Channels, s.serverCfg.Channels[:index]...)
+	updated.Channels = append(updated.Channels, s.serverCfg.Channels[index+1:]...)
+	if err := writeJSON(s.serverCfgPath, updated, 0o600); err != nil {
+		return DeleteChannelResult{}, fmt.Errorf("persist server config: %w", err)
+	}
+
+	s.broadcastChannelEventLocked(channelID, ChannelEvent{Type: "channel.deleted", ChannelID: channelID})
+	s.serverCfg = updated
+	s.channelActivity = nil
+	delete(s.voiceRosters, channelID)
+
+	return DeleteChannelResult{ChannelID: channelID, MessagesDeleted: deleted}, nil
+}
+
+// ChannelCapacity reports the configured channel count and MAX_CHANNELS (0 when
+// unlimited).
+func (s *State) ChannelCapacity() (count, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.serverCfg.Channels), s.cfg.MaxChannels
+}
+
+func (s *State) UpdateChannel(channelID string, update ChannelUpdate) (Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateChannelLocked(channelID, update)
+}
+
+// UpdateChannelAsMember lets a member holding PermissionManageChannel for the
+// channel change its settings. Role lists stay reserved for server admins so a
+// channel admin cannot widen their own scope.
+func (s *State) UpdateChannelAsMember(sessionToken, channelID string, update ChannelUpdate) (Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return Channel{}, err
+	}
+	channelID = strings.TrimSpace(channelID)
+	if _, ok := s.channelLocked(channelID); !ok {
+		return Channel{}, newAPIError(404, "channel_not_found", "channel does not exist")
+	}
+	if !s.hasChannelPermissionLocked(identity.PublicKey, channelID, PermissionManageChannel) {
+		return Channel{}, newAPIError(403, "missing_permission", "you cannot manage this channel")
+	}
+	if !s.isAdminPublicKeyLocked(identity.PublicKey) && (update.ReadRoles != nil || update.WriteRoles != nil || update.AdminRoles != nil) {
+		return Channel{}, newAPIError(403, "missing_permission", "only server admins can change channel roles")
+	}
+
+	return s.updateChannelLocked(channelID, update)
+}
+
+func (s *State) updateChannelLocked(channelID string, update ChannelUpdate) (Channel, error) {
+	channelID = strings.TrimSpace(channelID)
+	index := s.channelIndexLocked(channelID)
+	if index < 0 {
+		return Channel{}, newAPIError(404, "channel_not_found", "channel does not exist")
+	}
+
+	updated := s.serverCfg
+	updated.Channels = make([]Channel, len(s.serverCfg.Channels))
+	copy(updated.Channels, s.serverCfg.Channels)
+
+	channel := updated.Channels[index]
+	if update.Name != nil {
+		name, err := normalizeChannelName(*update.Name)
+		if err != nil {
+			return Channel{}, newAPIError(400, "invalid_channel_name", err.Error())
+		}
+		channel.Name = name
+	}
+	if update.Topic != nil {
+		topic, err := normalizeChannelTopic(*update.Topic)
+		if err != nil {
+			return Channel{}, newAPIError(400, "invalid_channel_topic", err.Error())
+		}
+		channel.Topic = topic
+	}
+	if update.SlowModeSeconds != nil {
+		if err := validateSlowModeSeconds(*update.SlowModeSeconds); err != nil {
+			return Channel{}, newAPIError(400, "invalid_slow_mode", err.Error())
+		}
+		channel.SlowModeSeconds = *update.SlowModeSeconds
+	}
+	if update.MaxMessageLength != nil {
+		if err := validateMaxMessageLength(*update.MaxMessageLength); err != nil {
+			return Channel{}, newAPIError(400, "invalid_max_message_length", err.Error())
+		}
+		channel.MaxMessageLength = *update.MaxMessageLength
+	}
+	if update.PublicPreview != nil {
+		channel.PublicPreview = *update.PublicPreview
+	}
+	if update.E2EE != nil {
+		channel.E2EE = *update.E2EE
+	}
+	if update.TypingIndicators != nil {
+		// Only the opt-out is stored, so the default stays implicit.
+		channel.TypingIndicators = nil
+		if !*update.TypingIndicators {
+			channel.TypingIndicators = update.TypingIndicators
+		}
+	}
+	if update.ReadRoles != nil {
+		roles, err := normalizeRoles(*update.ReadRoles)
+		if err != nil {
+			return Channel{}, newAPIError(400, "invalid_roles", err.Error())
+		}
+		channel.ReadRoles = roles
+	}
+	if update.WriteRoles != nil {
+		roles, err := normalizeRoles(*update.WriteRoles)
+		if err != nil {
+			return Channel{}, newAPIError(400, "invalid_roles", err.Error())
+		}
+		channel.WriteRoles = roles
+	}
+	if update.AdminRoles != nil {
+		roles, err := normalizeRoles(*update.AdminRoles)
+		if err != nil {
+			return Channel{}, newAPIError(400, "invalid_roles", err.Error())
+		}
+		channel.AdminRoles = roles
+	}
+	if err := validateChannelFlags(channel); err != nil {
+		return Channel{}, newAPIError(400, "invalid_channel_settings", err.Error())
+	}
+	updated.Channels[index] = channel
+
+	if err := writeJSON(s.serverCfgPath, updated, 0o600); err != nil {
+		return Channel{}, fmt.Errorf("persist server config: %w", err)
+	}
+	s.serverCfg = updated
+
+	return channel, nil
+}
+
+func (s *State) channelIndexLocked(channelID string) int {
+	for i, channel := range s.serverCfg.Channels {
+		if channel.ID == channelID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *State) channelLocked(channelID string) (Channel, bool) {
+	index := s.channelIndexLocked(strings.TrimSpace(channelID))
+	if index < 0 {
+		return Channel{}, false
+	}
+	return s.serverCfg.Channels[index], true
+}
+
+// enforceSlowModeLocked rejects a post when the member's previous message in the
+// channel is more recent than the channel's slow mode interval. Members who may
+// moderate the channel are exempt.
+func (s *State) enforceSlowModeLocked(identity SessionIdentity, channelID string, now time.Time) error {
+	limit := s.slowModeRateLimitLocked(identity, channelID, now)
+	if limit == nil || limit.Remaining > 0 {
+		return nil
+	}
+
+	remaining := limit.Reset.Sub(now)
+	retryAfter := int((remaining + time.Second - 1) / time.Second)
+	return &APIError{
+		Status:    429,
+		Code:      "slow_mode",
+		Message:   fmt.Sprintf("slow mode is enabled for this channel, retry in %d seconds", retryAfter),
+		Details:   map[string]any{"retryAfterSeconds": retryAfter},
+		RateLimit: limit,
+	}
+}
+
+// slowModeRateLimitLocked expresses slow mode as a window of one post per
+// SlowModeSeconds. It returns nil when the channel has no slow mode or the
+// member may moderate it.
+func (s *State) slowModeRateLimitLocked(identity SessionIdentity, channelID string, now time.Time) *RateLimit {
+	channel, ok := s.channelLocked(channelID)
+	if !ok || channel.SlowModeSeconds <= 0 || s.hasChannelPermissionLocked(identity.PublicKey, channel.ID, PermissionModerateMessages) {
+		return nil
+	}
+
+	limit := &RateLimit{Limit: 1, Remaining: 1, Reset: now}
+	lastPostAt, ok := s.lastPostAt[slowModeKey{ChannelID: channel.ID, PublicKey: identity.PublicKey}]
+	if !ok {
+		return limit
+	}
+	if reset := lastPostAt.Add(time.Duration(channel.SlowModeSeconds) * time.Second); reset.After(now) {
+		limit.Remaining = 0
+		limit.Reset = reset
+	}
+	return limit
+}
+
+func (s *State) recordPostLocked(identity SessionIdentity, channelID string, now time.Time) {
+	channel, ok := s.channelLocked(channelID)
+	if !ok || channel.SlowModeSeconds <= 0 {
+		return
+	}
+	s.lastPostAt[slowModeKey{ChannelID: channel.ID, PublicKey: identity.PublicKey}] = now
+}
+
+// publicPreviewEnabledLocked reports whether anonymous clients may read the channel:
+// the server must run with PUBLIC_PREVIEW and the channel must opt in.
+func (s *State) publicPreviewEnabledLocked(channelID string) bool {
+	if !s.cfg.PublicPreview {
+		return false
+	}
+	channel, ok := s.channelLocked(channelID)
+	return ok && channel.Type == "text" && channel.PublicPreview
+}
+
+func validateChannelFlags(channel Channel) error {
+	if channel.PublicPreview && channel.Type != "text" {
+		return errors.New("publicPreview is only supported on text channels")
+	}
+	if channel.PublicPreview && len(channel.ReadRoles) > 0 {
+		return errors.New("publicPreview cannot be combined with readRoles")
+	}
+	if channel.E2EE && channel.Type != "text" {
+		return errors.New("e2ee is only supported on text channels")
+	}
+	if channel.TypingIndicators != nil && channel.Type != "text" {
+		return errors.New("typingIndicators is only supported on text channels")
+	}
+	if channel.MaxMessageLength != 0 && channel.Type != "text" {
+		return errors.New("maxMessageLength is only supported on text channels")
+	}
+	return nil
+}
+
+func normalizeChannelName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxChannelNameLength {
+		return "", fmt.Errorf("name must be at most %d characters", maxChannelNameLength)
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", errors.New("name must not contain control characters")
+	}
+	return name, nil
+}
+
+// normalizeChannelTopic trims the topic; an empty topic clears it.
+func normalizeChannelTopic(topic string) (string, error) {
+	topic = strings.TrimSpace(topic)
+	if utf8.RuneCountInString(topic) > maxChannelTopicLength {
+		return "", fmt.Errorf("topic must be at most %d characters", maxChannelTopicLength)
+	}
+	if strings.IndexFunc(topic, unicode.IsControl) >= 0 {
+		return "", errors.New("topic must not contain control characters")
+	}
+	return topic, nil
+}
+
+func validateSlowModeSeconds(value int) error {
+	if value < 0 || value > maxSlowModeSeconds {
+		return fmt.Errorf("slowModeSeconds must be between 0 and %d", maxSlowModeSeconds)
+	}
+	return nil
+}
+
+// validateMaxMessageLength accepts 0, meaning the server default, or an
+// override up to maxChannelMessageLength.
+func validateMaxMessageLength(value int) error {
+	if value < 0 || value > maxChannelMessageLength {
+		return fmt.Errorf("maxMessageLength must be between 0 and %d", maxChannelMessageLength)
+	}
+	return nil
+}
+
+// messageLengthLimit is the effective message length limit of a channel.
+func messageLengthLimit(channel Channel) int {
+	if channel.MaxMessageLength > 0 {
+		return channel.MaxMessageLength
+	}
+	return maxMessageLength
+}
+
+func (s *State) messageLengthLimitLocked(channelID string) int {
+	channel, _ := s.channelLocked(channelID)
+	return messageLengthLimit(channel)
+}
+
+// validateDefaultChannel fails startup when DEFAULT_CHANNEL_ID names a channel
+// clients could not open as their landing channel.
+func validateDefaultChannel(channels []Channel, channelID string) error {
+	if channelID == "" {
+		return nil
+	}
+	for _, channel := range channels {
+		if channel.ID != channelID {
+			continue
+		}
+		if channel.Type != "text" {
+			return fmt.Errorf("DEFAULT_CHANNEL_ID %q is a %s channel, expected text", channelID, channel.Type)
+		}
+		return nil
+	}
+	return fmt.Errorf("DEFAULT_CHANNEL_ID %q does not match any channel", channelID)
+}
+
+// defaultChannelIDLocked is the channel clients open first: DEFAULT_CHANNEL_ID,
+// or the first text channel when it is unset.
+func (s *State) defaultChannelIDLocked() string {
+	if s.cfg.DefaultChannelID != "" {
+		return s.cfg.DefaultChannelID
+	}
+	for _, channel := range s.serverCfg.Channels {
+		if channel.Type == "text" {
+			return channel.ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,464 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestSlowModeRejectsRapidPostsForMembersOnly(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{SlowModeSeconds: intPointer(60)}); err != nil {
+		t.Fatalf("failed to enable slow mode: %v", err)
+	}
+
+	if _, err := s.CreateMessage(member.SessionToken, "general", "first"); err != nil {
+		t.Fatalf("first message should be accepted: %v", err)
+	}
+	_, err := s.CreateMessage(member.SessionToken, "general", "second")
+	apiErr := requireAPIErrorCode(t, err, "slow_mode")
+	if apiErr.Status != 429 {
+		t.Fatalf("unexpected status: got=%d want=429", apiErr.Status)
+	}
+	if remaining, _ := apiErr.Details["retryAfterSeconds"].(int); remaining <= 0 || remaining > 60 {
+		t.Fatalf("unexpected retryAfterSeconds: %v", apiErr.Details["retryAfterSeconds"])
+	}
+	if apiErr.RateLimit == nil || apiErr.RateLimit.Limit != 1 || apiErr.RateLimit.Remaining != 0 {
+		t.Fatalf("expected exhausted rate limit on slow mode error, got %+v", apiErr.RateLimit)
+	}
+	if limit, err := s.ChannelRateLimit(admin.SessionToken, "general"); err != nil || limit != nil {
+		t.Fatalf("admins must not be rate limited, got %+v, %v", limit, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.CreateMessage(admin.SessionToken, "general", "admin post"); err != nil {
+			t.Fatalf("admin should be exempt from slow mode: %v", err)
+		}
+	}
+}
+
+func TestUpdateChannelRenamesAndSetsTopic(t *testing.T) {
+	s := newTestState(t, nil)
+
+	channel, err := s.UpdateChannel("general", ChannelUpdate{Name: stringPointer("  lobby "), Topic: stringPointer(" Say hi ")})
+	if err != nil {
+		t.Fatalf("update channel failed: %v", err)
+	}
+	if channel.Name != "lobby" || channel.Topic != "Say hi" {
+		t.Fatalf("expected trimmed name and topic, got %q / %q", channel.Name, channel.Topic)
+	}
+
+	_, err = s.UpdateChannel("general", ChannelUpdate{Name: stringPointer(" ")})
+	requireAPIErrorCode(t, err, "invalid_channel_name")
+	_, err = s.UpdateChannel("general", ChannelUpdate{Name: stringPointer("line\nbreak")})
+	requireAPIErrorCode(t, err, "invalid_channel_name")
+	_, err = s.UpdateChannel("general", ChannelUpdate{Name: stringPointer(strings.Repeat("n", maxChannelNameLength+1))})
+	requireAPIErrorCode(t, err, "invalid_channel_name")
+	_, err = s.UpdateChannel("general", ChannelUpdate{Topic: stringPointer(strings.Repeat("t", maxChannelTopicLength+1))})
+	requireAPIErrorCode(t, err, "invalid_channel_topic")
+
+	channel, err = s.UpdateChannel("general", ChannelUpdate{Topic: stringPointer("")})
+	if err != nil || channel.Topic != "" || channel.Name != "lobby" {
+		t.Fatalf("empty topic should clear it, got %+v, %v", channel, err)
+	}
+}
+
+func TestUpdateChannelRejectsInvalidSlowMode(t *testing.T) {
+	s := newTestState(t, nil)
+
+	_, err := s.UpdateChannel("general", ChannelUpdate{SlowModeSeconds: intPointer(-1)})
+	requireAPIErrorCode(t, err, "invalid_slow_mode")
+
+	_, err = s.UpdateChannel("missing", ChannelUpdate{SlowModeSeconds: intPointer(5)})
+	requireAPIErrorCode(t, err, "channel_not_found")
+}
+
+func TestChannelMaxMessageLengthOverride(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	long := strings.Repeat("x", maxMessageLength+1)
+	_, err := s.CreateMessage(member.SessionToken, "general", long)
+	requireAPIErrorCode(t, err, "invalid_message")
+
+	channels, err := s.Channels(member.SessionToken)
+	if err != nil || channels[0].MaxMessageLength != maxMessageLength {
+		t.Fatalf("expected default limit on general, got %+v, %v", channels, err)
+	}
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{MaxMessageLength: intPointer(8000)}); err != nil {
+		t.Fatalf("failed to raise limit: %v", err)
+	}
+	message, err := s.CreateMessage(member.SessionToken, "general", long)
+	if err != nil {
+		t.Fatalf("message within raised limit should be accepted: %v", err)
+	}
+	if _, err := s.EditMessage(member.SessionToken, "general", message.ID, strings.Repeat("y", 8000)); err != nil {
+		t.Fatalf("edit within raised limit should be accepted: %v", err)
+	}
+	_, err = s.EditMessage(member.SessionToken, "general", message.ID, strings.Repeat("y", 8001))
+	requireAPIErrorCode(t, err, "invalid_message")
+
+	channels, err = s.Channels(member.SessionToken)
+	if err != nil || channels[0].MaxMessageLength != 8000 {
+		t.Fatalf("expected raised limit on general, got %+v, %v", channels, err)
+	}
+	for _, channel := range channels[1:] {
+		if channel.MaxMessageLength != 0 {
+			t.Fatalf("voice channels must not report a message limit, got %+v", channel)
+		}
+	}
+
+	_, err = s.UpdateChannel("general", ChannelUpdate{MaxMessageLength: intPointer(maxChannelMessageLength + 1)})
+	requireAPIErrorCode(t, err, "invalid_max_message_length")
+	_, err = s.UpdateChannel("voice-main", ChannelUpdate{MaxMessageLength: intPointer(100)})
+	requireAPIErrorCode(t, err, "invalid_channel_settings")
+}
+
+func TestPublicPreviewAllowsAnonymousReadsOnFlaggedChannels(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.PublicPreview = true })
+	member := connectTestMember(t, s, "member")
+	if _, err := s.CreateMessage(member.SessionToken, "general", "hello visitors"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	_, err := s.ListMessages("", "general", 10)
+	requireAPIErrorCode(t, err, "missing_session_token")
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{PublicPreview: boolPointer(true)}); err != nil {
+		t.Fatalf("failed to enable public preview: %v", err)
+	}
+
+	result, err := s.ListMessages("", "general", 10)
+	if err != nil {
+		t.Fatalf("anonymous read should be allowed: %v", err)
+	}
+	if len(result.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(result.Messages))
+	}
+
+	_, err = s.CreateMessage("", "general", "anonymous post")
+	requireAPIErrorCode(t, err, "missing_session_token")
+
+	_, err = s.UpdateChannel("voice-main", ChannelUpdate{PublicPreview: boolPointer(true)})
+	requireAPIErrorCode(t, err, "invalid_channel_settings")
+}
+
+func TestPublicPreviewRequiresServerOptIn(t *testing.T) {
+	s := newTestState(t, nil)
+	if _, err := s.UpdateChannel("general", ChannelUpdate{PublicPreview: boolPointer(true)}); err != nil {
+		t.Fatalf("failed to flag channel: %v", err)
+	}
+
+	_, err := s.ListMessages("", "general", 10)
+	requireAPIErrorCode(t, err, "missing_session_token")
+	channels, err := s.Channels("")
+	if err != nil {
+		t.Fatalf("list channels failed: %v", err)
+	}
+	for _, channel := range channels {
+		if channel.PublicPreview {
+			t.Fatalf("channel %q must not report publicPreview while PUBLIC_PREVIEW is off", channel.ID)
+		}
+	}
+}
+
+func TestChannelRolesGateReadAndWrite(t *testing.T) {
+	s := newTestState(t, nil)
+	reader := connectTestMember(t, s, "reader")
+	writer := connectTestMember(t, s, "writer")
+	outsider := connectTestMember(t, s, "outsider")
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+
+	if _, err := s.SetMemberRoles(reader.PublicKey, []string{"Member"}); err != nil {
+		t.Fatalf("set reader roles failed: %v", err)
+	}
+	if _, err := s.SetMemberRoles(writer.PublicKey, []string{"member", "mod"}); err != nil {
+		t.Fatalf("set writer roles failed: %v", err)
+	}
+	if _, err := s.UpdateChannel("general", ChannelUpdate{
+		ReadRoles:  &[]string{"member"},
+		WriteRoles: &[]string{"mod"},
+	}); err != nil {
+		t.Fatalf("failed to restrict channel: %v", err)
+	}
+
+	if _, err := s.CreateMessage(writer.SessionToken, "general", "from writer"); err != nil {
+		t.Fatalf("writer should be able to post: %v", err)
+	}
+	if _, err := s.CreateMessage(admin.SessionToken, "general", "from admin"); err != nil {
+		t.Fatalf("admin should bypass roles: %v", err)
+	}
+	_, err := s.CreateMessage(reader.SessionToken, "general", "from reader")
+	requireAPIErrorCode(t, err, "channel_forbidden")
+
+	if _, err := s.ListMessages(reader.SessionToken, "general", 10); err != nil {
+		t.Fatalf("reader should be able to read: %v", err)
+	}
+	_, err = s.ListMessages(outsider.SessionToken, "general", 10)
+	requireAPIErrorCode(t, err, "channel_forbidden")
+	_, _, err = s.SubscribeChannelEvents(outsider.SessionToken, "general")
+	requireAPIErrorCode(t, err, "channel_forbidden")
+
+	channels, err := s.Channels(outsider.SessionToken)
+	if err != nil {
+		t.Fatalf("list channels failed: %v", err)
+	}
+	for _, channel := range channels {
+		if channel.ID == "general" {
+			t.Fatal("outsider must not see a channel it cannot read")
+		}
+	}
+
+	_, err = s.UpdateChannel("general", ChannelUpdate{ReadRoles: &[]string{"bad role"}})
+	requireAPIErrorCode(t, err, "invalid_roles")
+}
+
+func TestValidateChannelStreamDetectsLostAccess(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	if err := s.ValidateChannelStream(member.SessionToken, "general"); err != nil {
+		t.Fatalf("expected open stream to stay valid: %v", err)
+	}
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{ReadRoles: &[]string{"staff"}}); err != nil {
+		t.Fatalf("failed to restrict channel: %v", err)
+	}
+	requireAPIErrorCode(t, s.ValidateChannelStream(member.SessionToken, "general"), "channel_forbidden")
+
+	if _, err := s.LeaveServer(member.SessionToken, false); err != nil {
+		t.Fatalf("leave failed: %v", err)
+	}
+	requireAPIErrorCode(t, s.ValidateChannelStream(member.SessionToken, "general"), "invalid_session_token")
+}
+
+func TestVoiceJoinRequiresChannelAccess(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	if _, err := s.UpdateChannel("voice-main", ChannelUpdate{ReadRoles: &[]string{"staff"}}); err != nil {
+		t.Fatalf("failed to restrict voice channel: %v", err)
+	}
+	_, err := s.BeginVoiceJoin(member.SessionToken, "voice-main")
+	requireAPIErrorCode(t, err, "channel_forbidden")
+
+	if _, err := s.SetMemberRoles(member.PublicKey, []string{"staff"}); err != nil {
+		t.Fatalf("set roles failed: %v", err)
+	}
+	if _, err := s.BeginVoiceJoin(member.SessionToken, "voice-main"); err != nil {
+		t.Fatalf("member with role should join: %v", err)
+	}
+}
+
+func TestChannelAdminRolesAreScopedToTheirChannel(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.AllowMassMention = false })
+	moderator := connectTestMember(t, s, "moderator")
+	member := connectTestMember(t, s, "member")
+	if _, err := s.SetMemberRoles(moderator.PublicKey, []string{"general-mod"}); err != nil {
+		t.Fatalf("set roles failed: %v", err)
+	}
+	if _, err := s.UpdateChannel("general", ChannelUpdate{
+		AdminRoles: &[]string{"general-mod"},
+		WriteRoles: &[]string{"poster"},
+	}); err != nil {
+		t.Fatalf("failed to scope channel admins: %v", err)
+	}
+
+	s.mu.Lock()
+	if !s.hasChannelPermissionLocked(moderator.PublicKey, "general", PermissionManageChannel) {
+		t.Error("channel admin must manage its channel")
+	}
+	if s.hasChannelPermissionLocked(moderator.PublicKey, "voice-main", PermissionManageChannel) {
+		t.Error("channel admin must not manage other channels")
+	}
+	s.mu.Unlock()
+
+	if _, err := s.CreateMessage(moderator.SessionToken, "general", "@everyone rules updated"); err != nil {
+		t.Fatalf("channel admin should bypass write roles and mention limits: %v", err)
+	}
+
+	channel, err := s.UpdateChannelAsMember(moderator.SessionToken, "general", ChannelUpdate{SlowModeSeconds: intPointer(30)})
+	if err != nil {
+		t.Fatalf("channel admin should update settings: %v", err)
+	}
+	if channel.SlowModeSeconds != 30 {
+		t.Fatalf("unexpected slow mode: %d", channel.SlowModeSeconds)
+	}
+	_, err = s.UpdateChannelAsMember(moderator.SessionToken, "general", ChannelUpdate{AdminRoles: &[]string{}})
+	requireAPIErrorCode(t, err, "missing_permission")
+	_, err = s.UpdateChannelAsMember(moderator.SessionToken, "voice-main", ChannelUpdate{SlowModeSeconds: intPointer(5)})
+	requireAPIErrorCode(t, err, "missing_permission")
+	_, err = s.UpdateChannelAsMember(member.SessionToken, "general", ChannelUpdate{SlowModeSeconds: intPointer(0)})
+	requireAPIErrorCode(t, err, "missing_permission")
+}
+
+func TestDefaultChannelFallsBackToFirstTextChannel(t *testing.T) {
+	s := newTestState(t, nil)
+	if got := s.ServerInfo().DefaultChannelID; got != "general" {
+		t.Fatalf("expected fallback to general, got %q", got)
+	}
+
+	configured := newTestState(t, func(cfg *config.Config) { cfg.DefaultChannelID = "general" })
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	invite, err := configured.CreateInvite(publicKey, "test", nil, false)
+	if err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+	begin, err := configured.BeginConnect(invite.InviteID)
+	if err != nil {
+		t.Fatalf("begin connect failed: %v", err)
+	}
+	challenge, _ := base64.StdEncoding.DecodeString(begin.Challenge)
+	hash := SignaturePayloadHash(challenge, invite.InviteID, begin.ServerFingerprint)
+	result, err := configured.FinishConnect(FinishRequest{
+		InviteID:        invite.InviteID,
+		ClientPublicKey: publicKey,
+		Challenge:       begin.Challenge,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(priv, hash[:])),
+	})
+	if err != nil {
+		t.Fatalf("finish connect failed: %v", err)
+	}
+	if result.DefaultChannelID != "general" {
+		t.Fatalf("expected defaultChannelId in connect result, got %q", result.DefaultChannelID)
+	}
+
+	for _, channelID := range []string{"missing", "voice-main"} {
+		cfg := config.Config{ServerName: "Test Server", DataDir: t.TempDir(), DefaultChannelID: channelID}
+		if _, err := New(cfg); err == nil {
+			t.Fatalf("expected startup to reject DEFAULT_CHANNEL_ID %q", channelID)
+		}
+	}
+}
+
+func TestCreateChannelEnforcesMaxChannels(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.MaxChannels = 4 })
+
+	channel, err := s.CreateChannel(Channel{ID: "random", Type: "text", Name: " Random "})
+	if err != nil {
+		t.Fatalf("create channel failed: %v", err)
+	}
+	if channel.Name != "Random" {
+		t.Fatalf("expected a normalized name, got %q", channel.Name)
+	}
+	if count, limit := s.ChannelCapacity(); count != 4 || limit != 4 {
+		t.Fatalf("expected 4 of 4 channels, got %d of %d", count, limit)
+	}
+
+	_, err = s.CreateChannel(Channel{ID: "overflow", Type: "text", Name: "overflow"})
+	requireAPIErrorCode(t, err, "channel_limit_reached")
+	_, err = s.CreateChannel(Channel{ID: "general", Type: "text", Name: "general"})
+	requireAPIErrorCode(t, err, "channel_exists")
+
+	unlimited := newTestState(t, nil)
+	_, err = unlimited.CreateChannel(Channel{ID: "bad id", Type: "text", Name: "bad"})
+	requireAPIErrorCode(t, err, "invalid_channel_id")
+	for i := 0; i < 3; i++ {
+		if _, err := unlimited.CreateChannel(Channel{ID: "extra-" + strings.Repeat("x", i+1), Type: "voice", Name: "extra"}); err != nil {
+			t.Fatalf("create channel without a limit failed: %v", err)
+		}
+	}
+}
+
+func TestTypingIndicatorsCanBeSuppressedPerChannel(t *testing.T) {
+	s := newTestState(t, nil)
+	typist := connectTestMember(t, s, "typist")
+	reader := connectTestMember(t, s, "reader")
+
+	events, cancel, err := s.SubscribeChannelEvents(reader.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	if err := s.NotifyTyping(typist.SessionToken, "general"); err != nil {
+		t.Fatalf("notify typing failed: %v", err)
+	}
+	if event := <-events; event.Type != "typing" || event.Typing == nil || event.Typing.PublicKey != typist.PublicKey {
+		t.Fatalf("expected typing event, got %+v", event)
+	}
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{TypingIndicators: boolPointer(false)}); err != nil {
+		t.Fatalf("failed to disable typing indicators: %v", err)
+	}
+	if err := s.NotifyTyping(typist.SessionToken, "general"); err != nil {
+		t.Fatalf("suppressed typing should still succeed: %v", err)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("expected no broadcast, got %+v", event)
+	default:
+	}
+
+	channels, err := s.Channels(reader.SessionToken)
+	if err != nil || channels[0].TypingIndicators == nil || *channels[0].TypingIndicators {
+		t.Fatalf("expected typingIndicators=false on general, got %+v, %v", channels, err)
+	}
+
+	err = s.NotifyTyping(typist.SessionToken, "voice-main")
+	requireAPIErrorCode(t, err, "invalid_channel_type")
+	_, err = s.UpdateChannel("voice-main", ChannelUpdate{TypingIndicators: boolPointer(false)})
+	requireAPIErrorCode(t, err, "invalid_channel_settings")
+}
+
+func TestDeleteChannelKeepsTheLastChannel(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	if _, err := s.CreateMessage(member.SessionToken, "general", "hello"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if _, err := s.SaveDraft(member.SessionToken, "general", "unsent"); err != nil {
+		t.Fatalf("save draft failed: %v", err)
+	}
+
+	for _, channelID := range []string{"voice-main", "voice-afk"} {
+		if _, err := s.DeleteChannel(channelID); err != nil {
+			t.Fatalf("delete %s failed: %v", channelID, err)
+		}
+	}
+	_, err := s.DeleteChannel("voice-main")
+	requireAPIErrorCode(t, err, "channel_not_found")
+
+	_, err = s.DeleteChannel("general")
+	if apiErr := requireAPIErrorCode(t, err, "last_channel"); apiErr.Status != 409 {
+		t.Fatalf("unexpected status: got=%d want=409", apiErr.Status)
+	}
+
+	if _, err := s.CreateChannel(Channel{ID: "lobby", Type: "text", Name: "lobby"}); err != nil {
+		t.Fatalf("create channel failed: %v", err)
+	}
+	events, cancel, err := s.SubscribeChannelEvents(member.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+	result, err := s.DeleteChannel("general")
+	if err != nil || result.MessagesDeleted != 1 {
+		t.Fatalf("unexpected delete result: %+v, %v", result, err)
+	}
+	if event := <-events; event.Type != "channel.deleted" || event.ChannelID != "general" {
+		t.Fatalf("unexpected delete event: %+v", event)
+	}
+	var drafts int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM drafts WHERE channel_id = 'general'`).Scan(&drafts); err != nil || drafts != 0 {
+		t.Fatalf("drafts of a deleted channel must be removed, got %d, %v", drafts, err)
+	}
+
+	reloaded, err := loadOrCreateServerConfig(s.serverCfgPath, "fallback")
+	if err != nil || len(reloaded.Channels) != 1 || reloaded.Channels[0].ID != "lobby" {
+		t.Fatalf("deletion not persisted: %+v, %v", reloaded.Channels, err)
+	}
+}
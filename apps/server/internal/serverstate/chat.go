@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"strings"
 	"time"
 )
@@ -29,17 +31,50 @@ type ChannelMessage struct {
 	ChannelID       string        `json:"channelId"`
 	Author          MessageAuthor `json:"author"`
 	ContentMarkdown string        `json:"contentMarkdown"`
-	CreatedAt       string        `json:"createdAt"`
-	UpdatedAt       string        `json:"updatedAt"`
+	// ContentEncrypted is the base64 ciphertext of messages in e2ee channels; the
+	// server stores and relays it without reading it.
+	ContentEncrypted string `json:"contentEncrypted,omitempty"`
+	// EditedBy is the public key of the last editor when it was not the author,
+	// which only admins and channel moderators may be; authorship itself never
+	// changes on edit.
+	EditedBy string `json:"editedBy,omitempty"`
+	// Edited is set once the message has been edited at least once, so clients
+	// need not compare second-resolution timestamps.
+	Edited    bool   `json:"edited"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	System    bool   `json:"system,omitempty"`
+	// MessageType is normal, announcement or system; clients style the latter two.
+	MessageType string `json:"messageType"`
 }
 
 type ListMessagesResult struct {
 	Messages []ChannelMessage `json:"messages"`
+	// NextCursor pages to older messages via ListMessagesBefore; empty once the
+	// start of the channel is reached.
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
+// MessagePageResult is one page of a channel's history. Page 1 holds the newest
+// PageSize messages; messages within a page are oldest first.
+type MessagePageResult struct {
+	Messages      []ChannelMessage `json:"messages"`
+	Page          int              `json:"page"`
+	PageSize      int              `json:"pageSize"`
+	TotalMessages int              `json:"totalMessages"`
+}
+
+// ChannelEvent is sent to channel streams and the message webhook. Message
+// events are self-sufficient: message.created and message.updated carry the
+// full message, message.deleted the channel and message id, so clients never
+// need a follow-up fetch and can ignore events for messages they never loaded.
 type ChannelEvent struct {
-	Type    string          `json:"type"`
-	Message *ChannelMessage `json:"message,omitempty"`
+	Type       string           `json:"type"`
+	Message    *ChannelMessage  `json:"message,omitempty"`
+	ChannelID  string           `json:"channelId,omitempty"`
+	MessageID  string           `json:"messageId,omitempty"`
+	Typing     *TypingIndicator `json:"typing,omitempty"`
+	ServerName string           `json:"serverName,omitempty"`
 }
 
 func (s *State) AuthenticateSession(token string) (SessionIdentity, error) {
@@ -89,10 +124,7 @@ func (s *State) ListMessages(sessionToken, channelID string, limit int) (ListMes
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, err := s.authenticateSessionLocked(sessionToken); err != nil {
-		return ListMessagesResult{}, err
-	}
-	if err := s.ensureTextChannelLocked(channelID); err != nil {
+	if err := s.ensureHistoryAccessLocked(sessionToken, channelID); err != nil {
 		return ListMessagesResult{}, err
 	}
 
@@ -100,42 +132,123 @@ func (s *State) ListMessages(sessionToken, channelID string, limit int) (ListMes
 		limit = defaultMessageHistoryLimit
 	}
 
+	return s.listHistoryLocked(channelID, historyCursor{}, limit)
+}
+
+// ListMessagesPage pages through history with OFFSET (page-1)*pageSize. Deep
+// pages get slower as SQLite has to skip every earlier row, so clients walking
+// large histories should prefer the since cursor of the poll endpoint.
+func (s *State) ListMessagesPage(sessionToken, channelID string, page, pageSize int) (MessagePageResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureHistoryAccessLocked(sessionToken, channelID); err != nil {
+		return MessagePageResult{}, err
+	}
+
+	if page < 1 {
+		return MessagePageResult{}, newAPIError(400, "invalid_page", "page must be a positive integer")
+	}
+	if pageSize < 1 || pageSize > maxMessageHistoryLimit {
+		return MessagePageResult{}, newAPIError(400, "invalid_page_size", fmt.Sprintf("pageSize must be between 1 and %d", maxMessageHistoryLimit))
+	}
+	// Larger pages would overflow the OFFSET below.
+	if page-1 > math.MaxInt/pageSize {
+		return MessagePageResult{}, newAPIError(400, "invalid_page", "page is too large")
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE channel_id = ?`, channelID).Scan(&total); err != nil {
+		return MessagePageResult{}, fmt.Errorf("count messages: %w", err)
+	}
+
+	messages, _, err := s.queryHistoryLocked(channelID, historyCursor{}, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return MessagePageResult{}, err
+	}
+	return MessagePageResult{
+		Messages:      messages,
+		Page:          page,
+		PageSize:      pageSize,
+		TotalMessages: total,
+	}, nil
+}
+
+// ensureHistoryAccessLocked authorizes reading a channel's history. Anonymous
+// reads are only allowed on channels flagged for public preview.
+func (s *State) ensureHistoryAccessLocked(sessionToken, channelID string) error {
+	anonymous := strings.TrimSpace(sessionToken) == "" && s.publicPreviewEnabledLocked(channelID)
+	var identity SessionIdentity
+	if !anonymous {
+		var err error
+		if identity, err = s.authenticateSessionLocked(sessionToken); err != nil {
+			return err
+		}
+	}
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return err
+	}
+	if !anonymous {
+		return s.ensureChannelAccessLocked(identity, channelID, false)
+	}
+	return nil
+}
+
+// queryHistoryLocked returns up to limit messages older than cursor (if set),
+// skipping the offset newest ones, in chronological order, together with the
+// cursor of the oldest returned message.
+func (s *State) queryHistoryLocked(channelID string, cursor historyCursor, limit, offset int) ([]ChannelMessage, historyCursor, error) {
+	where, args := `channel_id = ?`, []any{channelID}
+	if cursor.valid() {
+		where += ` AND (created_at < ? OR (created_at = ? AND rowid < ?))`
+		args = append(args, cursor.CreatedAt, cursor.CreatedAt, cursor.RowID)
+	}
 	rows, err := s.db.Query(`
-		SELECT id, channel_id, author_public_key, author_name, content_markdown, created_at, updated_at
+		SELECT rowid, id, channel_id, author_public_key, author_name, content_markdown, content_encrypted, edited_by_public_key, edit_count, message_type, created_at, updated_at
 		FROM messages
-		WHERE channel_id = ?
-		ORDER BY created_at DESC
-		LIMIT ?
-	`, channelID, limit)
+		WHERE `+where+`
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
 	if err != nil {
-		return ListMessagesResult{}, fmt.Errorf("query messages: %w", err)
+		return nil, historyCursor{}, fmt.Errorf("query messages: %w", err)
 	}
 	defer rows.Close()
 
 	desc := make([]ChannelMessage, 0, limit)
+	var oldest historyCursor
 	for rows.Next() {
-		message, err := scanMessageRow(rows)
+		var rowID int64
+		message, err := scanMessageRow(rowIDScanner{rows: rows, rowID: &rowID})
 		if err != nil {
-			return ListMessagesResult{}, err
+			return nil, historyCursor{}, err
 		}
 		desc = append(desc, message)
+		oldest = historyCursor{CreatedAt: message.CreatedAt, RowID: rowID}
 	}
 	if err := rows.Err(); err != nil {
-		return ListMessagesResult{}, fmt.Errorf("iterate message rows: %w", err)
+		return nil, historyCursor{}, fmt.Errorf("iterate message rows: %w", err)
 	}
 
 	messages := make([]ChannelMessage, 0, len(desc))
 	for i := len(desc) - 1; i >= 0; i-- {
 		messages = append(messages, desc[i])
 	}
-
-	return ListMessagesResult{Messages: messages}, nil
+	return messages, oldest, nil
 }
 
 func (s *State) CreateMessage(sessionToken, channelID, contentMarkdown string) (ChannelMessage, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.createMessageLocked(sessionToken, channelID, messageBody{markdown: contentMarkdown}, MessageTypeNormal)
+}
+
+func (s *State) createMessageLocked(sessionToken, channelID string, body messageBody, messageType string) (ChannelMessage, error) {
+	if err := s.ensureWritableLocked(); err != nil {
+		return ChannelMessage{}, err
+	}
+
 	identity, err := s.authenticateSessionLocked(sessionToken)
 	if err != nil {
 		return ChannelMessage{}, err
@@ -143,25 +256,33 @@ func (s *State) CreateMessage(sessionToken, channelID, contentMarkdown string) (
 	if err := s.ensureTextChannelLocked(channelID); err != nil {
 		return ChannelMessage{}, err
 	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, true); err != nil {
+		return ChannelMessage{}, err
+	}
 
-	content, err := normalizeMessageContent(contentMarkdown)
+	messageType, err = s.ensureMessageTypeAllowedLocked(identity, channelID, messageType)
+	if err != nil {
+		return ChannelMessage{}, err
+	}
+	body, err = s.normalizeMessageBodyLocked(identity, channelID, body)
 	if err != nil {
 		return ChannelMessage{}, err
 	}
 
+	postedAt := time.Now().UTC()
+	if err := s.enforceSlowModeLocked(identity, channelID, postedAt); err != nil {
+		return ChannelMessage{}, err
+	}
+	if err := s.enforceMessageRateLocked(identity, postedAt); err != nil {
+		return ChannelMessage{}, err
+	}
+
 	messageID, err := randomHex(16)
 	if err != nil {
 		return ChannelMessage{}, fmt.Errorf("generate message id: %w", err)
 	}
 
-	now := time.Now().UTC().Format(time.RFC3339)
-	if _, err := s.db.Exec(`
-		INSERT INTO messages(id, channel_id, author_public_key, author_name, content_markdown, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, messageID, channelID, identity.PublicKey, identity.DisplayName, content, now, now); err != nil {
-		return ChannelMessage{}, fmt.Errorf("insert message: %w", err)
-	}
-
+	now := postedAt.Format(time.RFC3339)
 	message := ChannelMessage{
 		ID:        messageID,
 		ChannelID: channelID,
@@ -169,14 +290,24 @@ func (s *State) CreateMessage(sessionToken, channelID, contentMarkdown string) (
 			DisplayName: identity.DisplayName,
 			PublicKey:   identity.PublicKey,
 		},
-		ContentMarkdown: content,
-		CreatedAt:       now,
-		UpdatedAt:       now,
+		ContentMarkdown:  body.markdown,
+		ContentEncrypted: body.encrypted,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		MessageType:      messageType,
+	}
+	evicted, err := s.insertMessageLocked(message)
+	if err != nil {
+		return ChannelMessage{}, err
 	}
+	s.recordPostLocked(identity, channelID, postedAt)
+	s.consumeMessageTokenLocked(identity, postedAt)
+
 	s.broadcastChannelEventLocked(channelID, ChannelEvent{
 		Type:    "message.created",
 		Message: &message,
 	})
+	s.broadcastEvictedLocked(channelID, evicted)
 
 	return message, nil
 }
@@ -185,14 +316,26 @@ func (s *State) EditMessage(sessionToken, channelID, messageID, contentMarkdown
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, err := s.authenticateSessionLocked(sessionToken); err != nil {
+	return s.editMessageLocked(sessionToken, channelID, messageID, messageBody{markdown: contentMarkdown})
+}
+
+func (s *State) editMessageLocked(sessionToken, channelID, messageID string, body messageBody) (ChannelMessage, error) {
+	if err := s.ensureWritableLocked(); err != nil {
+		return ChannelMessage{}, err
+	}
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
 		return ChannelMessage{}, err
 	}
 	if err := s.ensureTextChannelLocked(channelID); err != nil {
 		return ChannelMessage{}, err
 	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, true); err != nil {
+		return ChannelMessage{}, err
+	}
 
-	content, err := normalizeMessageContent(contentMarkdown)
+	body, err = s.normalizeMessageBodyLocked(identity, channelID, body)
 	if err != nil {
 		return ChannelMessage{}, err
 	}
@@ -202,17 +345,28 @@ func (s *State) EditMessage(sessionToken, channelID, messageID, contentMarkdown
 		return ChannelMessage{}, err
 	}
 
+	var editedBy sql.NullString
+	if identity.PublicKey != existing.Author.PublicKey {
+		if !s.hasChannelPermissionLocked(identity.PublicKey, channelID, PermissionModerateMessages) {
+			return ChannelMessage{}, newAPIError(403, "not_message_author", "only the author or a moderator can edit this message")
+		}
+		editedBy = sql.NullString{String: identity.PublicKey, Valid: true}
+	}
+
 	updatedAt := time.Now().UTC().Format(time.RFC3339)
 	if _, err := s.db.Exec(`
 		UPDATE messages
-		SET content_markdown = ?, updated_at = ?
+		SET content_markdown = ?, content_encrypted = ?, edited_by_public_key = ?, edit_count = edit_count + 1, updated_at = ?
 		WHERE id = ? AND channel_id = ?
-	`, content, updatedAt, messageID, channelID); err != nil {
+	`, body.markdown, body.encryptedValue(), editedBy, updatedAt, messageID, channelID); err != nil {
 		return ChannelMessage{}, fmt.Errorf("update message: %w", err)
 	}
 
 	updated := existing
-	updated.ContentMarkdown = content
+	updated.ContentMarkdown = body.markdown
+	updated.ContentEncrypted = body.encrypted
+	updated.EditedBy = editedBy.String
+	updated.Edited = true
 	updated.UpdatedAt = updatedAt
 
 	s.broadcastChannelEventLocked(channelID, ChannelEvent{
@@ -227,12 +381,19 @@ func (s *State) SubscribeChannelEvents(sessionToken, channelID string) (<-chan C
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, err := s.authenticateSessionLocked(sessionToken); err != nil {
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
 		return nil, nil, err
 	}
 	if err := s.ensureTextChannelLocked(channelID); err != nil {
 		return nil, nil, err
 	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return nil, nil, err
+	}
+	if s.streamsClosed {
+		return nil, nil, newAPIError(503, "server_shutdown", "server is shutting down")
+	}
 
 	if _, exists := s.streams[channelID]; !exists {
 		s.streams[channelID] = make(map[int]chan ChannelEvent)
@@ -240,13 +401,23 @@ func (s *State) SubscribeChannelEvents(sessionToken, channelID string) (<-chan C
 
 	s.nextStream++
 	streamID := s.nextStream
-	stream := make(chan ChannelEvent, 32)
+	stream := make(chan ChannelEvent, s.streamBufferSize())
 	s.streams[channelID][streamID] = stream
+	s.streamMembers[identity.PublicKey]++
+	s.streamOwners[streamID] = identity.PublicKey
+	s.openStreams.Add(1)
 
+	cancelled := false
 	cancel := func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
+		if cancelled {
+			return
+		}
+		cancelled = true
+		defer s.openStreams.Done()
+
 		channelStreams, exists := s.streams[channelID]
 		if !exists {
 			return
@@ -257,7 +428,11 @@ func (s *State) SubscribeChannelEvents(sessionToken, channelID string) (<-chan C
 			return
 		}
 		delete(channelStreams, streamID)
+		delete(s.streamOwners, streamID)
 		close(ch)
+		if s.streamMembers[identity.PublicKey]--; s.streamMembers[identity.PublicKey] <= 0 {
+			delete(s.streamMembers, identity.PublicKey)
+		}
 		if len(channelStreams) == 0 {
 			delete(s.streams, channelID)
 		}
@@ -266,7 +441,25 @@ func (s *State) SubscribeChannelEvents(sessionToken, channelID string) (<-chan C
 	return stream, cancel, nil
 }
 
+// ValidateChannelStream re-checks an open stream's session and channel access so
+// long-lived subscriptions notice expired sessions and removed channels.
+func (s *State) ValidateChannelStream(sessionToken, channelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return err
+	}
+	return s.ensureChannelAccessLocked(identity, channelID, false)
+}
+
 func (s *State) broadcastChannelEventLocked(channelID string, event ChannelEvent) {
+	s.notifyWebhookLocked(channelID, event)
+
 	channelStreams, exists := s.streams[channelID]
 	if !exists {
 		return
@@ -276,6 +469,8 @@ func (s *State) broadcastChannelEventLocked(channelID string, event ChannelEvent
 		select {
 		case stream <- event:
 		default:
+			s.streamDrops[channelID]++
+			slog.Debug("dropped channel event for slow subscriber", "channel_id", channelID, "event_type", event.Type)
 		}
 	}
 }
@@ -299,20 +494,41 @@ func (s *State) ensureTextChannelLocked(channelID string) error {
 	return newAPIError(404, "channel_not_found", "channel does not exist")
 }
 
-func normalizeMessageContent(contentMarkdown string) (string, error) {
+// normalizeMessageContent trims markdown content and checks it against limit,
+// the effective length limit of the target channel.
+func normalizeMessageContent(contentMarkdown string, limit int) (string, error) {
 	content := strings.TrimSpace(contentMarkdown)
 	if content == "" {
 		return "", newAPIError(400, "invalid_message", "message content cannot be empty")
 	}
-	if len(content) > maxMessageLength {
+	if len(content) > limit {
 		return "", newAPIError(400, "invalid_message", "message content exceeds maximum length")
 	}
 	return content, nil
 }
 
+// GetMessage returns a single message, e.g. to resolve a reply or a deep link.
+func (s *State) GetMessage(sessionToken, channelID, messageID string) (ChannelMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return ChannelMessage{}, err
+	}
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return ChannelMessage{}, err
+	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return ChannelMessage{}, err
+	}
+
+	return s.findMessageLocked(channelID, strings.TrimSpace(messageID))
+}
+
 func (s *State) findMessageLocked(channelID, messageID string) (ChannelMessage, error) {
 	row := s.db.QueryRow(`
-		SELECT id, channel_id, author_public_key, author_name, content_markdown, created_at, updated_at
+		SELECT id, channel_id, author_public_key, author_name, content_markdown, content_encrypted, edited_by_public_key, edit_count, message_type, created_at, updated_at
 		FROM messages
 		WHERE id = ? AND channel_id = ?
 	`, messageID, channelID)
@@ -335,11 +551,15 @@ func scanMessageRow(scanner messageScanner) (ChannelMessage, error) {
 		authorPublic string
 		authorName   string
 		content      string
+		encrypted    sql.NullString
+		editedBy     sql.NullString
+		editCount    int
+		messageType  string
 		createdAt    string
 		updatedAt    string
 	)
 
-	if err := scanner.Scan(&messageID, &channelID, &authorPublic, &authorName, &content, &createdAt, &updatedAt); err != nil {
+	if err := scanner.Scan(&messageID, &channelID, &authorPublic, &authorName, &content, &encrypted, &editedBy, &editCount, &messageType, &createdAt, &updatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ChannelMessage{}, newAPIError(404, "message_not_found", "message does not exist")
 		}
@@ -353,9 +573,14 @@ func scanMessageRow(scanner messageScanner) (ChannelMessage, error) {
 			DisplayName: authorName,
 			PublicKey:   authorPublic,
 		},
-		ContentMarkdown: content,
-		CreatedAt:       createdAt,
-		UpdatedAt:       updatedAt,
+		ContentMarkdown:  content,
+		ContentEncrypted: encrypted.String,
+		EditedBy:         editedBy.String,
+		Edited:           editCount > 0,
+		CreatedAt:        createdAt,
+		UpdatedAt:        updatedAt,
+		System:           authorPublic == systemAuthorPublicKey,
+		MessageType:      messageType,
 	}, nil
 }
 
@@ -375,7 +600,7 @@ func (s *State) upsertMemberLocked(publicKey, displayName string) error {
 
 func (s *State) issueSessionTokenLocked(publicKey string) (string, error) {
 	now := time.Now().UTC()
-	token, err := randomHex(32)
+	token, err := s.newSessionToken()
 	if err != nil {
 		return "", fmt.Errorf("generate session token: %w", err)
 	}
@@ -0,0 +1,230 @@
+package serverstate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestListMessagesPage(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	for i := 1; i <= 5; i++ {
+		if _, err := s.CreateMessage(member.SessionToken, "general", fmt.Sprintf("message %d", i)); err != nil {
+			t.Fatalf("create message failed: %v", err)
+		}
+	}
+
+	first, err := s.ListMessagesPage(member.SessionToken, "general", 1, 2)
+	if err != nil {
+		t.Fatalf("list page failed: %v", err)
+	}
+	if first.TotalMessages != 5 || first.Page != 1 || first.PageSize != 2 || len(first.Messages) != 2 {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	last, err := s.ListMessagesPage(member.SessionToken, "general", 3, 2)
+	if err != nil {
+		t.Fatalf("list page failed: %v", err)
+	}
+	if len(last.Messages) != 1 || last.Messages[0].ContentMarkdown != "message 1" {
+		t.Fatalf("expected the oldest message on the last page, got %+v", last.Messages)
+	}
+
+	_, err = s.ListMessagesPage(member.SessionToken, "general", 0, 2)
+	requireAPIErrorCode(t, err, "invalid_page")
+	_, err = s.ListMessagesPage(member.SessionToken, "general", math.MaxInt, 2)
+	requireAPIErrorCode(t, err, "invalid_page")
+	_, err = s.ListMessagesPage(member.SessionToken, "general", 1, maxMessageHistoryLimit+1)
+	requireAPIErrorCode(t, err, "invalid_page_size")
+}
+
+func TestEditMessageRecordsEditorOtherThanAuthor(t *testing.T) {
+	s := newTestState(t, nil)
+	author := connectTestMember(t, s, "author")
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+
+	message, err := s.CreateMessage(author.SessionToken, "general", "original")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	events, cancel, err := s.SubscribeChannelEvents(author.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	edited, err := s.EditMessage(admin.SessionToken, "general", message.ID, "moderated")
+	if err != nil {
+		t.Fatalf("admin edit failed: %v", err)
+	}
+	if edited.EditedBy != admin.PublicKey || edited.Author.PublicKey != author.PublicKey {
+		t.Fatalf("expected original author with admin as editor, got %+v", edited)
+	}
+	if event := <-events; event.Type != "message.updated" || event.Message.EditedBy != admin.PublicKey {
+		t.Fatalf("unexpected update event: %+v", event)
+	}
+	if stored, err := s.GetMessage(author.SessionToken, "general", message.ID); err != nil || stored.EditedBy != admin.PublicKey {
+		t.Fatalf("expected editedBy to be stored, got %+v, %v", stored, err)
+	}
+
+	edited, err = s.EditMessage(author.SessionToken, "general", message.ID, "reworded")
+	if err != nil {
+		t.Fatalf("author edit failed: %v", err)
+	}
+	if edited.EditedBy != "" {
+		t.Fatalf("editedBy must clear once the author edits again, got %q", edited.EditedBy)
+	}
+}
+
+func TestEditMessageRejectsOtherMembers(t *testing.T) {
+	s := newTestState(t, nil)
+	author := connectTestMember(t, s, "author")
+	other := connectTestMember(t, s, "other")
+
+	message, err := s.CreateMessage(author.SessionToken, "general", "original")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	_, err = s.EditMessage(other.SessionToken, "general", message.ID, "rewritten")
+	requireAPIErrorCode(t, err, "not_message_author")
+	if stored, err := s.GetMessage(author.SessionToken, "general", message.ID); err != nil || stored.ContentMarkdown != "original" || stored.Edited {
+		t.Fatalf("rejected edit must leave the message untouched, got %+v, %v", stored, err)
+	}
+}
+
+func TestListMessagesReportsEditedMessages(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	message, err := s.CreateMessage(member.SessionToken, "general", "original")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if _, err := s.CreateMessage(member.SessionToken, "general", "untouched"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if message.Edited {
+		t.Fatal("new messages must not be marked edited")
+	}
+	if _, err := s.EditMessage(member.SessionToken, "general", message.ID, "reworded"); err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+
+	result, err := s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	if len(result.Messages) != 2 || !result.Messages[0].Edited || result.Messages[1].Edited {
+		t.Fatalf("expected only the first message to be edited, got %+v", result.Messages)
+	}
+}
+
+func TestPostMessageTypesRequireChannelAdmin(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+
+	events, cancel, err := s.SubscribeChannelEvents(member.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	plain, err := s.CreateMessage(member.SessionToken, "general", "hello")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if plain.MessageType != MessageTypeNormal {
+		t.Fatalf("expected a normal message, got %q", plain.MessageType)
+	}
+
+	_, err = s.PostMessage(member.SessionToken, "general", MessageInput{ContentMarkdown: "listen up", MessageType: "announcement"})
+	requireAPIErrorCode(t, err, "missing_permission")
+	_, err = s.PostMessage(admin.SessionToken, "general", MessageInput{ContentMarkdown: "hi", MessageType: "shout"})
+	requireAPIErrorCode(t, err, "invalid_message_type")
+
+	announcement, err := s.PostMessage(admin.SessionToken, "general", MessageInput{ContentMarkdown: "Release tonight", MessageType: "Announcement"})
+	if err != nil {
+		t.Fatalf("admin announcement failed: %v", err)
+	}
+	if announcement.MessageType != MessageTypeAnnouncement {
+		t.Fatalf("expected announcement, got %q", announcement.MessageType)
+	}
+
+	<-events
+	if event := <-events; event.Message == nil || event.Message.MessageType != MessageTypeAnnouncement {
+		t.Fatalf("expected the event to carry the message type, got %+v", event)
+	}
+	result, err := s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	if len(result.Messages) != 2 || result.Messages[0].MessageType != MessageTypeNormal || result.Messages[1].MessageType != MessageTypeAnnouncement {
+		t.Fatalf("expected stored message types, got %+v", result.Messages)
+	}
+}
+
+func TestGetMessageContextReturnsSurroundingWindow(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	ids := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		message, err := s.CreateMessage(member.SessionToken, "general", fmt.Sprintf("message %d", i))
+		if err != nil {
+			t.Fatalf("create message failed: %v", err)
+		}
+		ids = append(ids, message.ID)
+	}
+
+	result, err := s.GetMessageContext(member.SessionToken, "general", ids[2], 1, 10)
+	if err != nil {
+		t.Fatalf("get context failed: %v", err)
+	}
+	if result.Message.ID != ids[2] {
+		t.Fatalf("unexpected target: %s", result.Message.ID)
+	}
+	got := make([]string, 0, len(result.Messages))
+	for _, message := range result.Messages {
+		got = append(got, message.ID)
+	}
+	if strings.Join(got, ",") != strings.Join(ids[1:], ",") {
+		t.Fatalf("unexpected window: got=%v want=%v", got, ids[1:])
+	}
+
+	result, err = s.GetMessageContext(member.SessionToken, "general", ids[0], -3, 0)
+	if err != nil || len(result.Messages) != 1 || result.Messages[0].ID != ids[0] {
+		t.Fatalf("negative window should clamp to the target only, got %+v, %v", result.Messages, err)
+	}
+
+	_, err = s.GetMessageContext(member.SessionToken, "general", "missing", 5, 5)
+	requireAPIErrorCode(t, err, "message_not_found")
+}
+
+func TestGetMessageContextFollowsHistoryOrderForImports(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	live, err := s.CreateMessage(member.SessionToken, "general", "live")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if _, err := s.ImportMessages("general", []ImportedMessage{
+		{ID: "imported", AuthorPublicKey: "legacy-key", ContentMarkdown: "old", CreatedAt: "2020-01-01T10:00:00Z"},
+	}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	result, err := s.GetMessageContext(member.SessionToken, "general", live.ID, 1, 1)
+	if err != nil {
+		t.Fatalf("get context failed: %v", err)
+	}
+	if len(result.Messages) != 2 || result.Messages[0].ID != "imported" || result.Messages[1].ID != live.ID {
+		t.Fatalf("imported message must precede the live one as in history, got %+v", result.Messages)
+	}
+}
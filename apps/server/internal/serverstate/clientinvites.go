@@ -0,0 +1,95 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const clientInvitesPayloadPrefix = "fosscord-client-invites:"
+
+type ListClientInvitesRequest struct {
+	ClientPublicKey string
+	IssuedAt        string
+	Signature       string
+}
+
+// ClientInvitesPayloadHash is what a client signs to list its own invites. The
+// prefix and fingerprint keep it from being replayed as an admin list request
+// or against another server.
+func ClientInvitesPayloadHash(clientPublicKey, issuedAt, serverFingerprint string) [32]byte {
+	payload := make([]byte, 0, len(clientInvitesPayloadPrefix)+len(clientPublicKey)+len(issuedAt)+len(serverFingerprint))
+	payload = append(payload, []byte(clientInvitesPayloadPrefix)...)
+	payload = append(payload, []byte(clientPublicKey)...)
+	payload = append(payload, []byte(issuedAt)...)
+	payload = append(payload, []byte(serverFingerprint)...)
+	return sha256.Sum256(payload)
+}
+
+// ListClientInvites returns the unused invites bound to a client key, so a client
+// can confirm an invite link is meant for it without redeeming it. The request
+// must be signed by that key, so invites cannot be enumerated for other keys.
+// Invite metadata stays admin-only, and invites created as adminOnly are hidden
+// unless the key itself belongs to an admin.
+func (s *State) ListClientInvites(req ListClientInvitesRequest) (ListInvitesResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req.ClientPublicKey = strings.TrimSpace(req.ClientPublicKey)
+	req.IssuedAt = strings.TrimSpace(req.IssuedAt)
+	req.Signature = strings.TrimSpace(req.Signature)
+
+	if req.ClientPublicKey == "" || req.IssuedAt == "" || req.Signature == "" {
+		return ListInvitesResult{}, newAPIError(400, "invalid_request", "clientPublicKey, issuedAt and signature are required")
+	}
+
+	clientKey, err := decodePublicKey(req.ClientPublicKey)
+	if err != nil {
+		return ListInvitesResult{}, newAPIError(400, "invalid_client_public_key", "clientPublicKey must be base64(ed25519 public key)")
+	}
+
+	issuedAt, err := time.Parse(time.RFC3339, req.IssuedAt)
+	if err != nil {
+		return ListInvitesResult{}, newAPIError(400, "invalid_issued_at", "issuedAt must be RFC3339")
+	}
+	if time.Since(issuedAt.UTC()) > adminRequestMaxSkew || time.Until(issuedAt.UTC()) > adminRequestMaxSkew {
+		return ListInvitesResult{}, newAPIError(401, "stale_request", "issuedAt is outside allowed skew")
+	}
+
+	signature, err := decodeSignature(req.Signature)
+	if err != nil {
+		return ListInvitesResult{}, newAPIError(400, "invalid_signature", "signature must be base64(ed25519 signature)")
+	}
+
+	hash := ClientInvitesPayloadHash(req.ClientPublicKey, req.IssuedAt, s.serverFingerprint)
+	if !ed25519.Verify(clientKey, hash[:], signature) {
+		return ListInvitesResult{}, newAPIError(401, "invalid_signature", "signature verification failed")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, label, created_at
+		FROM invites
+		WHERE allowed_client_public_key = ? AND used_at IS NULL AND (admin_only = 0 OR ?)
+		ORDER BY created_at DESC
+	`, req.ClientPublicKey, s.isAdminPublicKeyLocked(req.ClientPublicKey))
+	if err != nil {
+		return ListInvitesResult{}, fmt.Errorf("query client invites: %w", err)
+	}
+	defer rows.Close()
+
+	result := ListInvitesResult{Invites: []InviteSummary{}}
+	for rows.Next() {
+		invite := InviteSummary{AllowedClientPublicKey: req.ClientPublicKey, Status: "active"}
+		if err := rows.Scan(&invite.InviteID, &invite.Label, &invite.CreatedAt); err != nil {
+			return ListInvitesResult{}, fmt.Errorf("scan client invite row: %w", err)
+		}
+		result.Invites = append(result.Invites, invite)
+	}
+	if err := rows.Err(); err != nil {
+		return ListInvitesResult{}, fmt.Errorf("iterate client invite rows: %w", err)
+	}
+
+	return result, nil
+}
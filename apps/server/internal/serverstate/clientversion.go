@@ -0,0 +1,129 @@
+package serverstate
+
+import (
+	"strconv"
+	"strings"
+)
+
+type clientVersion struct {
+	core       [3]int
+	prerelease []string
+}
+
+// parseClientVersion accepts MAJOR.MINOR.PATCH with an optional leading "v" and
+// "-prerelease"; build metadata after "+" is ignored, as semver requires.
+func parseClientVersion(value string) (clientVersion, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if build := strings.IndexByte(value, '+'); build >= 0 {
+		value = value[:build]
+	}
+
+	var version clientVersion
+	core, prerelease, hasPrerelease := strings.Cut(value, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return clientVersion{}, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part == "" || (len(part) > 1 && part[0] == '0') {
+			return clientVersion{}, false
+		}
+		version.core[i] = n
+	}
+	if hasPrerelease {
+		if prerelease == "" {
+			return clientVersion{}, false
+		}
+		version.prerelease = strings.Split(prerelease, ".")
+		for _, identifier := range version.prerelease {
+			if identifier == "" {
+				return clientVersion{}, false
+			}
+		}
+	}
+	return version, true
+}
+
+// compare orders versions by semver precedence: a prerelease ranks below its
+// release, numeric identifiers below alphanumeric ones.
+func (v clientVersion) compare(other clientVersion) int {
+	for i := range v.core {
+		if v.core[i] != other.core[i] {
+			if v.core[i] < other.core[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.prerelease) == 0 && len(other.prerelease) == 0:
+		return 0
+	case len(v.prerelease) == 0:
+		return 1
+	case len(other.prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.prerelease) && i < len(other.prerelease); i++ {
+		if c := comparePrereleaseIdentifier(v.prerelease[i], other.prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.prerelease) < len(other.prerelease):
+		return -1
+	case len(v.prerelease) > len(other.prerelease):
+		return 1
+	}
+	return 0
+}
+
+func comparePrereleaseIdentifier(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// ensureClientVersionLocked enforces MIN_CLIENT_VERSION on connect. Clients that
+// report no version, or one that does not parse, follow CLIENT_VERSION_MISSING.
+func (s *State) ensureClientVersionLocked(info ClientInfo) error {
+	if s.cfg.MinClientVersion == "" {
+		return nil
+	}
+	minimum, ok := parseClientVersion(s.cfg.MinClientVersion)
+	if !ok {
+		return nil
+	}
+
+	reported := strings.TrimSpace(info.AppVersion)
+	version, ok := parseClientVersion(reported)
+	if ok && version.compare(minimum) >= 0 {
+		return nil
+	}
+	if !ok && s.cfg.ClientVersionMissing != "deny" {
+		return nil
+	}
+
+	apiErr := newAPIError(426, "client_too_old", "client version "+s.cfg.MinClientVersion+" or newer is required")
+	apiErr.Details = map[string]any{"minClientVersion": s.cfg.MinClientVersion}
+	if reported != "" {
+		apiErr.Details["clientVersion"] = reported
+	}
+	return apiErr
+}
@@ -0,0 +1,62 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestClientVersionComparesBySemverPrecedence(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1+build.7", "1.10.0"}
+	for i := 1; i < len(ordered); i++ {
+		lower, ok := parseClientVersion(ordered[i-1])
+		if !ok {
+			t.Fatalf("failed to parse %q", ordered[i-1])
+		}
+		higher, ok := parseClientVersion(ordered[i])
+		if !ok {
+			t.Fatalf("failed to parse %q", ordered[i])
+		}
+		if lower.compare(higher) >= 0 || higher.compare(lower) <= 0 {
+			t.Fatalf("expected %q < %q", ordered[i-1], ordered[i])
+		}
+	}
+
+	for _, invalid := range []string{"", "1.0", "1.0.0.0", "01.0.0", "1.0.0-", "1.0.0-a..b", "latest"} {
+		if _, ok := parseClientVersion(invalid); ok {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestFinishConnectRejectsOutdatedClients(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) {
+		cfg.MinClientVersion = "1.4.0"
+		cfg.ClientVersionMissing = "deny"
+	})
+
+	_, err := s.FinishConnect(FinishRequest{
+		InviteID:        "invite",
+		ClientPublicKey: "key",
+		Challenge:       "challenge",
+		Signature:       "signature",
+		ClientInfo:      ClientInfo{AppVersion: "1.4.0-rc.1"},
+	})
+	apiErr := requireAPIErrorCode(t, err, "client_too_old")
+	if apiErr.Status != 426 || apiErr.Details["minClientVersion"] != "1.4.0" || apiErr.Details["clientVersion"] != "1.4.0-rc.1" {
+		t.Fatalf("unexpected error: status=%d details=%v", apiErr.Status, apiErr.Details)
+	}
+
+	s.mu.Lock()
+	if err := s.ensureClientVersionLocked(ClientInfo{}); !isAPIErrorCode(err, "client_too_old") {
+		t.Errorf("missing version must be denied by policy, got %v", err)
+	}
+	if err := s.ensureClientVersionLocked(ClientInfo{AppVersion: "1.4.2"}); err != nil {
+		t.Errorf("newer client must be accepted: %v", err)
+	}
+	s.cfg.ClientVersionMissing = "allow"
+	if err := s.ensureClientVersionLocked(ClientInfo{}); err != nil {
+		t.Errorf("missing version must be allowed by policy: %v", err)
+	}
+	s.mu.Unlock()
+}
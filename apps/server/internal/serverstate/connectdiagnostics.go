@@ -0,0 +1,62 @@
+package serverstate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConnectDiagnostics is the server's view of a connect handshake for support:
+// the invite row and the outstanding challenge, if any. The challenge value
+// itself is never included.
+type ConnectDiagnostics struct {
+	InviteID  string               `json:"inviteId"`
+	Invite    *InviteSummary       `json:"invite"`
+	Challenge *ChallengeDiagnostic `json:"challenge"`
+}
+
+type ChallengeDiagnostic struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	Expired   bool      `json:"expired"`
+	// RedeemedBy is set while a completed connect may still be retried.
+	RedeemedBy string `json:"redeemedBy,omitempty"`
+}
+
+// ConnectDiagnostics reports the invite and challenge state for inviteID
+// without side effects: expired challenges are reported, not removed. A nil
+// Invite means the invite does not exist (or was auto-deleted once used).
+func (s *State) ConnectDiagnostics(inviteID string) (ConnectDiagnostics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inviteID = strings.TrimSpace(inviteID)
+	if inviteID == "" {
+		return ConnectDiagnostics{}, newAPIError(400, "missing_invite_id", "inviteId is required")
+	}
+	result := ConnectDiagnostics{InviteID: inviteID}
+
+	rows, err := s.db.Query(`SELECT `+inviteSummaryColumns+` FROM invites WHERE id = ?`, inviteID)
+	if err != nil {
+		return ConnectDiagnostics{}, fmt.Errorf("query invite: %w", err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		invite, err := scanInviteSummary(rows)
+		if err != nil {
+			return ConnectDiagnostics{}, fmt.Errorf("scan invite: %w", err)
+		}
+		result.Invite = &invite
+	}
+	if err := rows.Err(); err != nil {
+		return ConnectDiagnostics{}, fmt.Errorf("iterate invite: %w", err)
+	}
+
+	if pending, ok := s.challenges[inviteID]; ok {
+		result.Challenge = &ChallengeDiagnostic{
+			ExpiresAt:  pending.ExpiresAt,
+			Expired:    time.Now().UTC().After(pending.ExpiresAt),
+			RedeemedBy: pending.RedeemedBy,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConnectDiagnosticsReportsChallengeWithoutValue(t *testing.T) {
+	s := newTestState(t, nil)
+	pub, _, _ := ed25519.GenerateKey(nil)
+	invite, err := s.CreateInvite(base64.StdEncoding.EncodeToString(pub), "support", nil, false)
+	if err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+
+	diagnostics, err := s.ConnectDiagnostics(invite.InviteID)
+	if err != nil {
+		t.Fatalf("diagnostics failed: %v", err)
+	}
+	if diagnostics.Invite == nil || diagnostics.Invite.Status != "active" || diagnostics.Challenge != nil {
+		t.Fatalf("unexpected diagnostics before begin: %+v", diagnostics)
+	}
+
+	begin, err := s.BeginConnect(invite.InviteID)
+	if err != nil {
+		t.Fatalf("begin connect failed: %v", err)
+	}
+	diagnostics, err = s.ConnectDiagnostics(invite.InviteID)
+	if err != nil {
+		t.Fatalf("diagnostics failed: %v", err)
+	}
+	if diagnostics.Challenge == nil || diagnostics.Challenge.Expired || !diagnostics.Challenge.ExpiresAt.Equal(begin.ExpiresAt) {
+		t.Fatalf("unexpected challenge diagnostics: %+v", diagnostics.Challenge)
+	}
+	encoded, _ := json.Marshal(diagnostics)
+	if strings.Contains(string(encoded), begin.Challenge) {
+		t.Fatalf("diagnostics leak the challenge: %s", encoded)
+	}
+
+	missing, err := s.ConnectDiagnostics("missing")
+	if err != nil || missing.Invite != nil || missing.Challenge != nil {
+		t.Fatalf("unexpected diagnostics for a missing invite: %+v, %v", missing, err)
+	}
+	_, err = s.ConnectDiagnostics(" ")
+	requireAPIErrorCode(t, err, "missing_invite_id")
+}
@@ -0,0 +1,99 @@
+package serverstate
+
+import "context"
+
+// AcquireWebsocketSlot reserves one of the MAX_WS_CONNECTIONS upgraded
+// connections. The returned release func must be called once the connection
+// closes; it is safe to call more than once. A limit of 0 disables the cap.
+func (s *State) AcquireWebsocketSlot() (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit := s.cfg.MaxWSConnections; limit > 0 && s.wsConnections >= limit {
+		return nil, newAPIError(503, "too_many_connections", "server has reached its websocket connection limit")
+	}
+	s.wsConnections++
+
+	released := false
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if released {
+			return
+		}
+		released = true
+		s.wsConnections--
+	}, nil
+}
+
+// CloseAllStreams ends every channel subscription during shutdown: each
+// subscriber gets a terminal server.shutdown event (when its buffer has room)
+// and its channel is closed. Later subscriptions are refused.
+func (s *State) CloseAllStreams() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.streamsClosed = true
+	for channelID, channelStreams := range s.streams {
+		for _, stream := range channelStreams {
+			select {
+			case stream <- ChannelEvent{Type: "server.shutdown"}:
+			default:
+			}
+			close(stream)
+		}
+		delete(s.streams, channelID)
+	}
+	clear(s.streamMembers)
+	clear(s.streamOwners)
+}
+
+// closeMemberStreamsLocked ends the subscriptions of a removed member right
+// away instead of at the next revalidation. Their handlers see the closed
+// channel and find the session gone.
+func (s *State) closeMemberStreamsLocked(publicKey string) {
+	for channelID, channelStreams := range s.streams {
+		for streamID, stream := range channelStreams {
+			if s.streamOwners[streamID] != publicKey {
+				continue
+			}
+			close(stream)
+			delete(channelStreams, streamID)
+			delete(s.streamOwners, streamID)
+		}
+		if len(channelStreams) == 0 {
+			delete(s.streams, channelID)
+		}
+	}
+	delete(s.streamMembers, publicKey)
+}
+
+// WaitForStreams blocks until every subscription has been cancelled by its
+// handler, or ctx is done. Stream handlers run on hijacked connections that
+// http.Server.Shutdown does not wait for, so call this after CloseAllStreams and
+// before Close.
+func (s *State) WaitForStreams(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.openStreams.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes pending webhook deliveries and releases the database. Call it
+// after the HTTP server has shut down.
+func (s *State) Close() error {
+	s.stopSessionSweeper()
+	if s.messageWebhook != nil {
+		s.messageWebhook.Close()
+	}
+	return s.db.Close()
+}
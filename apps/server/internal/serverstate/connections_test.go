@@ -0,0 +1,41 @@
+package serverstate
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCloseAllStreamsSendsShutdownAndCloses(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	stream, cancel, err := s.SubscribeChannelEvents(member.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	s.CloseAllStreams()
+
+	ctx, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	if err := s.WaitForStreams(ctx); err == nil {
+		t.Fatal("WaitForStreams must block until the handler cancels its subscription")
+	}
+	cancel()
+	cancel()
+	if err := s.WaitForStreams(context.Background()); err != nil {
+		t.Fatalf("wait for streams failed: %v", err)
+	}
+
+	event, ok := <-stream
+	if !ok || event.Type != "server.shutdown" {
+		t.Fatalf("expected server.shutdown event, got %+v (open=%v)", event, ok)
+	}
+	if _, ok := <-stream; ok {
+		t.Fatal("stream must be closed after the shutdown event")
+	}
+
+	_, _, err = s.SubscribeChannelEvents(member.SessionToken, "general")
+	requireAPIErrorCode(t, err, "server_shutdown")
+}
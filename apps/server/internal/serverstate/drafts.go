@@ -0,0 +1,111 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Draft struct {
+	ChannelID       string `json:"channelId"`
+	ContentMarkdown string `json:"contentMarkdown"`
+	UpdatedAt       string `json:"updatedAt"`
+}
+
+// SaveDraft stores the member's unsent message for the channel so it can be
+// resumed on another device. Drafts are private and never broadcast. Empty
+// content deletes the draft, in which case nil is returned.
+func (s *State) SaveDraft(sessionToken, channelID, contentMarkdown string) (*Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return nil, err
+	}
+	identity, err := s.draftIdentityLocked(sessionToken, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(contentMarkdown) == "" {
+		return nil, s.deleteDraftLocked(identity.PublicKey, channelID)
+	}
+	if len(contentMarkdown) > s.messageLengthLimitLocked(channelID) {
+		return nil, newAPIError(400, "invalid_draft", "draft content exceeds maximum length")
+	}
+
+	draft := Draft{
+		ChannelID:       channelID,
+		ContentMarkdown: contentMarkdown,
+		UpdatedAt:       time.Now().UTC().Format(time.RFC3339),
+	}
+	if _, err := s.db.Exec(`
+		INSERT INTO drafts(client_public_key, channel_id, content_markdown, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(client_public_key, channel_id) DO UPDATE SET
+			content_markdown = excluded.content_markdown,
+			updated_at = excluded.updated_at
+	`, identity.PublicKey, channelID, draft.ContentMarkdown, draft.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("upsert draft: %w", err)
+	}
+	return &draft, nil
+}
+
+func (s *State) GetDraft(sessionToken, channelID string) (Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.draftIdentityLocked(sessionToken, channelID)
+	if err != nil {
+		return Draft{}, err
+	}
+
+	draft := Draft{ChannelID: channelID}
+	err = s.db.QueryRow(`
+		SELECT content_markdown, updated_at FROM drafts WHERE client_public_key = ? AND channel_id = ?
+	`, identity.PublicKey, channelID).Scan(&draft.ContentMarkdown, &draft.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Draft{}, newAPIError(404, "draft_not_found", "no draft saved for this channel")
+	}
+	if err != nil {
+		return Draft{}, fmt.Errorf("query draft: %w", err)
+	}
+	return draft, nil
+}
+
+func (s *State) DeleteDraft(sessionToken, channelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return err
+	}
+	identity, err := s.draftIdentityLocked(sessionToken, channelID)
+	if err != nil {
+		return err
+	}
+	return s.deleteDraftLocked(identity.PublicKey, channelID)
+}
+
+func (s *State) draftIdentityLocked(sessionToken, channelID string) (SessionIdentity, error) {
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return SessionIdentity{}, err
+	}
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return SessionIdentity{}, err
+	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, true); err != nil {
+		return SessionIdentity{}, err
+	}
+	return identity, nil
+}
+
+func (s *State) deleteDraftLocked(publicKey, channelID string) error {
+	if _, err := s.db.Exec(`DELETE FROM drafts WHERE client_public_key = ? AND channel_id = ?`, publicKey, channelID); err != nil {
+		return fmt.Errorf("delete draft: %w", err)
+	}
+	return nil
+}
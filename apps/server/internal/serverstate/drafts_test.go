@@ -0,0 +1,37 @@
+package serverstate
+
+import "testing"
+
+func TestDraftsArePrivateAndEmptyContentDeletes(t *testing.T) {
+	s := newTestState(t, nil)
+	alice := connectTestMember(t, s, "alice")
+	bob := connectTestMember(t, s, "bob")
+
+	if _, err := s.SaveDraft(alice.SessionToken, "general", "half-written"); err != nil {
+		t.Fatalf("save draft failed: %v", err)
+	}
+	if _, err := s.SaveDraft(alice.SessionToken, "general", "rewritten"); err != nil {
+		t.Fatalf("overwrite draft failed: %v", err)
+	}
+
+	draft, err := s.GetDraft(alice.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("get draft failed: %v", err)
+	}
+	if draft.ContentMarkdown != "rewritten" {
+		t.Fatalf("expected overwritten draft, got %+v", draft)
+	}
+
+	_, err = s.GetDraft(bob.SessionToken, "general")
+	requireAPIErrorCode(t, err, "draft_not_found")
+
+	saved, err := s.SaveDraft(alice.SessionToken, "general", "   ")
+	if err != nil || saved != nil {
+		t.Fatalf("expected empty content to delete the draft, got %+v, %v", saved, err)
+	}
+	_, err = s.GetDraft(alice.SessionToken, "general")
+	requireAPIErrorCode(t, err, "draft_not_found")
+
+	_, err = s.SaveDraft(alice.SessionToken, "voice-main", "nope")
+	requireAPIErrorCode(t, err, "invalid_channel_type")
+}
@@ -0,0 +1,85 @@
+package serverstate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// maxEncryptedMessageBytes caps the decoded ciphertext. It leaves room for
+// maxMessageLength of plaintext plus nonce, tag and padding overhead.
+const maxEncryptedMessageBytes = 16 * 1024
+
+// messageBody carries either markdown or, in e2ee channels, opaque ciphertext.
+type messageBody struct {
+	markdown  string
+	encrypted string
+}
+
+func (b messageBody) encryptedValue() any {
+	if b.encrypted == "" {
+		return nil
+	}
+	return b.encrypted
+}
+
+// CreateEncryptedMessage posts base64 ciphertext to an e2ee channel. Keys are
+// exchanged by clients out of band; the server never sees the plaintext.
+func (s *State) CreateEncryptedMessage(sessionToken, channelID, contentEncrypted string) (ChannelMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createMessageLocked(sessionToken, channelID, messageBody{encrypted: contentEncrypted}, MessageTypeNormal)
+}
+
+// EditEncryptedMessage replaces a message in an e2ee channel with new ciphertext.
+func (s *State) EditEncryptedMessage(sessionToken, channelID, messageID, contentEncrypted string) (ChannelMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.editMessageLocked(sessionToken, channelID, messageID, messageBody{encrypted: contentEncrypted})
+}
+
+// normalizeMessageBodyLocked validates a body against the channel's e2ee flag.
+// Markdown is trimmed and checked for mentions; ciphertext is only checked for
+// encoding and size, as the server cannot read it.
+func (s *State) normalizeMessageBodyLocked(identity SessionIdentity, channelID string, body messageBody) (messageBody, error) {
+	channel, _ := s.channelLocked(channelID)
+	if !channel.E2EE {
+		if body.encrypted != "" {
+			return messageBody{}, newAPIError(400, "encryption_not_enabled", "channel does not accept encrypted messages")
+		}
+		content, err := normalizeMessageContent(body.markdown, messageLengthLimit(channel))
+		if err != nil {
+			return messageBody{}, err
+		}
+		if err := s.ensureMentionsAllowedLocked(identity, channelID, content); err != nil {
+			return messageBody{}, err
+		}
+		return messageBody{markdown: content}, nil
+	}
+
+	if strings.TrimSpace(body.markdown) != "" {
+		return messageBody{}, newAPIError(400, "encryption_required", "channel only accepts contentEncrypted")
+	}
+	encrypted, err := normalizeEncryptedContent(body.encrypted)
+	if err != nil {
+		return messageBody{}, err
+	}
+	return messageBody{encrypted: encrypted}, nil
+}
+
+func normalizeEncryptedContent(contentEncrypted string) (string, error) {
+	encrypted := strings.TrimSpace(contentEncrypted)
+	if encrypted == "" {
+		return "", newAPIError(400, "encryption_required", "channel only accepts contentEncrypted")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", newAPIError(400, "invalid_message", "contentEncrypted must be base64")
+	}
+	if len(decoded) > maxEncryptedMessageBytes {
+		return "", newAPIError(400, "invalid_message", fmt.Sprintf("contentEncrypted exceeds %d bytes", maxEncryptedMessageBytes))
+	}
+	return encrypted, nil
+}
@@ -0,0 +1,51 @@
+package serverstate
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestE2EEChannelStoresCiphertextOpaquely(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	_, err := s.CreateEncryptedMessage(member.SessionToken, "general", "c2VhbGVk")
+	requireAPIErrorCode(t, err, "encryption_not_enabled")
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{E2EE: boolPointer(true)}); err != nil {
+		t.Fatalf("failed to enable e2ee: %v", err)
+	}
+	_, err = s.CreateMessage(member.SessionToken, "general", "plaintext")
+	requireAPIErrorCode(t, err, "encryption_required")
+
+	ciphertext := base64.StdEncoding.EncodeToString([]byte("  **not markdown** @everyone  "))
+	created, err := s.CreateEncryptedMessage(member.SessionToken, "general", ciphertext)
+	if err != nil {
+		t.Fatalf("create encrypted message failed: %v", err)
+	}
+	if created.ContentEncrypted != ciphertext || created.ContentMarkdown != "" {
+		t.Fatalf("unexpected message content: %+v", created)
+	}
+
+	edited := base64.StdEncoding.EncodeToString([]byte("edited"))
+	if _, err := s.EditEncryptedMessage(member.SessionToken, "general", created.ID, edited); err != nil {
+		t.Fatalf("edit encrypted message failed: %v", err)
+	}
+	result, err := s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	if len(result.Messages) != 1 || result.Messages[0].ContentEncrypted != edited {
+		t.Fatalf("expected stored ciphertext, got %+v", result.Messages)
+	}
+
+	_, err = s.CreateEncryptedMessage(member.SessionToken, "general", "not base64!")
+	requireAPIErrorCode(t, err, "invalid_message")
+	oversized := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", maxEncryptedMessageBytes+1)))
+	_, err = s.CreateEncryptedMessage(member.SessionToken, "general", oversized)
+	requireAPIErrorCode(t, err, "invalid_message")
+
+	_, err = s.UpdateChannel("voice-main", ChannelUpdate{E2EE: boolPointer(true)})
+	requireAPIErrorCode(t, err, "invalid_channel_settings")
+}
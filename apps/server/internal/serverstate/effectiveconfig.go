@@ -0,0 +1,31 @@
+package serverstate
+
+import (
+	"path/filepath"
+
+	"fosscord/apps/server/internal/config"
+)
+
+// EffectiveConfig is the configuration the server runs with after env parsing
+// and defaults, with secrets redacted, plus values derived from it.
+type EffectiveConfig struct {
+	Config          config.RedactedConfig `json:"config"`
+	ResolvedDataDir string                `json:"resolvedDataDir"`
+	DatabaseFile    string                `json:"databaseFile"`
+	SQLite          SQLiteSettings        `json:"sqlite"`
+}
+
+// EffectiveConfig reports the running configuration. DatabaseFile is the file
+// name the server resolved, also when DB_PATH is unset.
+func (s *State) EffectiveConfig() EffectiveConfig {
+	dataDir, err := filepath.Abs(s.cfg.DataDir)
+	if err != nil {
+		dataDir = s.cfg.DataDir
+	}
+	return EffectiveConfig{
+		Config:          s.cfg.Redacted(),
+		ResolvedDataDir: dataDir,
+		DatabaseFile:    filepath.Base(resolveDatabasePath(s.cfg)),
+		SQLite:          s.sqliteSettings,
+	}
+}
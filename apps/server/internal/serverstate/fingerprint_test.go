@@ -0,0 +1,41 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func TestFingerprintDistributionIsUniform(t *testing.T) {
+	const samples = 16000
+	buckets := len(fingerprintEmojis)
+
+	var counts [fingerprintLength][]int
+	for i := range counts {
+		counts[i] = make([]int, buckets)
+	}
+
+	for n := 0; n < samples; n++ {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		for position, index := range fingerprintIndexes(pub) {
+			counts[position][index]++
+		}
+	}
+
+	// Chi-square with 31 degrees of freedom; 80 is well past the p=0.0001 critical
+	// value, so a failure means the reduction is biased rather than unlucky.
+	expected := float64(samples) / float64(buckets)
+	for position, positionCounts := range counts {
+		var chiSquare float64
+		for _, count := range positionCounts {
+			diff := float64(count) - expected
+			chiSquare += diff * diff / expected
+		}
+		if chiSquare > 80 {
+			t.Fatalf("fingerprint position %d is not uniform: chi-square=%.1f counts=%v", position, chiSquare, positionCounts)
+		}
+	}
+}
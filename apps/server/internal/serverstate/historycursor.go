@@ -0,0 +1,87 @@
+package serverstate
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// historyCursor is the (created_at, rowid) key of a message, the order history
+// is listed in. Unlike a message id or an offset it stays meaningful when that
+// message or any other is deleted: paging resumes right after the position,
+// so deletions never cause skipped or repeated messages.
+type historyCursor struct {
+	CreatedAt string
+	RowID     int64
+}
+
+func (c historyCursor) valid() bool {
+	return c.CreatedAt != "" && c.RowID > 0
+}
+
+func (c historyCursor) String() string {
+	if !c.valid() {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt + "|" + strconv.FormatInt(c.RowID, 10)))
+}
+
+func parseHistoryCursor(raw string) (historyCursor, error) {
+	invalid := newAPIError(400, "invalid_cursor", "cursor is malformed")
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return historyCursor{}, invalid
+	}
+	createdAt, rowID, ok := strings.Cut(string(decoded), "|")
+	if !ok {
+		return historyCursor{}, invalid
+	}
+	cursor := historyCursor{CreatedAt: createdAt}
+	if cursor.RowID, err = strconv.ParseInt(rowID, 10, 64); err != nil || !cursor.valid() {
+		return historyCursor{}, invalid
+	}
+	return cursor, nil
+}
+
+// rowIDScanner scans a leading rowid column before handing the remaining
+// columns to scanMessageRow.
+type rowIDScanner struct {
+	rows  *sql.Rows
+	rowID *int64
+}
+
+func (r rowIDScanner) Scan(dest ...any) error {
+	return r.rows.Scan(append([]any{r.rowID}, dest...)...)
+}
+
+// ListMessagesBefore returns up to limit messages older than cursor, which
+// comes from the nextCursor of ListMessages or an earlier page.
+func (s *State) ListMessagesBefore(sessionToken, channelID, cursor string, limit int) (ListMessagesResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureHistoryAccessLocked(sessionToken, channelID); err != nil {
+		return ListMessagesResult{}, err
+	}
+	parsed, err := parseHistoryCursor(cursor)
+	if err != nil {
+		return ListMessagesResult{}, err
+	}
+	if limit <= 0 || limit > maxMessageHistoryLimit {
+		limit = defaultMessageHistoryLimit
+	}
+	return s.listHistoryLocked(channelID, parsed, limit)
+}
+
+func (s *State) listHistoryLocked(channelID string, cursor historyCursor, limit int) (ListMessagesResult, error) {
+	messages, oldest, err := s.queryHistoryLocked(channelID, cursor, limit, 0)
+	if err != nil {
+		return ListMessagesResult{}, err
+	}
+	result := ListMessagesResult{Messages: messages}
+	if len(messages) == limit {
+		result.NextCursor = oldest.String()
+	}
+	return result, nil
+}
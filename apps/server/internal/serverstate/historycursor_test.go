@@ -0,0 +1,63 @@
+package serverstate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestListMessagesBeforeIsStableAcrossDeletions(t *testing.T) {
+	s := newTestState(t, nil)
+	reader := connectTestMember(t, s, "reader")
+	leaver := connectTestMember(t, s, "leaver")
+
+	// Messages alternate between authors and share a created_at second, so the
+	// rowid half of the cursor decides the order.
+	var kept, purged []string
+	for i := 0; i < 5; i++ {
+		message, err := s.CreateMessage(reader.SessionToken, "general", "kept")
+		if err != nil {
+			t.Fatalf("create message failed: %v", err)
+		}
+		kept = append(kept, message.ID)
+		if message, err = s.CreateMessage(leaver.SessionToken, "general", "purged"); err != nil {
+			t.Fatalf("create message failed: %v", err)
+		}
+		purged = append(purged, message.ID)
+	}
+
+	page, err := s.ListMessages(reader.SessionToken, "general", 3)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	var seen []string
+	for _, message := range page.Messages {
+		seen = append(seen, message.ID)
+	}
+	// The first page ends on a purged message, so the cursor points at a row
+	// that no longer exists once the author leaves.
+	if page.Messages[0].ID != purged[3] {
+		t.Fatalf("unexpected first page: %v", seen)
+	}
+	if _, err := s.LeaveServer(leaver.SessionToken, true); err != nil {
+		t.Fatalf("leave failed: %v", err)
+	}
+
+	for cursor := page.NextCursor; cursor != ""; cursor = page.NextCursor {
+		if page, err = s.ListMessagesBefore(reader.SessionToken, "general", cursor, 3); err != nil {
+			t.Fatalf("list messages before failed: %v", err)
+		}
+		var ids []string
+		for _, message := range page.Messages {
+			ids = append(ids, message.ID)
+		}
+		seen = append(ids, seen...)
+	}
+
+	want := append(slices.Clone(kept[:4]), purged[3], kept[4], purged[4])
+	if !slices.Equal(seen, want) {
+		t.Fatalf("paging skipped or repeated messages:\ngot  %v\nwant %v", seen, want)
+	}
+
+	_, err = s.ListMessagesBefore(reader.SessionToken, "general", "not-a-cursor", 3)
+	requireAPIErrorCode(t, err, "invalid_cursor")
+}
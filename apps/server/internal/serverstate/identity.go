@@ -0,0 +1,116 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+)
+
+// loadExternalIdentity keeps the server private key in IDENTITY_KEY_FILE or
+// IDENTITY_PRIVATE_KEY and stores only the public key in server_identity, so
+// database backups do not carry the secret. A key still present in the database
+// is moved to the key file on first start.
+func loadExternalIdentity(db *sql.DB, cfg config.Config) (identityRecord, error) {
+	var stored identityRecord
+	err := db.QueryRow(`SELECT public_key, private_key FROM server_identity WHERE id = 1`).Scan(&stored.PublicKey, &stored.PrivateKey)
+	hasStored := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return identityRecord{}, fmt.Errorf("load server identity: %w", err)
+	}
+
+	privateKey, err := readExternalPrivateKey(cfg)
+	if err != nil {
+		return identityRecord{}, err
+	}
+	if privateKey == nil {
+		switch {
+		case hasStored && stored.PrivateKey != "":
+			if privateKey, err = decodePrivateKey(stored.PrivateKey); err != nil {
+				return identityRecord{}, fmt.Errorf("invalid persisted server private key: %w", err)
+			}
+		case hasStored:
+			return identityRecord{}, fmt.Errorf("server private key not found at IDENTITY_KEY_FILE %q", cfg.IdentityKeyFile)
+		default:
+			if _, privateKey, err = ed25519.GenerateKey(rand.Reader); err != nil {
+				return identityRecord{}, fmt.Errorf("generate server identity: %w", err)
+			}
+		}
+		if err := writeIdentityKeyFile(cfg.IdentityKeyFile, privateKey); err != nil {
+			return identityRecord{}, fmt.Errorf("write identity key file: %w", err)
+		}
+	}
+
+	identity := identityRecord{
+		PublicKey:  base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+		PrivateKey: base64.StdEncoding.EncodeToString(privateKey),
+	}
+	if !hasStored {
+		if _, err := db.Exec(
+			`INSERT INTO server_identity(id, public_key, private_key, created_at) VALUES (1, ?, '', ?)`,
+			identity.PublicKey,
+			time.Now().UTC().Format(time.RFC3339),
+		); err != nil {
+			return identityRecord{}, fmt.Errorf("persist server identity: %w", err)
+		}
+		return identity, nil
+	}
+
+	if stored.PublicKey != identity.PublicKey {
+		return identityRecord{}, errors.New("server identity keypair mismatch: external private key does not match the persisted public key")
+	}
+	if stored.PrivateKey != "" {
+		if _, err := db.Exec(`UPDATE server_identity SET private_key = '' WHERE id = 1`); err != nil {
+			return identityRecord{}, fmt.Errorf("remove private key from database: %w", err)
+		}
+	}
+	return identity, nil
+}
+
+// readExternalPrivateKey returns nil without an error when IDENTITY_KEY_FILE is
+// configured but does not exist yet.
+func readExternalPrivateKey(cfg config.Config) (ed25519.PrivateKey, error) {
+	raw := cfg.IdentityPrivateKey
+	source := "IDENTITY_PRIVATE_KEY"
+	if raw == "" {
+		contents, err := os.ReadFile(cfg.IdentityKeyFile)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read identity key file: %w", err)
+		}
+		raw = string(contents)
+		source = "IDENTITY_KEY_FILE"
+	}
+
+	privateKey, err := decodePrivateKey(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server private key in %s: %w", source, err)
+	}
+	return privateKey, nil
+}
+
+func writeIdentityKeyFile(path string, privateKey ed25519.PrivateKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(base64.StdEncoding.EncodeToString(privateKey)+"\n"), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
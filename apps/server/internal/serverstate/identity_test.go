@@ -0,0 +1,83 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestIdentityKeyFileMovesKeyOutOfDatabase(t *testing.T) {
+	dataDir := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "identity.key")
+
+	cfg := config.Config{
+		ServerName:          "Test Server",
+		DataDir:             dataDir,
+		SQLiteBusyTimeoutMS: 5000,
+		SQLiteCacheSize:     -2000,
+	}
+	first, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	publicKey := first.serverPublicKey
+	_ = first.db.Close()
+
+	cfg.IdentityKeyFile = keyFile
+	second, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to start with IDENTITY_KEY_FILE: %v", err)
+	}
+	defer second.db.Close()
+	if second.serverPublicKey != publicKey {
+		t.Fatal("migrating the key to IDENTITY_KEY_FILE must keep the server identity")
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected key file with mode 0600, got %v, %v", info, err)
+	}
+
+	var storedPrivateKey string
+	if err := second.db.QueryRow(`SELECT private_key FROM server_identity WHERE id = 1`).Scan(&storedPrivateKey); err != nil {
+		t.Fatalf("query identity failed: %v", err)
+	}
+	if storedPrivateKey != "" {
+		t.Fatal("private key must be removed from the database")
+	}
+}
+
+func TestExternalIdentityMustMatchStoredPublicKey(t *testing.T) {
+	cfg := config.Config{
+		ServerName:          "Test Server",
+		DataDir:             t.TempDir(),
+		SQLiteBusyTimeoutMS: 5000,
+		SQLiteCacheSize:     -2000,
+		IdentityKeyFile:     filepath.Join(t.TempDir(), "identity.key"),
+	}
+	state, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	_ = state.db.Close()
+
+	other := filepath.Join(t.TempDir(), "other.key")
+	cfg.IdentityKeyFile = other
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := writeIdentityKeyFile(other, otherKey); err != nil {
+		t.Fatalf("write key failed: %v", err)
+	}
+	if _, err := New(cfg); err == nil {
+		t.Fatal("expected a keypair mismatch error")
+	}
+
+	cfg.IdentityKeyFile = ""
+	if _, err := New(cfg); err == nil {
+		t.Fatal("expected startup to fail without the external key")
+	}
+}
@@ -0,0 +1,130 @@
+package serverstate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxImportBatchSize = 1000
+
+type ImportedMessage struct {
+	ID              string `json:"id"`
+	AuthorPublicKey string `json:"authorPublicKey"`
+	AuthorName      string `json:"authorName"`
+	ContentMarkdown string `json:"contentMarkdown"`
+	CreatedAt       string `json:"createdAt"`
+	UpdatedAt       string `json:"updatedAt"`
+}
+
+type ImportMessagesResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// ImportMessages inserts messages from another server keeping their IDs, authors
+// and timestamps. Messages whose ID already exists are skipped, so a migration can
+// be retried. Imports are not broadcast to channel streams.
+func (s *State) ImportMessages(channelID string, messages []ImportedMessage) (ImportMessagesResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return ImportMessagesResult{}, err
+	}
+
+	channelID = strings.TrimSpace(channelID)
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return ImportMessagesResult{}, err
+	}
+	if len(messages) > maxImportBatchSize {
+		return ImportMessagesResult{}, newAPIError(400, "import_too_large", fmt.Sprintf("at most %d messages can be imported per request", maxImportBatchSize))
+	}
+
+	limit := s.messageLengthLimitLocked(channelID)
+	normalized := make([]ImportedMessage, 0, len(messages))
+	for i, message := range messages {
+		message, err := normalizeImportedMessage(message, limit)
+		if err != nil {
+			return ImportMessagesResult{}, &APIError{
+				Status:  400,
+				Code:    "invalid_import",
+				Message: err.Error(),
+				Details: map[string]any{"index": i},
+			}
+		}
+		normalized = append(normalized, message)
+	}
+	// Insert oldest first so insertion order matches message chronology.
+	sort.SliceStable(normalized, func(i, j int) bool { return normalized[i].CreatedAt < normalized[j].CreatedAt })
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return ImportMessagesResult{}, fmt.Errorf("begin import tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var result ImportMessagesResult
+	for _, message := range normalized {
+		inserted, err := tx.Exec(`
+			INSERT OR IGNORE INTO messages(id, channel_id, author_public_key, author_name, content_markdown, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, message.ID, channelID, message.AuthorPublicKey, message.AuthorName, message.ContentMarkdown, message.CreatedAt, message.UpdatedAt)
+		if err != nil {
+			return ImportMessagesResult{}, fmt.Errorf("insert imported message: %w", err)
+		}
+		rows, err := inserted.RowsAffected()
+		if err != nil {
+			return ImportMessagesResult{}, fmt.Errorf("check imported message: %w", err)
+		}
+		if rows == 0 {
+			result.Skipped++
+			continue
+		}
+		result.Imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportMessagesResult{}, fmt.Errorf("commit import tx: %w", err)
+	}
+	return result, nil
+}
+
+func normalizeImportedMessage(message ImportedMessage, limit int) (ImportedMessage, error) {
+	message.ID = strings.TrimSpace(message.ID)
+	if message.ID == "" || len(message.ID) > 128 {
+		return ImportedMessage{}, errors.New("id is required and must be at most 128 characters")
+	}
+
+	message.AuthorPublicKey = strings.TrimSpace(message.AuthorPublicKey)
+	if message.AuthorPublicKey == "" || message.AuthorPublicKey == systemAuthorPublicKey {
+		return ImportedMessage{}, fmt.Errorf("message %q: authorPublicKey is required and must not be %q", message.ID, systemAuthorPublicKey)
+	}
+	message.AuthorName = normalizeDisplayName(message.AuthorName, message.AuthorPublicKey)
+
+	content, err := normalizeMessageContent(message.ContentMarkdown, limit)
+	if err != nil {
+		return ImportedMessage{}, fmt.Errorf("message %q: %w", message.ID, err)
+	}
+	message.ContentMarkdown = content
+
+	createdAt, err := time.Parse(time.RFC3339, strings.TrimSpace(message.CreatedAt))
+	if err != nil {
+		return ImportedMessage{}, fmt.Errorf("message %q: createdAt must be RFC3339", message.ID)
+	}
+	updatedAt := createdAt
+	if raw := strings.TrimSpace(message.UpdatedAt); raw != "" {
+		if updatedAt, err = time.Parse(time.RFC3339, raw); err != nil {
+			return ImportedMessage{}, fmt.Errorf("message %q: updatedAt must be RFC3339", message.ID)
+		}
+	}
+	if updatedAt.Before(createdAt) {
+		return ImportedMessage{}, fmt.Errorf("message %q: updatedAt must not be earlier than createdAt", message.ID)
+	}
+
+	message.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+	message.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+	return message, nil
+}
@@ -0,0 +1,63 @@
+package serverstate
+
+import "testing"
+
+func TestImportMessagesKeepsTimestampsAndSkipsDuplicates(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	_, cancel, err := s.SubscribeChannelEvents(member.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	batch := []ImportedMessage{
+		{ID: "old-2", AuthorPublicKey: "legacy-key", AuthorName: "legacy", ContentMarkdown: "second", CreatedAt: "2020-01-01T10:05:00Z"},
+		{ID: "old-1", AuthorPublicKey: "legacy-key", ContentMarkdown: "first", CreatedAt: "2020-01-01T12:00:00+02:00", UpdatedAt: "2020-01-02T00:00:00Z"},
+	}
+	result, err := s.ImportMessages("general", batch)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Imported != 2 || result.Skipped != 0 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+
+	result, err = s.ImportMessages("general", batch)
+	if err != nil {
+		t.Fatalf("repeat import failed: %v", err)
+	}
+	if result.Imported != 0 || result.Skipped != 2 {
+		t.Fatalf("expected duplicates to be skipped: %+v", result)
+	}
+
+	if stats := s.Stats(); len(stats.Streams.Channels) != 1 || stats.Streams.Channels[0].DroppedEvents != 0 {
+		t.Fatalf("unexpected stream stats: %+v", stats.Streams)
+	}
+	s.mu.Lock()
+	pending := 0
+	for _, stream := range s.streams["general"] {
+		pending += len(stream)
+	}
+	s.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("imports must not be broadcast, got %d pending events", pending)
+	}
+
+	listed, err := s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(listed.Messages) != 2 || listed.Messages[0].ID != "old-1" || listed.Messages[0].CreatedAt != "2020-01-01T10:00:00Z" {
+		t.Fatalf("expected imported history in original order, got %+v", listed.Messages)
+	}
+	if listed.Messages[1].Author.DisplayName != "legacy" {
+		t.Fatalf("expected original author name, got %+v", listed.Messages[1].Author)
+	}
+
+	_, err = s.ImportMessages("general", []ImportedMessage{{ID: "bad", AuthorPublicKey: "k", ContentMarkdown: "x", CreatedAt: "yesterday"}})
+	requireAPIErrorCode(t, err, "invalid_import")
+	_, err = s.ImportMessages("voice-main", batch)
+	requireAPIErrorCode(t, err, "invalid_channel_type")
+}
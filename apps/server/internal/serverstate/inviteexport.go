@@ -0,0 +1,206 @@
+package serverstate
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const inviteSummaryColumns = "id, allowed_client_public_key, label, created_at, used_at, used_by_public_key, metadata, admin_only"
+
+type ImportInvitesResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+func scanInviteSummary(rows *sql.Rows) (InviteSummary, error) {
+	var (
+		summary  = InviteSummary{Status: "active"}
+		usedAt   sql.NullString
+		usedBy   sql.NullString
+		metadata sql.NullString
+	)
+	if err := rows.Scan(&summary.InviteID, &summary.AllowedClientPublicKey, &summary.Label, &summary.CreatedAt, &usedAt, &usedBy, &metadata, &summary.AdminOnly); err != nil {
+		return InviteSummary{}, err
+	}
+	if usedAt.Valid {
+		summary.UsedAt = &usedAt.String
+		summary.Status = "used"
+	}
+	if usedBy.Valid {
+		summary.UsedByPublicKey = &usedBy.String
+	}
+	if metadata.Valid {
+		summary.Metadata = json.RawMessage(metadata.String)
+	}
+	return summary, nil
+}
+
+// ExportInvites passes every invite to emit, oldest first, including used
+// ones and their redeemer, so a migrated server keeps invite status. Invites
+// are bound to a client key and are not secrets on their own. As with
+// ExportMembers, invites are read in batches that are closed before emit runs.
+func (s *State) ExportInvites(emit func(InviteSummary) error) error {
+	return s.exportInvites(exportBatchSize, emit)
+}
+
+func (s *State) exportInvites(batchSize int, emit func(InviteSummary) error) error {
+	var after *InviteSummary
+	for {
+		batch, err := s.inviteExportBatch(after, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, invite := range batch {
+			if err := emit(invite); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		after = &batch[len(batch)-1]
+	}
+}
+
+// inviteExportBatch reads up to limit invites ordered after the given invite.
+func (s *State) inviteExportBatch(after *InviteSummary, limit int) ([]InviteSummary, error) {
+	where, args := "", []any{}
+	if after != nil {
+		where = ` WHERE created_at > ? OR (created_at = ? AND id > ?)`
+		args = append(args, after.CreatedAt, after.CreatedAt, after.InviteID)
+	}
+	rows, err := s.db.Query(`SELECT `+inviteSummaryColumns+` FROM invites`+where+` ORDER BY created_at ASC, id ASC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("query invite export: %w", err)
+	}
+	defer rows.Close()
+
+	invites := make([]InviteSummary, 0, limit)
+	for rows.Next() {
+		invite, err := scanInviteSummary(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan invite export row: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate invite export rows: %w", err)
+	}
+	return invites, nil
+}
+
+// ImportInvites inserts invites exported from another server keeping their IDs,
+// bindings and timestamps. Invites whose ID already exists are skipped, so an
+// import can be retried. Status is derived from usedAt and not read back.
+func (s *State) ImportInvites(invites []InviteSummary) (ImportInvitesResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return ImportInvitesResult{}, err
+	}
+	if len(invites) > maxImportBatchSize {
+		return ImportInvitesResult{}, newAPIError(400, "import_too_large", fmt.Sprintf("at most %d invites can be imported per request", maxImportBatchSize))
+	}
+
+	normalized := make([]InviteSummary, 0, len(invites))
+	for i, invite := range invites {
+		invite, err := normalizeImportedInvite(invite)
+		if err != nil {
+			return ImportInvitesResult{}, &APIError{
+				Status:  400,
+				Code:    "invalid_import",
+				Message: err.Error(),
+				Details: map[string]any{"index": i},
+			}
+		}
+		normalized = append(normalized, invite)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return ImportInvitesResult{}, fmt.Errorf("begin invite import tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var result ImportInvitesResult
+	for _, invite := range normalized {
+		var metadata sql.NullString
+		if invite.Metadata != nil {
+			metadata = sql.NullString{String: string(invite.Metadata), Valid: true}
+		}
+		inserted, err := tx.Exec(`
+			INSERT OR IGNORE INTO invites(id, allowed_client_public_key, label, created_at, used_at, used_by_public_key, metadata, admin_only)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, invite.InviteID, invite.AllowedClientPublicKey, invite.Label, invite.CreatedAt, invite.UsedAt, invite.UsedByPublicKey, metadata, invite.AdminOnly)
+		if err != nil {
+			return ImportInvitesResult{}, fmt.Errorf("insert imported invite: %w", err)
+		}
+		rows, err := inserted.RowsAffected()
+		if err != nil {
+			return ImportInvitesResult{}, fmt.Errorf("check imported invite: %w", err)
+		}
+		if rows == 0 {
+			result.Skipped++
+			continue
+		}
+		result.Imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ImportInvitesResult{}, fmt.Errorf("commit invite import tx: %w", err)
+	}
+	return result, nil
+}
+
+func normalizeImportedInvite(invite InviteSummary) (InviteSummary, error) {
+	invite.InviteID = strings.TrimSpace(invite.InviteID)
+	if invite.InviteID == "" || len(invite.InviteID) > 128 {
+		return InviteSummary{}, errors.New("inviteId is required and must be at most 128 characters")
+	}
+
+	invite.AllowedClientPublicKey = strings.TrimSpace(invite.AllowedClientPublicKey)
+	if _, err := decodePublicKey(invite.AllowedClientPublicKey); err != nil && invite.AllowedClientPublicKey != "" {
+		return InviteSummary{}, fmt.Errorf("invite %q: allowedClientPublicKey must be empty or base64(ed25519 public key)", invite.InviteID)
+	}
+
+	label, err := normalizeInviteLabel(invite.Label, false)
+	if err != nil {
+		return InviteSummary{}, fmt.Errorf("invite %q: %w", invite.InviteID, err)
+	}
+	invite.Label = label
+
+	if invite.Metadata, err = normalizeInviteMetadata(invite.Metadata); err != nil {
+		return InviteSummary{}, fmt.Errorf("invite %q: %w", invite.InviteID, err)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, strings.TrimSpace(invite.CreatedAt))
+	if err != nil {
+		return InviteSummary{}, fmt.Errorf("invite %q: createdAt must be RFC3339", invite.InviteID)
+	}
+	invite.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+
+	if invite.UsedAt != nil {
+		usedAt, err := time.Parse(time.RFC3339, strings.TrimSpace(*invite.UsedAt))
+		if err != nil {
+			return InviteSummary{}, fmt.Errorf("invite %q: usedAt must be RFC3339", invite.InviteID)
+		}
+		formatted := usedAt.UTC().Format(time.RFC3339)
+		invite.UsedAt = &formatted
+	}
+	if invite.UsedByPublicKey != nil {
+		usedBy := strings.TrimSpace(*invite.UsedByPublicKey)
+		if _, err := decodePublicKey(usedBy); err != nil {
+			return InviteSummary{}, fmt.Errorf("invite %q: usedByPublicKey must be base64(ed25519 public key)", invite.InviteID)
+		}
+		if invite.UsedAt == nil {
+			return InviteSummary{}, fmt.Errorf("invite %q: usedByPublicKey requires usedAt", invite.InviteID)
+		}
+		invite.UsedByPublicKey = &usedBy
+	}
+	return invite, nil
+}
@@ -0,0 +1,68 @@
+package serverstate
+
+import (
+	"net/url"
+	"strings"
+)
+
+type InviteLink struct {
+	BaseURL           string `json:"baseUrl"`
+	InviteID          string `json:"inviteId"`
+	ServerFingerprint string `json:"serverFingerprint"`
+}
+
+// ParseInviteLink decodes an invite link as produced by CreateInvite
+// (fw://connect?baseUrl=...&inviteId=...&serverFp=...). Web links carrying the
+// same query parameters are accepted too. The fingerprint must match this server.
+func (s *State) ParseInviteLink(raw string) (InviteLink, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return InviteLink{}, newAPIError(400, "invalid_link", "link is required")
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return InviteLink{}, newAPIError(400, "invalid_link", "link is not a valid URL")
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "fw":
+		if parsed.Host != "connect" {
+			return InviteLink{}, newAPIError(400, "invalid_link", "fw links must use fw://connect")
+		}
+	case "http", "https":
+	default:
+		return InviteLink{}, newAPIError(400, "invalid_link", "link must use the fw, http or https scheme")
+	}
+
+	query := parsed.Query()
+	link := InviteLink{
+		BaseURL:           strings.TrimRight(strings.TrimSpace(query.Get("baseUrl")), "/"),
+		InviteID:          strings.TrimSpace(query.Get("inviteId")),
+		ServerFingerprint: strings.TrimSpace(query.Get("serverFp")),
+	}
+	if link.InviteID == "" {
+		return InviteLink{}, newAPIError(400, "invalid_link", "link is missing inviteId")
+	}
+	if link.ServerFingerprint == "" {
+		return InviteLink{}, newAPIError(400, "invalid_link", "link is missing serverFp")
+	}
+
+	base, err := url.Parse(link.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return InviteLink{}, newAPIError(400, "invalid_link", "baseUrl must be an absolute http(s) URL")
+	}
+
+	s.mu.Lock()
+	fingerprint := s.serverFingerprint
+	s.mu.Unlock()
+	if link.ServerFingerprint != fingerprint {
+		return InviteLink{}, &APIError{
+			Status:  400,
+			Code:    "fingerprint_mismatch",
+			Message: "link fingerprint does not match this server",
+			Details: map[string]any{"expected": fingerprint, "actual": link.ServerFingerprint},
+		}
+	}
+
+	return link, nil
+}
@@ -0,0 +1,49 @@
+package serverstate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxInviteMetadataBytes = 4096
+	maxInviteLabelLength   = 100
+)
+
+// normalizeInviteLabel trims the label and enforces its length; with
+// REQUIRE_INVITE_LABEL a blank label is rejected.
+func normalizeInviteLabel(label string, required bool) (string, error) {
+	label = strings.TrimSpace(label)
+	if label == "" && required {
+		return "", newAPIError(400, "label_required", "invites must have a label")
+	}
+	if utf8.RuneCountInString(label) > maxInviteLabelLength {
+		return "", newAPIError(400, "invalid_label", fmt.Sprintf("label must be at most %d characters", maxInviteLabelLength))
+	}
+	return label, nil
+}
+
+// normalizeInviteMetadata validates the opaque metadata object attached to an
+// invite and returns its compact form, or nil when none was given. The server
+// never interprets the contents.
+func normalizeInviteMetadata(metadata json.RawMessage) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(metadata)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+	if trimmed[0] != '{' || !json.Valid(trimmed) {
+		return nil, newAPIError(400, "invalid_metadata", "metadata must be a JSON object")
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, trimmed); err != nil {
+		return nil, newAPIError(400, "invalid_metadata", "metadata must be a JSON object")
+	}
+	if compact.Len() > maxInviteMetadataBytes {
+		return nil, newAPIError(400, "invalid_metadata", fmt.Sprintf("metadata must be at most %d bytes", maxInviteMetadataBytes))
+	}
+	return compact.Bytes(), nil
+}
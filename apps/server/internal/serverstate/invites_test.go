@@ -0,0 +1,634 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestFinishConnectRecordsInviteRedemption(t *testing.T) {
+	s := newTestState(t, nil)
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+	member := connectTestMember(t, s, "member")
+
+	issuedAt := time.Now().UTC().Format(time.RFC3339)
+	hash := AdminListInvitesPayloadHash(admin.PublicKey, issuedAt)
+	result, err := s.ListInvitesByAdminClient(ListInvitesByAdminClientRequest{
+		AdminPublicKey: admin.PublicKey,
+		IssuedAt:       issuedAt,
+		Signature:      base64.StdEncoding.EncodeToString(ed25519.Sign(admin.PrivateKey, hash[:])),
+	})
+	if err != nil {
+		t.Fatalf("list invites failed: %v", err)
+	}
+
+	found := false
+	for _, invite := range result.Invites {
+		if invite.AllowedClientPublicKey != member.PublicKey {
+			continue
+		}
+		found = true
+		if invite.UsedByPublicKey == nil || *invite.UsedByPublicKey != member.PublicKey {
+			t.Fatalf("expected invite to record redeeming key, got %+v", invite)
+		}
+	}
+	if !found {
+		t.Fatalf("member invite missing from listing")
+	}
+
+	var redemptions int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM invite_redemptions WHERE client_public_key = ?`, member.PublicKey).Scan(&redemptions); err != nil {
+		t.Fatalf("count redemptions failed: %v", err)
+	}
+	if redemptions != 1 {
+		t.Fatalf("expected 1 redemption row, got %d", redemptions)
+	}
+}
+
+func TestFinishConnectReportsMissingFields(t *testing.T) {
+	s := newTestState(t, nil)
+	complete := FinishRequest{InviteID: "invite", ClientPublicKey: "key", Challenge: "challenge", Signature: "signature"}
+
+	cases := []struct {
+		code  string
+		clear func(*FinishRequest)
+	}{
+		{"missing_invite_id", func(req *FinishRequest) { req.InviteID = " " }},
+		{"missing_client_public_key", func(req *FinishRequest) { req.ClientPublicKey = "" }},
+		{"missing_challenge", func(req *FinishRequest) { req.Challenge = "" }},
+		{"missing_signature", func(req *FinishRequest) { req.Signature = "" }},
+	}
+	for _, tc := range cases {
+		req := complete
+		tc.clear(&req)
+		_, err := s.FinishConnect(req)
+		if apiErr := requireAPIErrorCode(t, err, tc.code); apiErr.Status != 400 {
+			t.Fatalf("%s: unexpected status %d", tc.code, apiErr.Status)
+		}
+	}
+}
+
+func TestAutoDeleteUsedInvitesRemovesRedeemedInvite(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.AutoDeleteUsedInvites = true })
+	member := connectTestMember(t, s, "member")
+
+	var invites int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM invites WHERE allowed_client_public_key = ?`, member.PublicKey).Scan(&invites); err != nil {
+		t.Fatalf("count invites failed: %v", err)
+	}
+	if invites != 0 {
+		t.Fatalf("expected redeemed invite to be deleted, got %d rows", invites)
+	}
+
+	var redemptions int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM invite_redemptions WHERE client_public_key = ?`, member.PublicKey).Scan(&redemptions); err != nil {
+		t.Fatalf("count redemptions failed: %v", err)
+	}
+	if redemptions != 1 {
+		t.Fatalf("expected redemption to be recorded, got %d rows", redemptions)
+	}
+}
+
+func TestParseInviteLinkRoundTrip(t *testing.T) {
+	s := newTestState(t, nil)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	invite, err := s.CreateInvite(base64.StdEncoding.EncodeToString(pub), "", nil, false)
+	if err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+
+	link, err := s.ParseInviteLink(invite.InviteLink)
+	if err != nil {
+		t.Fatalf("parse link failed: %v", err)
+	}
+	if link.InviteID != invite.InviteID || link.BaseURL != invite.ServerBaseURL || link.ServerFingerprint != invite.ServerFingerprint {
+		t.Fatalf("unexpected components: %+v", link)
+	}
+
+	webLink := "https://chat.example.org/join?baseUrl=https%3A%2F%2Fchat.example.org&inviteId=abc&serverFp=" + url.QueryEscape(invite.ServerFingerprint)
+	if _, err := s.ParseInviteLink(webLink); err != nil {
+		t.Fatalf("web link should parse: %v", err)
+	}
+
+	_, err = s.ParseInviteLink("fw://connect?baseUrl=http%3A%2F%2Fx&inviteId=abc&serverFp=nope")
+	requireAPIErrorCode(t, err, "fingerprint_mismatch")
+	_, err = s.ParseInviteLink("fw://connect?baseUrl=ftp%3A%2F%2Fx&inviteId=abc&serverFp=" + url.QueryEscape(invite.ServerFingerprint))
+	requireAPIErrorCode(t, err, "invalid_link")
+	_, err = s.ParseInviteLink("mailto:someone@example.org")
+	requireAPIErrorCode(t, err, "invalid_link")
+}
+
+func TestInviteMetadataIsStoredAndListed(t *testing.T) {
+	s := newTestState(t, nil)
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	clientKey := base64.StdEncoding.EncodeToString(pub)
+
+	if _, err := s.CreateInvite(clientKey, "spring", json.RawMessage(`{ "campaign": "spring", "source": "newsletter" }`), false); err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+	_, err = s.CreateInvite(clientKey, "bad", json.RawMessage(`["not", "an", "object"]`), false)
+	requireAPIErrorCode(t, err, "invalid_metadata")
+	_, err = s.CreateInvite(clientKey, "big", json.RawMessage(`{"blob":"`+strings.Repeat("x", maxInviteMetadataBytes)+`"}`), false)
+	requireAPIErrorCode(t, err, "invalid_metadata")
+
+	issuedAt := time.Now().UTC().Format(time.RFC3339)
+	hash := AdminListInvitesPayloadHash(admin.PublicKey, issuedAt)
+	result, err := s.ListInvitesByAdminClient(ListInvitesByAdminClientRequest{
+		AdminPublicKey: admin.PublicKey,
+		IssuedAt:       issuedAt,
+		Signature:      base64.StdEncoding.EncodeToString(ed25519.Sign(admin.PrivateKey, hash[:])),
+	})
+	if err != nil {
+		t.Fatalf("list invites failed: %v", err)
+	}
+	for _, invite := range result.Invites {
+		if invite.AllowedClientPublicKey != clientKey {
+			if invite.Metadata != nil {
+				t.Fatalf("invites without metadata must omit it, got %s", invite.Metadata)
+			}
+			continue
+		}
+		if string(invite.Metadata) != `{"campaign":"spring","source":"newsletter"}` {
+			t.Fatalf("unexpected metadata: %s", invite.Metadata)
+		}
+		return
+	}
+	t.Fatal("invite with metadata missing from listing")
+}
+
+func TestListClientInvitesRequiresOwnSignature(t *testing.T) {
+	s := newTestState(t, nil)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	invite, err := s.CreateInvite(publicKey, "laptop", json.RawMessage(`{"note":"secret"}`), false)
+	if err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+	member := connectTestMember(t, s, "member")
+
+	issuedAt := time.Now().UTC().Format(time.RFC3339)
+	hash := ClientInvitesPayloadHash(publicKey, issuedAt, s.serverFingerprint)
+	result, err := s.ListClientInvites(ListClientInvitesRequest{
+		ClientPublicKey: publicKey,
+		IssuedAt:        issuedAt,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(priv, hash[:])),
+	})
+	if err != nil {
+		t.Fatalf("list client invites failed: %v", err)
+	}
+	if len(result.Invites) != 1 || result.Invites[0].InviteID != invite.InviteID || result.Invites[0].Label != "laptop" || result.Invites[0].Status != "active" {
+		t.Fatalf("unexpected invites: %+v", result.Invites)
+	}
+	if result.Invites[0].Metadata != nil {
+		t.Fatalf("metadata must not be exposed to clients: %+v", result.Invites[0])
+	}
+
+	_, err = s.ListClientInvites(ListClientInvitesRequest{
+		ClientPublicKey: publicKey,
+		IssuedAt:        issuedAt,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(member.PrivateKey, hash[:])),
+	})
+	requireAPIErrorCode(t, err, "invalid_signature")
+
+	redeemedHash := ClientInvitesPayloadHash(member.PublicKey, issuedAt, s.serverFingerprint)
+	redeemed, err := s.ListClientInvites(ListClientInvitesRequest{
+		ClientPublicKey: member.PublicKey,
+		IssuedAt:        issuedAt,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(member.PrivateKey, redeemedHash[:])),
+	})
+	if err != nil {
+		t.Fatalf("list client invites failed: %v", err)
+	}
+	if len(redeemed.Invites) != 0 {
+		t.Fatalf("redeemed invites must not be listed, got %+v", redeemed.Invites)
+	}
+}
+
+func TestFinishConnectRetryReissuesSession(t *testing.T) {
+	s := newTestState(t, nil)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	invite, err := s.CreateInvite(publicKey, "test", nil, false)
+	if err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+	begin, err := s.BeginConnect(invite.InviteID)
+	if err != nil {
+		t.Fatalf("begin connect failed: %v", err)
+	}
+	challenge, _ := base64.StdEncoding.DecodeString(begin.Challenge)
+	hash := SignaturePayloadHash(challenge, invite.InviteID, begin.ServerFingerprint)
+	req := FinishRequest{
+		InviteID:        invite.InviteID,
+		ClientPublicKey: publicKey,
+		Challenge:       begin.Challenge,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(priv, hash[:])),
+	}
+
+	first, err := s.FinishConnect(req)
+	if err != nil {
+		t.Fatalf("finish connect failed: %v", err)
+	}
+	retry, err := s.FinishConnect(req)
+	if err != nil {
+		t.Fatalf("retried finish must succeed: %v", err)
+	}
+	if retry.SessionToken == "" || retry.SessionToken == first.SessionToken {
+		t.Fatalf("expected a fresh session token on retry, got %q", retry.SessionToken)
+	}
+
+	other, otherPriv, _ := ed25519.GenerateKey(nil)
+	forged := req
+	forged.ClientPublicKey = base64.StdEncoding.EncodeToString(other)
+	forged.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(otherPriv, hash[:]))
+	_, err = s.FinishConnect(forged)
+	requireAPIErrorCode(t, err, "invite_used")
+
+	s.mu.Lock()
+	pending := s.challenges[invite.InviteID]
+	pending.ExpiresAt = time.Now().Add(-time.Second)
+	s.challenges[invite.InviteID] = pending
+	s.mu.Unlock()
+	_, err = s.FinishConnect(req)
+	requireAPIErrorCode(t, err, "invite_used")
+}
+
+func TestFinishConnectRetryRefusedAfterMemberRemoved(t *testing.T) {
+	s := newTestState(t, nil)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	invite, err := s.CreateInvite(publicKey, "test", nil, false)
+	if err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+	begin, err := s.BeginConnect(invite.InviteID)
+	if err != nil {
+		t.Fatalf("begin connect failed: %v", err)
+	}
+	challenge, _ := base64.StdEncoding.DecodeString(begin.Challenge)
+	hash := SignaturePayloadHash(challenge, invite.InviteID, begin.ServerFingerprint)
+	req := FinishRequest{
+		InviteID:        invite.InviteID,
+		ClientPublicKey: publicKey,
+		Challenge:       begin.Challenge,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(priv, hash[:])),
+	}
+	if _, err := s.FinishConnect(req); err != nil {
+		t.Fatalf("finish connect failed: %v", err)
+	}
+
+	if _, err := s.KickMember(publicKey, nil); err != nil {
+		t.Fatalf("kick failed: %v", err)
+	}
+	_, err = s.FinishConnect(req)
+	requireAPIErrorCode(t, err, "invite_used")
+}
+
+func TestInviteLabelRequirements(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.RequireInviteLabel = true })
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	clientKey := base64.StdEncoding.EncodeToString(pub)
+
+	createSigned := func(label string) error {
+		issuedAt := time.Now().UTC().Format(time.RFC3339)
+		hash := AdminInvitePayloadHash(admin.PublicKey, clientKey, issuedAt)
+		_, err := s.CreateInviteByAdminClient(CreateInviteByAdminClientRequest{
+			AdminPublicKey:  admin.PublicKey,
+			ClientPublicKey: clientKey,
+			Label:           label,
+			IssuedAt:        issuedAt,
+			Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(admin.PrivateKey, hash[:])),
+		})
+		return err
+	}
+	createWithToken := func(label string) error {
+		_, err := s.CreateInvite(clientKey, label, nil, false)
+		return err
+	}
+
+	overlong := strings.Repeat("x", maxInviteLabelLength+1)
+	for name, create := range map[string]func(string) error{"token": createWithToken, "client-signed": createSigned} {
+		requireAPIErrorCode(t, create("   "), "label_required")
+		requireAPIErrorCode(t, create(overlong), "invalid_label")
+		if err := create(" laptop "); err != nil {
+			t.Fatalf("%s: labelled invite rejected: %v", name, err)
+		}
+	}
+
+	optional := newTestState(t, nil)
+	if _, err := optional.CreateInvite(clientKey, "", nil, false); err != nil {
+		t.Fatalf("labels must stay optional by default: %v", err)
+	}
+	_, err = optional.CreateInvite(clientKey, overlong, nil, false)
+	requireAPIErrorCode(t, err, "invalid_label")
+}
+
+func TestImportInvitesRoundTripsExport(t *testing.T) {
+	source := newTestState(t, nil)
+	member := connectTestMember(t, source, "member")
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := source.CreateInvite(base64.StdEncoding.EncodeToString(pub), "pending", json.RawMessage(`{"team":"ops"}`), false); err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+
+	var exported []InviteSummary
+	if err := source.ExportInvites(func(invite InviteSummary) error {
+		exported = append(exported, invite)
+		// Other requests must not wait on a consumer that is still reading.
+		_, err := source.ListMembers(ListMembersQuery{Limit: 1})
+		return err
+	}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if len(exported) != 2 {
+		t.Fatalf("expected 2 invites, got %d", len(exported))
+	}
+
+	target := newTestState(t, nil)
+	result, err := target.ImportInvites(exported)
+	if err != nil || result.Imported != 2 || result.Skipped != 0 {
+		t.Fatalf("unexpected import result: %+v, %v", result, err)
+	}
+	if result, err := target.ImportInvites(exported); err != nil || result.Imported != 0 || result.Skipped != 2 {
+		t.Fatalf("re-import should skip existing ids, got %+v, %v", result, err)
+	}
+
+	statuses := map[string]InviteSummary{}
+	if err := target.ExportInvites(func(invite InviteSummary) error {
+		statuses[invite.Label] = invite
+		return nil
+	}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	used := statuses["test"]
+	if used.Status != "used" || used.UsedByPublicKey == nil || *used.UsedByPublicKey != member.PublicKey {
+		t.Fatalf("used invite lost its status: %+v", used)
+	}
+	if pending := statuses["pending"]; pending.Status != "active" || string(pending.Metadata) != `{"team":"ops"}` {
+		t.Fatalf("pending invite not preserved: %+v", pending)
+	}
+
+	bad := exported[0]
+	bad.InviteID = "other"
+	bad.AllowedClientPublicKey = "not-a-key"
+	_, err = target.ImportInvites([]InviteSummary{bad})
+	if apiErr := requireAPIErrorCode(t, err, "invalid_import"); apiErr.Details["index"] != 0 {
+		t.Fatalf("expected failing index, got %+v", apiErr.Details)
+	}
+}
+
+func TestExportInvitesPagesThroughEveryInvite(t *testing.T) {
+	s := newTestState(t, nil)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	for _, label := range []string{"one", "two", "three"} {
+		if _, err := s.CreateInvite(base64.StdEncoding.EncodeToString(pub), label, nil, false); err != nil {
+			t.Fatalf("create invite failed: %v", err)
+		}
+	}
+
+	for _, batchSize := range []int{1, 2, 3} {
+		seen := map[string]bool{}
+		if err := s.exportInvites(batchSize, func(invite InviteSummary) error {
+			if seen[invite.InviteID] {
+				t.Fatalf("batch size %d exported %s twice", batchSize, invite.Label)
+			}
+			seen[invite.InviteID] = true
+			return nil
+		}); err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+		if len(seen) != 3 {
+			t.Fatalf("batch size %d exported %d of 3 invites", batchSize, len(seen))
+		}
+	}
+}
+
+func TestAdminOnlyInvitesAreHiddenFromClientListing(t *testing.T) {
+	s := newTestState(t, nil)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	if _, err := s.CreateInvite(publicKey, "visible", nil, false); err != nil {
+		t.Fatalf("create invite failed: %v", err)
+	}
+	if _, err := s.CreateInvite(publicKey, "hidden", nil, true); err != nil {
+		t.Fatalf("create admin-only invite failed: %v", err)
+	}
+
+	listOwn := func(publicKey string, key ed25519.PrivateKey) []InviteSummary {
+		t.Helper()
+		issuedAt := time.Now().UTC().Format(time.RFC3339)
+		hash := ClientInvitesPayloadHash(publicKey, issuedAt, s.serverFingerprint)
+		result, err := s.ListClientInvites(ListClientInvitesRequest{
+			ClientPublicKey: publicKey,
+			IssuedAt:        issuedAt,
+			Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(key, hash[:])),
+		})
+		if err != nil {
+			t.Fatalf("list client invites failed: %v", err)
+		}
+		return result.Invites
+	}
+
+	if invites := listOwn(publicKey, priv); len(invites) != 1 || invites[0].Label != "visible" {
+		t.Fatalf("admin-only invite must be hidden from regular clients, got %+v", invites)
+	}
+
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+	if _, err := s.CreateInvite(admin.PublicKey, "second device", nil, true); err != nil {
+		t.Fatalf("create admin-only invite failed: %v", err)
+	}
+	if invites := listOwn(admin.PublicKey, admin.PrivateKey); len(invites) != 1 || invites[0].Label != "second device" {
+		t.Fatalf("admins must see their admin-only invites, got %+v", invites)
+	}
+
+	var adminOnly int
+	if err := s.ExportInvites(func(invite InviteSummary) error {
+		if invite.AdminOnly {
+			adminOnly++
+		}
+		return nil
+	}); err != nil || adminOnly != 2 {
+		t.Fatalf("admin export must flag admin-only invites, got %d, %v", adminOnly, err)
+	}
+}
+
+func TestAdminOnlyInvitesAreHiddenFromPreview(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+
+	bound, err := s.CreateInvite(member.PublicKey, "hidden", nil, true)
+	if err != nil {
+		t.Fatalf("create admin-only invite failed: %v", err)
+	}
+	_, err = s.BeginConnect(bound.InviteID)
+	requireAPIErrorCode(t, err, "invite_not_found")
+
+	unbound, err := s.CreateUnboundInvite("open", nil, true)
+	if err != nil {
+		t.Fatalf("create unbound admin-only invite failed: %v", err)
+	}
+	finish := func(member testMember) error {
+		t.Helper()
+		begin, err := s.BeginConnect(unbound.InviteID)
+		if err != nil {
+			t.Fatalf("begin connect failed: %v", err)
+		}
+		challenge, _ := base64.StdEncoding.DecodeString(begin.Challenge)
+		hash := SignaturePayloadHash(challenge, unbound.InviteID, begin.ServerFingerprint)
+		_, err = s.FinishConnect(FinishRequest{
+			InviteID:        unbound.InviteID,
+			ClientPublicKey: member.PublicKey,
+			Challenge:       begin.Challenge,
+			Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(member.PrivateKey, hash[:])),
+			ClientInfo:      ClientInfo{DisplayName: "device"},
+		})
+		return err
+	}
+	requireAPIErrorCode(t, finish(member), "invite_not_found")
+	if err := finish(admin); err != nil {
+		t.Fatalf("admins must be able to redeem admin-only invites: %v", err)
+	}
+}
+
+func TestSignedAdminInviteCoversAdminOnlyAndMetadata(t *testing.T) {
+	s := newTestState(t, nil)
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	clientKey := base64.StdEncoding.EncodeToString(pub)
+	issuedAt := time.Now().UTC().Format(time.RFC3339)
+	sign := func(hash [32]byte) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(admin.PrivateKey, hash[:]))
+	}
+	request := func(adminOnly bool, metadata json.RawMessage, signature string) CreateInviteByAdminClientRequest {
+		return CreateInviteByAdminClientRequest{
+			AdminPublicKey:  admin.PublicKey,
+			ClientPublicKey: clientKey,
+			Metadata:        metadata,
+			AdminOnly:       adminOnly,
+			IssuedAt:        issuedAt,
+			Signature:       signature,
+		}
+	}
+
+	v1 := sign(AdminInvitePayloadHash(admin.PublicKey, clientKey, issuedAt))
+	if _, err := s.CreateInviteByAdminClient(request(false, nil, v1)); err != nil {
+		t.Fatalf("the original payload must still work without adminOnly or metadata: %v", err)
+	}
+	_, err = s.CreateInviteByAdminClient(request(true, nil, v1))
+	requireAPIErrorCode(t, err, "invalid_signature")
+	_, err = s.CreateInviteByAdminClient(request(false, json.RawMessage(`{"team":"ops"}`), v1))
+	requireAPIErrorCode(t, err, "invalid_signature")
+
+	metadata := json.RawMessage(`{"team":"ops"}`)
+	v2 := sign(AdminInviteV2PayloadHash(admin.PublicKey, clientKey, issuedAt, true, metadata, s.serverFingerprint))
+	if _, err := s.CreateInviteByAdminClient(request(true, metadata, v2)); err != nil {
+		t.Fatalf("v2 signed invite failed: %v", err)
+	}
+	_, err = s.CreateInviteByAdminClient(request(false, metadata, v2))
+	requireAPIErrorCode(t, err, "invalid_signature")
+	_, err = s.CreateInviteByAdminClient(request(true, json.RawMessage(`{"team":"dev"}`), v2))
+	requireAPIErrorCode(t, err, "invalid_signature")
+}
+
+func TestUnboundInviteAcceptsAnyClientKey(t *testing.T) {
+	s := newTestState(t, nil)
+	finishWith := func(inviteID string, pub ed25519.PublicKey, priv ed25519.PrivateKey) (BeginResult, error) {
+		begin, err := s.BeginConnect(inviteID)
+		if err != nil {
+			t.Fatalf("begin connect failed: %v", err)
+		}
+		challenge, _ := base64.StdEncoding.DecodeString(begin.Challenge)
+		hash := SignaturePayloadHash(challenge, inviteID, begin.ServerFingerprint)
+		_, err = s.FinishConnect(FinishRequest{
+			InviteID:        inviteID,
+			ClientPublicKey: base64.StdEncoding.EncodeToString(pub),
+			Challenge:       begin.Challenge,
+			Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(priv, hash[:])),
+		})
+		return begin, err
+	}
+	boundPub, _, _ := ed25519.GenerateKey(nil)
+	strangerPub, strangerPriv, _ := ed25519.GenerateKey(nil)
+
+	bound, err := s.CreateInvite(base64.StdEncoding.EncodeToString(boundPub), "bound", nil, false)
+	if err != nil {
+		t.Fatalf("create bound invite failed: %v", err)
+	}
+	begin, err := finishWith(bound.InviteID, strangerPub, strangerPriv)
+	requireAPIErrorCode(t, err, "client_not_allowed")
+	if !begin.InviteBound {
+		t.Fatal("expected a bound invite to be reported as bound")
+	}
+
+	_, err = s.CreateInvite("", "open", nil, false)
+	requireAPIErrorCode(t, err, "invalid_client_public_key")
+	unbound, err := s.CreateUnboundInvite("open", nil, false)
+	if err != nil {
+		t.Fatalf("create unbound invite failed: %v", err)
+	}
+	begin, err = finishWith(unbound.InviteID, strangerPub, strangerPriv)
+	if err != nil {
+		t.Fatalf("finish with any key failed: %v", err)
+	}
+	if begin.InviteBound {
+		t.Fatal("expected an unbound invite to be reported as unbound")
+	}
+
+	var usedBy string
+	if err := s.db.QueryRow(`SELECT used_by_public_key FROM invites WHERE id = ?`, unbound.InviteID).Scan(&usedBy); err != nil {
+		t.Fatalf("query invite failed: %v", err)
+	}
+	if usedBy != base64.StdEncoding.EncodeToString(strangerPub) {
+		t.Fatalf("expected the redemption to bind the finishing key, got %q", usedBy)
+	}
+	_, err = s.BeginConnect(unbound.InviteID)
+	requireAPIErrorCode(t, err, "invite_used")
+
+	_, err = s.CreateInvite("not-a-key", "broken", nil, false)
+	requireAPIErrorCode(t, err, "invalid_client_public_key")
+}
@@ -0,0 +1,38 @@
+package serverstate
+
+// RateWindow is a fixed-window rate limit: Requests per WindowSeconds.
+type RateWindow struct {
+	Requests      int `json:"requests"`
+	WindowSeconds int `json:"windowSeconds"`
+}
+
+// Limits reports the limits the server enforces, so clients can throttle
+// proactively instead of discovering them through 429s. Values come from the
+// same constants and config the limiters read. Per-channel slow mode and
+// message length are listed with the channels.
+type Limits struct {
+	ServerSign              RateWindow `json:"serverSign"`
+	MaxSlowModeSeconds      int        `json:"maxSlowModeSeconds"`
+	MaxMessageLength        int        `json:"maxMessageLength"`
+	MaxChannelMessageLength int        `json:"maxChannelMessageLength"`
+	MaxHistoryPageSize      int        `json:"maxHistoryPageSize"`
+	MaxImportBatchSize      int        `json:"maxImportBatchSize"`
+	// Messages is omitted when MESSAGE_RATE is unset.
+	Messages *MessageRate `json:"messages,omitempty"`
+}
+
+func (s *State) Limits() Limits {
+	var messages *MessageRate
+	if s.cfg.MessageRatePerMinute > 0 {
+		messages = &MessageRate{RatePerMinute: s.cfg.MessageRatePerMinute, Burst: s.cfg.MessageBurst}
+	}
+	return Limits{
+		ServerSign:              RateWindow{Requests: signRequestsPerWindow, WindowSeconds: int(signRateWindow.Seconds())},
+		MaxSlowModeSeconds:      maxSlowModeSeconds,
+		MaxMessageLength:        maxMessageLength,
+		MaxChannelMessageLength: maxChannelMessageLength,
+		MaxHistoryPageSize:      maxMessageHistoryLimit,
+		MaxImportBatchSize:      maxImportBatchSize,
+		Messages:                messages,
+	}
+}
@@ -0,0 +1,33 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestLimitsReportEnforcedValues(t *testing.T) {
+	s := newTestState(t, nil)
+
+	limits := s.Limits()
+	if limits.ServerSign != (RateWindow{Requests: signRequestsPerWindow, WindowSeconds: int(signRateWindow.Seconds())}) {
+		t.Fatalf("unexpected signing window: %+v", limits.ServerSign)
+	}
+	if limits.MaxMessageLength != maxMessageLength || limits.MaxChannelMessageLength != maxChannelMessageLength {
+		t.Fatalf("unexpected message lengths: %+v", limits)
+	}
+	if limits.MaxSlowModeSeconds != maxSlowModeSeconds || limits.MaxHistoryPageSize != maxMessageHistoryLimit || limits.MaxImportBatchSize != maxImportBatchSize {
+		t.Fatalf("unexpected bounds: %+v", limits)
+	}
+	if limits.Messages != nil {
+		t.Fatalf("message rate must be omitted without MESSAGE_RATE, got %+v", limits.Messages)
+	}
+
+	limited := newTestState(t, func(cfg *config.Config) {
+		cfg.MessageRatePerMinute = 30
+		cfg.MessageBurst = 2
+	})
+	if messages := limited.Limits().Messages; messages == nil || *messages != (MessageRate{RatePerMinute: 30, Burst: 2}) {
+		t.Fatalf("unexpected message rate: %+v", messages)
+	}
+}
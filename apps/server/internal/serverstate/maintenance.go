@@ -0,0 +1,18 @@
+package serverstate
+
+// SetMaintenance toggles maintenance mode at runtime. While enabled, mutations such as
+// posting or editing messages, joining voice and creating invites are rejected, while
+// reads keep working.
+func (s *State) SetMaintenance(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maintenance = enabled
+}
+
+func (s *State) ensureWritableLocked() error {
+	if s.maintenance {
+		return newAPIError(503, "maintenance_mode", "server is in maintenance mode")
+	}
+	return nil
+}
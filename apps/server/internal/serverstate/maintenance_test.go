@@ -0,0 +1,46 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestMaintenanceModeBlocksWritesButNotReads(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	message, err := s.CreateMessage(member.SessionToken, "general", "before maintenance")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	s.SetMaintenance(true)
+	if !s.ServerInfo().Maintenance {
+		t.Fatal("expected maintenance flag in server info")
+	}
+
+	_, err = s.CreateMessage(member.SessionToken, "general", "during maintenance")
+	requireAPIErrorCode(t, err, "maintenance_mode")
+	_, err = s.EditMessage(member.SessionToken, "general", message.ID, "edited")
+	requireAPIErrorCode(t, err, "maintenance_mode")
+	_, err = s.BeginVoiceJoin(member.SessionToken, "voice-main")
+	requireAPIErrorCode(t, err, "maintenance_mode")
+	_, err = s.CreateInvite(member.PublicKey, "blocked", nil, false)
+	requireAPIErrorCode(t, err, "maintenance_mode")
+
+	if _, err := s.ListMessages(member.SessionToken, "general", 10); err != nil {
+		t.Fatalf("reads must keep working in maintenance mode: %v", err)
+	}
+
+	s.SetMaintenance(false)
+	if _, err := s.CreateMessage(member.SessionToken, "general", "after maintenance"); err != nil {
+		t.Fatalf("create message failed after maintenance: %v", err)
+	}
+}
+
+func TestMaintenanceModeDefaultsFromConfig(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.Maintenance = true })
+	if !s.ServerInfo().Maintenance {
+		t.Fatal("expected maintenance mode enabled from config")
+	}
+}
@@ -0,0 +1,101 @@
+package serverstate
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// exportBatchSize bounds how many rows the streaming exports hold in memory.
+const exportBatchSize = 500
+
+// MemberExport is one line of the admin member export. Sessions, invites and
+// other credentials are deliberately left out.
+type MemberExport struct {
+	PublicKey        string   `json:"publicKey"`
+	DisplayName      string   `json:"displayName"`
+	FirstConnectedAt string   `json:"firstConnectedAt"`
+	LastConnectedAt  string   `json:"lastConnectedAt"`
+	IsAdmin          bool     `json:"isAdmin"`
+	Roles            []string `json:"roles"`
+}
+
+// ExportMembers passes every member to emit, oldest first. Members are read in
+// keyset-paginated batches and each batch is closed before emit runs: the
+// database has a single connection, so emitting to a slow consumer with rows
+// still open would stall every other request.
+func (s *State) ExportMembers(emit func(MemberExport) error) error {
+	return s.exportMembers(exportBatchSize, emit)
+}
+
+func (s *State) exportMembers(batchSize int, emit func(MemberExport) error) error {
+	var after *MemberExport
+	for {
+		batch, err := s.memberExportBatch(after, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, member := range batch {
+			if err := emit(member); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		after = &batch[len(batch)-1]
+	}
+}
+
+// memberExportBatch reads up to limit members ordered after the given member.
+func (s *State) memberExportBatch(after *MemberExport, limit int) ([]MemberExport, error) {
+	s.mu.Lock()
+	admins := make(map[string]bool, len(s.serverCfg.AdminPublicKeys))
+	for _, publicKey := range s.serverCfg.AdminPublicKeys {
+		admins[publicKey] = true
+	}
+	s.mu.Unlock()
+
+	where, args := "", []any{}
+	if after != nil {
+		where = `WHERE m.first_connected_at > ? OR (m.first_connected_at = ? AND m.public_key > ?)`
+		args = append(args, after.FirstConnectedAt, after.FirstConnectedAt, after.PublicKey)
+	}
+	rows, err := s.db.Query(`
+		SELECT
+			m.public_key,
+			m.display_name,
+			m.first_connected_at,
+			m.last_connected_at,
+			(SELECT GROUP_CONCAT(r.role, ',') FROM member_roles r WHERE r.public_key = m.public_key)
+		FROM members m
+		`+where+`
+		ORDER BY m.first_connected_at ASC, m.public_key ASC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("query member export: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]MemberExport, 0, limit)
+	for rows.Next() {
+		var (
+			member MemberExport
+			roles  sql.NullString
+		)
+		if err := rows.Scan(&member.PublicKey, &member.DisplayName, &member.FirstConnectedAt, &member.LastConnectedAt, &roles); err != nil {
+			return nil, fmt.Errorf("scan member export row: %w", err)
+		}
+		member.IsAdmin = admins[member.PublicKey]
+		member.Roles = []string{}
+		if roles.Valid && roles.String != "" {
+			member.Roles = strings.Split(roles.String, ",")
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate member export rows: %w", err)
+	}
+	return members, nil
+}
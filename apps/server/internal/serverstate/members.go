@@ -0,0 +1,368 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const deletedAuthorName = "Deleted User"
+
+type LeaveServerResult struct {
+	Status             string `json:"status"`
+	MessagesDeleted    int64  `json:"messagesDeleted"`
+	MessagesAnonymized int64  `json:"messagesAnonymized"`
+}
+
+// LeaveServer removes the authenticated member, their sessions and voice presence.
+// Message history is retained under the original author unless purgeMessages is set,
+// in which case every message the member authored is deleted as well. With
+// ANONYMIZE_ON_LEAVE, retained messages are reattributed to "Deleted User" with an
+// empty public key; the original author is not kept anywhere, so this is permanent.
+func (s *State) LeaveServer(sessionToken string, purgeMessages bool) (LeaveServerResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return LeaveServerResult{}, err
+	}
+
+	var purge *time.Time
+	if purgeMessages {
+		purge = &time.Time{}
+	}
+	return s.removeMemberLocked(identity.PublicKey, purge, s.cfg.AnonymizeOnLeave)
+}
+
+// KickMember removes a member like LeaveServer does on their behalf. When
+// deleteMessagesSince is set, the member's messages created after it are
+// deleted across all channels and announced; older ones are kept, and
+// anonymized under ANONYMIZE_ON_LEAVE. The member can come back with a new
+// invite.
+func (s *State) KickMember(publicKey string, deleteMessagesSince *time.Time) (LeaveServerResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return LeaveServerResult{}, err
+	}
+	publicKey = strings.TrimSpace(publicKey)
+	if _, err := s.findMemberLocked(publicKey); err != nil {
+		return LeaveServerResult{}, err
+	}
+
+	result, err := s.removeMemberLocked(publicKey, deleteMessagesSince, s.cfg.AnonymizeOnLeave)
+	if err != nil {
+		return LeaveServerResult{}, err
+	}
+	result.Status = "kicked"
+	return result, nil
+}
+
+// removeMemberLocked deletes a member with everything keyed by their public key
+// in one transaction. Messages created after purgeSince are deleted (the zero
+// time purges all of them); a nil purgeSince keeps every message.
+func (s *State) removeMemberLocked(publicKey string, purgeSince *time.Time, anonymize bool) (LeaveServerResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return LeaveServerResult{}, fmt.Errorf("begin leave tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var messagesDeleted int64
+	var purged []messageRef
+	if purgeSince != nil {
+		where, args := `author_public_key = ?`, []any{publicKey}
+		if !purgeSince.IsZero() {
+			where += ` AND created_at > ?`
+			args = append(args, purgeSince.UTC().Format(time.RFC3339))
+		}
+		if purged, err = listMessageRefsTx(tx, where, args...); err != nil {
+			return LeaveServerResult{}, err
+		}
+		if messagesDeleted, err = deleteMessagesTx(tx, where, args...); err != nil {
+			return LeaveServerResult{}, err
+		}
+	}
+
+	var messagesAnonymized int64
+	if anonymize {
+		if messagesAnonymized, err = anonymizeAuthorTx(tx, publicKey); err != nil {
+			return LeaveServerResult{}, err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM voice_presence WHERE client_public_key = ?`, publicKey); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("delete member voice presence: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE client_public_key = ?`, publicKey); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("delete member sessions: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM drafts WHERE client_public_key = ?`, publicKey); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("delete member drafts: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM read_markers WHERE client_public_key = ?`, publicKey); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("delete member read markers: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM motd_acks WHERE client_public_key = ?`, publicKey); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("delete member motd ack: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM member_roles WHERE public_key = ?`, publicKey); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("delete member roles: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM members WHERE public_key = ?`, publicKey); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("delete member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return LeaveServerResult{}, fmt.Errorf("commit leave tx: %w", err)
+	}
+
+	for key := range s.lastPostAt {
+		if key.PublicKey == publicKey {
+			delete(s.lastPostAt, key)
+		}
+	}
+	delete(s.messageBuckets, publicKey)
+	// A redeemed challenge would otherwise let the removed key replay its finish
+	// for a new session until finishRetryWindow runs out.
+	for inviteID, pending := range s.challenges {
+		if pending.RedeemedBy == publicKey {
+			delete(s.challenges, inviteID)
+		}
+	}
+	for _, ref := range purged {
+		s.broadcastChannelEventLocked(ref.ChannelID, messageDeletedEvent(ref.ChannelID, ref.MessageID))
+	}
+	s.closeMemberStreamsLocked(publicKey)
+
+	return LeaveServerResult{Status: "left", MessagesDeleted: messagesDeleted, MessagesAnonymized: messagesAnonymized}, nil
+}
+
+func anonymizeAuthorTx(tx *sql.Tx, publicKey string) (int64, error) {
+	result, err := tx.Exec(`
+		UPDATE messages SET author_name = ?, author_public_key = '' WHERE author_public_key = ?
+	`, deletedAuthorName, publicKey)
+	if err != nil {
+		return 0, fmt.Errorf("anonymize member messages: %w", err)
+	}
+	anonymized, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check anonymized messages: %w", err)
+	}
+	return anonymized, nil
+}
+
+const (
+	defaultMemberListLimit = 50
+	maxMemberListLimit     = 200
+)
+
+// keyPrefixPattern accepts a prefix of a standard base64 ed25519 public key.
+var keyPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9+/]{1,43}=?$`)
+
+type Member struct {
+	PublicKey        string `json:"publicKey"`
+	DisplayName      string `json:"displayName"`
+	FirstConnectedAt string `json:"firstConnectedAt"`
+	LastConnectedAt  string `json:"lastConnectedAt"`
+	Online           bool   `json:"online"`
+	IsAdmin          bool   `json:"isAdmin"`
+}
+
+type CreateMemberResult struct {
+	Member       Member `json:"member"`
+	SessionToken string `json:"sessionToken,omitempty"`
+}
+
+type ListMembersQuery struct {
+	Query string
+	// KeyPrefix matches the start of the base64 public key, case-sensitively.
+	KeyPrefix string
+	Online    *bool
+	Limit     int
+	Offset    int
+}
+
+type ListMembersResult struct {
+	Members []Member `json:"members"`
+	Total   int      `json:"total"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+}
+
+// ListMembers returns a page of members ordered by most recent connection. Query
+// matches a substring of the display name or a prefix of the public key; Online
+// filters on members holding an open channel stream or an active voice presence.
+func (s *State) ListMembers(query ListMembersQuery) (ListMembersResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if query.Limit <= 0 || query.Limit > maxMemberListLimit {
+		query.Limit = defaultMemberListLimit
+	}
+	if query.Offset < 0 {
+		return ListMembersResult{}, newAPIError(400, "invalid_offset", "offset must not be negative")
+	}
+	query.KeyPrefix = strings.TrimSpace(query.KeyPrefix)
+	if query.KeyPrefix != "" && !keyPrefixPattern.MatchString(query.KeyPrefix) {
+		return ListMembersResult{}, newAPIError(400, "invalid_key_prefix", "keyPrefix must be up to 44 base64 characters")
+	}
+
+	if err := s.cleanupVoicePresenceLocked(); err != nil {
+		return ListMembersResult{}, err
+	}
+
+	where, args := s.memberFilterLocked(query)
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM members m`+where, args...).Scan(&total); err != nil {
+		return ListMembersResult{}, fmt.Errorf("count members: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT
+			m.public_key,
+			m.display_name,
+			m.first_connected_at,
+			m.last_connected_at,
+			EXISTS(SELECT 1 FROM voice_presence vp WHERE vp.client_public_key = m.public_key)
+		FROM members m`+where+`
+		ORDER BY m.last_connected_at DESC, m.public_key ASC
+		LIMIT ? OFFSET ?
+	`, append(args, query.Limit, query.Offset)...)
+	if err != nil {
+		return ListMembersResult{}, fmt.Errorf("query members: %w", err)
+	}
+	defer rows.Close()
+
+	result := ListMembersResult{
+		Members: []Member{},
+		Total:   total,
+		Limit:   query.Limit,
+		Offset:  query.Offset,
+	}
+	for rows.Next() {
+		var (
+			member  Member
+			inVoice int
+		)
+		if err := rows.Scan(&member.PublicKey, &member.DisplayName, &member.FirstConnectedAt, &member.LastConnectedAt, &inVoice); err != nil {
+			return ListMembersResult{}, fmt.Errorf("scan member row: %w", err)
+		}
+		member.Online = inVoice != 0 || s.streamMembers[member.PublicKey] > 0
+		member.IsAdmin = s.isAdminPublicKeyLocked(member.PublicKey)
+		result.Members = append(result.Members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return ListMembersResult{}, fmt.Errorf("iterate member rows: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *State) memberFilterLocked(query ListMembersQuery) (string, []any) {
+	var (
+		predicates []string
+		args       []any
+	)
+
+	if q := strings.TrimSpace(query.Query); q != "" {
+		escaped := escapeLikePattern(q)
+		predicates = append(predicates, `(m.display_name LIKE ? ESCAPE '\' OR m.public_key LIKE ? ESCAPE '\')`)
+		args = append(args, "%"+escaped+"%", escaped+"%")
+	}
+
+	// substr keeps the match case-sensitive, unlike LIKE.
+	if query.KeyPrefix != "" {
+		predicates = append(predicates, `substr(m.public_key, 1, ?) = ?`)
+		args = append(args, len(query.KeyPrefix), query.KeyPrefix)
+	}
+
+	if query.Online != nil {
+		onlineKeys := make([]string, 0, len(s.streamMembers))
+		for publicKey := range s.streamMembers {
+			onlineKeys = append(onlineKeys, publicKey)
+		}
+
+		online := `m.public_key IN (SELECT client_public_key FROM voice_presence)`
+		if len(onlineKeys) > 0 {
+			online = `(` + online + ` OR m.public_key IN (?` + strings.Repeat(`, ?`, len(onlineKeys)-1) + `))`
+			for _, publicKey := range onlineKeys {
+				args = append(args, publicKey)
+			}
+		}
+		if !*query.Online {
+			online = `NOT ` + online
+		}
+		predicates = append(predicates, online)
+	}
+
+	if len(predicates) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(predicates, ` AND `), args
+}
+
+func escapeLikePattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
+
+// CreateMember registers a member directly, without an invite or handshake, for
+// roster imports and scripted setups. A session token is issued only on request.
+func (s *State) CreateMember(publicKey, displayName string, issueSession bool) (CreateMemberResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	publicKey = strings.TrimSpace(publicKey)
+	if _, err := decodePublicKey(publicKey); err != nil {
+		return CreateMemberResult{}, newAPIError(400, "invalid_client_public_key", "publicKey must be base64(ed25519 public key)")
+	}
+
+	if _, err := s.findMemberLocked(publicKey); err == nil {
+		return CreateMemberResult{}, newAPIError(409, "member_exists", "member already exists")
+	} else if !isAPIErrorCode(err, "member_not_found") {
+		return CreateMemberResult{}, err
+	}
+
+	if err := s.upsertMemberLocked(publicKey, normalizeDisplayName(displayName, publicKey)); err != nil {
+		return CreateMemberResult{}, err
+	}
+
+	member, err := s.findMemberLocked(publicKey)
+	if err != nil {
+		return CreateMemberResult{}, err
+	}
+
+	result := CreateMemberResult{Member: member}
+	if issueSession {
+		if result.SessionToken, err = s.issueSessionTokenLocked(publicKey); err != nil {
+			return CreateMemberResult{}, err
+		}
+	}
+	return result, nil
+}
+
+func (s *State) findMemberLocked(publicKey string) (Member, error) {
+	var member Member
+	err := s.db.QueryRow(`
+		SELECT public_key, display_name, first_connected_at, last_connected_at
+		FROM members
+		WHERE public_key = ?
+	`, publicKey).Scan(&member.PublicKey, &member.DisplayName, &member.FirstConnectedAt, &member.LastConnectedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Member{}, newAPIError(404, "member_not_found", "member does not exist")
+	}
+	if err != nil {
+		return Member{}, fmt.Errorf("query member: %w", err)
+	}
+
+	member.Online = s.streamMembers[member.PublicKey] > 0
+	member.IsAdmin = s.isAdminPublicKeyLocked(member.PublicKey)
+	return member, nil
+}
@@ -0,0 +1,371 @@
+package serverstate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestListMembersFiltersAndPaginates(t *testing.T) {
+	s := newTestState(t, nil)
+	alice := connectTestMember(t, s, "alice_100%")
+	connectTestMember(t, s, "bob")
+	connectTestMember(t, s, "alicia")
+
+	result, err := s.ListMembers(ListMembersQuery{Query: "ali", Limit: 1})
+	if err != nil {
+		t.Fatalf("list members failed: %v", err)
+	}
+	if result.Total != 2 || len(result.Members) != 1 {
+		t.Fatalf("unexpected page: total=%d members=%d", result.Total, len(result.Members))
+	}
+
+	result, err = s.ListMembers(ListMembersQuery{Query: "_100%"})
+	if err != nil {
+		t.Fatalf("list members failed: %v", err)
+	}
+	if result.Total != 1 || result.Members[0].PublicKey != alice.PublicKey {
+		t.Fatalf("expected LIKE wildcards to be matched literally, got %+v", result.Members)
+	}
+
+	result, err = s.ListMembers(ListMembersQuery{Query: alice.PublicKey[:10]})
+	if err != nil {
+		t.Fatalf("list members failed: %v", err)
+	}
+	if result.Total != 1 || result.Members[0].PublicKey != alice.PublicKey {
+		t.Fatalf("expected key prefix match, got %+v", result.Members)
+	}
+
+	_, cancel, err := s.SubscribeChannelEvents(alice.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	online := true
+	result, err = s.ListMembers(ListMembersQuery{Online: &online})
+	if err != nil {
+		t.Fatalf("list members failed: %v", err)
+	}
+	if result.Total != 1 || !result.Members[0].Online {
+		t.Fatalf("expected only the streaming member online, got %+v", result.Members)
+	}
+
+	cancel()
+	result, err = s.ListMembers(ListMembersQuery{Online: &online})
+	if err != nil {
+		t.Fatalf("list members failed: %v", err)
+	}
+	if result.Total != 0 {
+		t.Fatalf("expected no online members after unsubscribe, got %d", result.Total)
+	}
+}
+
+func TestExportMembersIncludesRolesAndAdmins(t *testing.T) {
+	s := newTestState(t, nil)
+	alice := connectTestMember(t, s, "alice")
+	bob := connectTestMember(t, s, "bob")
+	makeTestAdmin(t, s, bob)
+	if _, err := s.SetMemberRoles(alice.PublicKey, []string{"mod", "artist"}); err != nil {
+		t.Fatalf("set roles failed: %v", err)
+	}
+
+	exported := map[string]MemberExport{}
+	if err := s.ExportMembers(func(member MemberExport) error {
+		exported[member.PublicKey] = member
+		// Other requests must not wait on a consumer that is still reading.
+		_, err := s.ListMembers(ListMembersQuery{Limit: 1})
+		return err
+	}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	if len(exported) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(exported))
+	}
+	if roles := exported[alice.PublicKey].Roles; len(roles) != 2 {
+		t.Fatalf("expected alice's roles, got %v", roles)
+	}
+	if !exported[bob.PublicKey].IsAdmin || exported[alice.PublicKey].IsAdmin {
+		t.Fatalf("unexpected admin flags: %+v", exported)
+	}
+}
+
+func TestExportMembersPagesThroughEveryMember(t *testing.T) {
+	s := newTestState(t, nil)
+	want := map[string]bool{}
+	for _, name := range []string{"alice", "bob", "carol"} {
+		want[connectTestMember(t, s, name).PublicKey] = true
+	}
+
+	for _, batchSize := range []int{1, 2, 3} {
+		seen := map[string]bool{}
+		if err := s.exportMembers(batchSize, func(member MemberExport) error {
+			if seen[member.PublicKey] {
+				t.Fatalf("batch size %d exported %s twice", batchSize, member.DisplayName)
+			}
+			seen[member.PublicKey] = true
+			return nil
+		}); err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+		if len(seen) != len(want) {
+			t.Fatalf("batch size %d exported %d of %d members", batchSize, len(seen), len(want))
+		}
+	}
+}
+
+func TestLeaveServerAnonymizesRetainedMessages(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.AnonymizeOnLeave = true })
+	leaver := connectTestMember(t, s, "leaver")
+	reader := connectTestMember(t, s, "reader")
+	if _, err := s.CreateMessage(leaver.SessionToken, "general", "goodbye"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	result, err := s.LeaveServer(leaver.SessionToken, false)
+	if err != nil {
+		t.Fatalf("leave failed: %v", err)
+	}
+	if result.MessagesAnonymized != 1 {
+		t.Fatalf("expected 1 anonymized message, got %+v", result)
+	}
+
+	listed, err := s.ListMessages(reader.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(listed.Messages) != 1 {
+		t.Fatalf("expected retained message, got %+v", listed.Messages)
+	}
+	author := listed.Messages[0].Author
+	if author.DisplayName != "Deleted User" || author.PublicKey != "" {
+		t.Fatalf("expected anonymized author, got %+v", author)
+	}
+}
+
+func TestLeaveServerPurgeRemovesMessageReports(t *testing.T) {
+	s := newTestState(t, nil)
+	leaver := connectTestMember(t, s, "leaver")
+	reporter := connectTestMember(t, s, "reporter")
+	message, err := s.CreateMessage(leaver.SessionToken, "general", "spam")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if _, err := s.ReportMessage(reporter.SessionToken, "general", message.ID, "spam"); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+
+	result, err := s.LeaveServer(leaver.SessionToken, true)
+	if err != nil {
+		t.Fatalf("leave failed: %v", err)
+	}
+	if result.MessagesDeleted != 1 {
+		t.Fatalf("expected 1 deleted message, got %+v", result)
+	}
+
+	var orphans int
+	if err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM message_reports WHERE message_id NOT IN (SELECT id FROM messages)
+	`).Scan(&orphans); err != nil {
+		t.Fatalf("count orphans failed: %v", err)
+	}
+	if orphans != 0 {
+		t.Fatalf("expected no orphaned reports, got %d", orphans)
+	}
+}
+
+func TestListMembersFiltersByKeyPrefix(t *testing.T) {
+	s := newTestState(t, nil)
+	alice := connectTestMember(t, s, "alice")
+	connectTestMember(t, s, "bob")
+
+	result, err := s.ListMembers(ListMembersQuery{KeyPrefix: alice.PublicKey[:12]})
+	if err != nil {
+		t.Fatalf("list members failed: %v", err)
+	}
+	if result.Total != 1 || result.Members[0].PublicKey != alice.PublicKey {
+		t.Fatalf("expected key prefix match, got %+v", result.Members)
+	}
+
+	result, err = s.ListMembers(ListMembersQuery{KeyPrefix: alice.PublicKey[:12], Query: "bob"})
+	if err != nil {
+		t.Fatalf("list members failed: %v", err)
+	}
+	if result.Total != 0 {
+		t.Fatalf("keyPrefix and q must both match, got %+v", result.Members)
+	}
+
+	swapped := []rune(alice.PublicKey[:12])
+	for i, r := range swapped {
+		if r >= 'a' && r <= 'z' {
+			swapped[i] = r - 'a' + 'A'
+		} else if r >= 'A' && r <= 'Z' {
+			swapped[i] = r - 'A' + 'a'
+		}
+	}
+	if string(swapped) != alice.PublicKey[:12] {
+		result, err = s.ListMembers(ListMembersQuery{KeyPrefix: string(swapped)})
+		if err != nil {
+			t.Fatalf("list members failed: %v", err)
+		}
+		for _, member := range result.Members {
+			if member.PublicKey == alice.PublicKey {
+				t.Fatal("key prefix matching must be case-sensitive")
+			}
+		}
+	}
+
+	for _, prefix := range []string{"abc%", "ab_c", strings.Repeat("A", 45)} {
+		_, err = s.ListMembers(ListMembersQuery{KeyPrefix: prefix})
+		requireAPIErrorCode(t, err, "invalid_key_prefix")
+	}
+}
+
+func TestLeaveServerPurgeAnnouncesDeletedMessages(t *testing.T) {
+	s := newTestState(t, nil)
+	leaver := connectTestMember(t, s, "leaver")
+	reader := connectTestMember(t, s, "reader")
+	message, err := s.CreateMessage(leaver.SessionToken, "general", "goodbye")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	events, cancel, err := s.SubscribeChannelEvents(reader.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	if _, err := s.LeaveServer(leaver.SessionToken, true); err != nil {
+		t.Fatalf("leave failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events))
+	}
+	if event := <-events; event.Type != "message.deleted" || event.ChannelID != "general" || event.MessageID != message.ID {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+}
+
+func TestKickMemberDeletesOnlyMessagesSinceCutoff(t *testing.T) {
+	s := newTestState(t, nil)
+	spammer := connectTestMember(t, s, "spammer")
+	reader := connectTestMember(t, s, "reader")
+	old, err := s.CreateMessage(spammer.SessionToken, "general", "legitimate")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if _, err := s.db.Exec(`UPDATE messages SET created_at = ? WHERE id = ?`, "2020-01-01T00:00:00Z", old.ID); err != nil {
+		t.Fatalf("backdate message failed: %v", err)
+	}
+	spam, err := s.CreateMessage(spammer.SessionToken, "general", "spam")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	events, cancel, err := s.SubscribeChannelEvents(reader.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := s.KickMember(spammer.PublicKey, &cutoff)
+	if err != nil {
+		t.Fatalf("kick failed: %v", err)
+	}
+	if result.Status != "kicked" || result.MessagesDeleted != 1 {
+		t.Fatalf("unexpected kick result: %+v", result)
+	}
+	if event := <-events; event.Type != "message.deleted" || event.MessageID != spam.ID {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+
+	listed, err := s.ListMessages(reader.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(listed.Messages) != 1 || listed.Messages[0].ID != old.ID {
+		t.Fatalf("expected only the older message to remain, got %+v", listed.Messages)
+	}
+	_, err = s.AuthenticateSession(spammer.SessionToken)
+	requireAPIErrorCode(t, err, "invalid_session_token")
+	_, err = s.KickMember(spammer.PublicKey, nil)
+	requireAPIErrorCode(t, err, "member_not_found")
+}
+
+func TestKickMemberClosesTheirStreams(t *testing.T) {
+	s := newTestState(t, nil)
+	kicked := connectTestMember(t, s, "kicked")
+	reader := connectTestMember(t, s, "reader")
+
+	kickedEvents, cancelKicked, err := s.SubscribeChannelEvents(kicked.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	readerEvents, cancelReader, err := s.SubscribeChannelEvents(reader.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancelReader()
+
+	if _, err := s.KickMember(kicked.PublicKey, nil); err != nil {
+		t.Fatalf("kick failed: %v", err)
+	}
+	if _, ok := <-kickedEvents; ok {
+		t.Fatal("the kicked member's stream must be closed")
+	}
+	s.mu.Lock()
+	kickedStreams, readerStreams := s.streamMembers[kicked.PublicKey], s.streamMembers[reader.PublicKey]
+	s.mu.Unlock()
+	if kickedStreams != 0 || readerStreams != 1 {
+		t.Fatalf("expected only the reader's stream to count, got %d and %d", kickedStreams, readerStreams)
+	}
+	cancelKicked()
+
+	if _, err := s.CreateMessage(reader.SessionToken, "general", "still here"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if event := <-readerEvents; event.Type != "message.created" {
+		t.Fatalf("other streams must stay open, got %+v", event)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		cancelReader()
+		_ = s.WaitForStreams(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("closed streams must still be released by their cancel func")
+	}
+}
+
+func TestKickMemberAnonymizesRetainedMessages(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.AnonymizeOnLeave = true })
+	kicked := connectTestMember(t, s, "kicked")
+	reader := connectTestMember(t, s, "reader")
+	if _, err := s.CreateMessage(kicked.SessionToken, "general", "kept"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	result, err := s.KickMember(kicked.PublicKey, nil)
+	if err != nil {
+		t.Fatalf("kick failed: %v", err)
+	}
+	if result.MessagesAnonymized != 1 {
+		t.Fatalf("unexpected kick result: %+v", result)
+	}
+
+	listed, err := s.ListMessages(reader.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(listed.Messages) != 1 || listed.Messages[0].Author.PublicKey != "" || listed.Messages[0].Author.DisplayName != deletedAuthorName {
+		t.Fatalf("kicked member's messages must be anonymized, got %+v", listed.Messages)
+	}
+}
@@ -0,0 +1,35 @@
+package serverstate
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	fencedCodePattern  = regexp.MustCompile("(?s)```.*?```")
+	inlineCodePattern  = regexp.MustCompile("`[^`\n]*`")
+	massMentionPattern = regexp.MustCompile(`(^|[^\w@])@(everyone|here)\b`)
+)
+
+// containsMassMention reports whether content pings @everyone or @here outside
+// of code spans, where such tokens are shown literally.
+func containsMassMention(content string) bool {
+	if !strings.Contains(content, "@") {
+		return false
+	}
+	content = fencedCodePattern.ReplaceAllString(content, " ")
+	content = inlineCodePattern.ReplaceAllString(content, " ")
+	return massMentionPattern.MatchString(content)
+}
+
+// ensureMentionsAllowedLocked enforces ALLOW_MASS_MENTION=false for members;
+// anyone who may moderate the channel may always ping everyone.
+func (s *State) ensureMentionsAllowedLocked(identity SessionIdentity, channelID, content string) error {
+	if s.cfg.AllowMassMention || s.hasChannelPermissionLocked(identity.PublicKey, channelID, PermissionModerateMessages) {
+		return nil
+	}
+	if containsMassMention(content) {
+		return newAPIError(403, "mass_mention_forbidden", "@everyone and @here mentions are restricted to admins")
+	}
+	return nil
+}
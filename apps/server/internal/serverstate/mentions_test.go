@@ -0,0 +1,46 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestContainsMassMention(t *testing.T) {
+	cases := map[string]bool{
+		"hey @everyone":            true,
+		"@here look":               true,
+		"(@everyone)":              true,
+		"mail me at a@everyone.io": false,
+		"@everyoneelse":            false,
+		"`@everyone` is blocked":   false,
+		"```\n@here\n```":          false,
+		"no mentions":              false,
+	}
+	for content, want := range cases {
+		if got := containsMassMention(content); got != want {
+			t.Errorf("containsMassMention(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
+
+func TestMassMentionRestrictedToAdmins(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.AllowMassMention = false })
+	member := connectTestMember(t, s, "member")
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+
+	_, err := s.CreateMessage(member.SessionToken, "general", "@everyone hello")
+	requireAPIErrorCode(t, err, "mass_mention_forbidden")
+
+	message, err := s.CreateMessage(member.SessionToken, "general", "hello")
+	if err != nil {
+		t.Fatalf("plain message should be accepted: %v", err)
+	}
+	_, err = s.EditMessage(member.SessionToken, "general", message.ID, "hello @here")
+	requireAPIErrorCode(t, err, "mass_mention_forbidden")
+
+	if _, err := s.CreateMessage(admin.SessionToken, "general", "@everyone maintenance tonight"); err != nil {
+		t.Fatalf("admin should be exempt: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MessageContextResult is a window of history around one message, oldest
+// first. Messages includes the target itself.
+type MessageContextResult struct {
+	Message  ChannelMessage   `json:"message"`
+	Messages []ChannelMessage `json:"messages"`
+}
+
+// GetMessageContext returns up to before messages preceding messageID and up to
+// after messages following it, for jump-to-message views. It uses the history
+// order (see historyCursor), so the window matches what history shows around
+// the message, imported messages included. Window sizes are clamped to
+// 0..maxMessageHistoryLimit.
+func (s *State) GetMessageContext(sessionToken, channelID, messageID string, before, after int) (MessageContextResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureHistoryAccessLocked(sessionToken, channelID); err != nil {
+		return MessageContextResult{}, err
+	}
+
+	before = clampMessageContextWindow(before)
+	after = clampMessageContextWindow(after)
+
+	var at historyCursor
+	err := s.db.QueryRow(`SELECT created_at, rowid FROM messages WHERE id = ? AND channel_id = ?`, strings.TrimSpace(messageID), channelID).Scan(&at.CreatedAt, &at.RowID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return MessageContextResult{}, newAPIError(404, "message_not_found", "message does not exist")
+	}
+	if err != nil {
+		return MessageContextResult{}, fmt.Errorf("query context message: %w", err)
+	}
+
+	older, err := s.queryMessagesLocked(`
+		SELECT id, channel_id, author_public_key, author_name, content_markdown, content_encrypted, edited_by_public_key, edit_count, message_type, created_at, updated_at
+		FROM messages
+		WHERE channel_id = ? AND (created_at < ? OR (created_at = ? AND rowid <= ?))
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT ?
+	`, channelID, at.CreatedAt, at.CreatedAt, at.RowID, before+1)
+	if err != nil {
+		return MessageContextResult{}, err
+	}
+	newer, err := s.queryMessagesLocked(`
+		SELECT id, channel_id, author_public_key, author_name, content_markdown, content_encrypted, edited_by_public_key, edit_count, message_type, created_at, updated_at
+		FROM messages
+		WHERE channel_id = ? AND (created_at > ? OR (created_at = ? AND rowid > ?))
+		ORDER BY created_at ASC, rowid ASC
+		LIMIT ?
+	`, channelID, at.CreatedAt, at.CreatedAt, at.RowID, after)
+	if err != nil {
+		return MessageContextResult{}, err
+	}
+
+	messages := make([]ChannelMessage, 0, len(older)+len(newer))
+	for i := len(older) - 1; i >= 0; i-- {
+		messages = append(messages, older[i])
+	}
+	messages = append(messages, newer...)
+	return MessageContextResult{Message: older[0], Messages: messages}, nil
+}
+
+func clampMessageContextWindow(size int) int {
+	return max(0, min(size, maxMessageHistoryLimit))
+}
+
+func (s *State) queryMessagesLocked(query string, args ...any) ([]ChannelMessage, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]ChannelMessage, 0)
+	for rows.Next() {
+		message, err := scanMessageRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate message rows: %w", err)
+	}
+	return messages, nil
+}
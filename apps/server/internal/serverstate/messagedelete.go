@@ -0,0 +1,116 @@
+package serverstate
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// messageRef identifies a message across channels.
+type messageRef struct {
+	ChannelID string
+	MessageID string
+}
+
+// messageDeletedEvent also carries a stub message so clients that key their
+// view on event.message can drop it without special-casing deletions. The stub
+// holds only id and channelId; every other field is empty, so clients must not
+// merge it into a message they still display.
+func messageDeletedEvent(channelID, messageID string) ChannelEvent {
+	return ChannelEvent{
+		Type:      "message.deleted",
+		Message:   &ChannelMessage{ID: messageID, ChannelID: channelID},
+		ChannelID: channelID,
+		MessageID: messageID,
+	}
+}
+
+// DeleteMessage removes a single message. Only its author or a member holding
+// moderate_messages in the channel, which includes admins, may delete it.
+func (s *State) DeleteMessage(sessionToken, channelID, messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return err
+	}
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return err
+	}
+	channelID = strings.TrimSpace(channelID)
+	messageID = strings.TrimSpace(messageID)
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return err
+	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return err
+	}
+
+	existing, err := s.findMessageLocked(channelID, messageID)
+	if err != nil {
+		return err
+	}
+	if existing.Author.PublicKey != identity.PublicKey && !s.hasChannelPermissionLocked(identity.PublicKey, channelID, PermissionModerateMessages) {
+		return newAPIError(403, "not_message_author", "only the author or a moderator can delete this message")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin message delete tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := deleteMessagesTx(tx, `id = ? AND channel_id = ?`, messageID, channelID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit message delete tx: %w", err)
+	}
+
+	s.broadcastChannelEventLocked(channelID, messageDeletedEvent(channelID, messageID))
+	return nil
+}
+
+// listMessageRefsTx returns the messages matched by where, so callers can
+// announce them after deleteMessagesTx removed them.
+func listMessageRefsTx(tx *sql.Tx, where string, args ...any) ([]messageRef, error) {
+	rows, err := tx.Query(`SELECT channel_id, id FROM messages WHERE `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list messages: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []messageRef
+	for rows.Next() {
+		var ref messageRef
+		if err := rows.Scan(&ref.ChannelID, &ref.MessageID); err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate messages: %w", err)
+	}
+	return refs, nil
+}
+
+// deleteMessagesTx deletes the messages matched by where together with every row
+// that references them by message id, so dependent tables never keep orphans.
+// Tables keyed by message id must be added here.
+func deleteMessagesTx(tx *sql.Tx, where string, args ...any) (int64, error) {
+	if _, err := tx.Exec(`DELETE FROM message_reports WHERE message_id IN (SELECT id FROM messages WHERE `+where+`)`, args...); err != nil {
+		return 0, fmt.Errorf("delete message reports: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM messages WHERE `+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete messages: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check deleted messages: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,72 @@
+package serverstate
+
+import "testing"
+
+func TestDeleteMessageAllowsAuthorOrAdmin(t *testing.T) {
+	s := newTestState(t, nil)
+	author := connectTestMember(t, s, "author")
+	other := connectTestMember(t, s, "other")
+	admin := connectTestMember(t, s, "admin")
+	makeTestAdmin(t, s, admin)
+
+	first, err := s.CreateMessage(author.SessionToken, "general", "first")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	second, err := s.CreateMessage(author.SessionToken, "general", "second")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	err = s.DeleteMessage(other.SessionToken, "general", first.ID)
+	requireAPIErrorCode(t, err, "not_message_author")
+
+	events, cancel, err := s.SubscribeChannelEvents(other.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	if err := s.DeleteMessage(author.SessionToken, "general", first.ID); err != nil {
+		t.Fatalf("author delete failed: %v", err)
+	}
+	event := <-events
+	if event.Type != "message.deleted" || event.Message == nil || event.Message.ID != first.ID || event.Message.ChannelID != "general" {
+		t.Fatalf("unexpected delete event: %+v", event)
+	}
+	_, err = s.GetMessage(author.SessionToken, "general", first.ID)
+	requireAPIErrorCode(t, err, "message_not_found")
+
+	if err := s.DeleteMessage(admin.SessionToken, "general", second.ID); err != nil {
+		t.Fatalf("admin delete failed: %v", err)
+	}
+
+	err = s.DeleteMessage(author.SessionToken, "general", first.ID)
+	requireAPIErrorCode(t, err, "message_not_found")
+}
+
+func TestDeleteMessageAllowsChannelModerator(t *testing.T) {
+	s := newTestState(t, nil)
+	author := connectTestMember(t, s, "author")
+	moderator := connectTestMember(t, s, "moderator")
+	if _, err := s.SetMemberRoles(moderator.PublicKey, []string{"general-mod"}); err != nil {
+		t.Fatalf("set roles failed: %v", err)
+	}
+
+	message, err := s.CreateMessage(author.SessionToken, "general", "spam")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	err = s.DeleteMessage(moderator.SessionToken, "general", message.ID)
+	requireAPIErrorCode(t, err, "not_message_author")
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{AdminRoles: &[]string{"general-mod"}}); err != nil {
+		t.Fatalf("failed to scope channel admins: %v", err)
+	}
+	if err := s.DeleteMessage(moderator.SessionToken, "general", message.ID); err != nil {
+		t.Fatalf("moderator delete failed: %v", err)
+	}
+	_, err = s.GetMessage(author.SessionToken, "general", message.ID)
+	requireAPIErrorCode(t, err, "message_not_found")
+}
@@ -0,0 +1,69 @@
+package serverstate
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MessageRate is the per-member token bucket applied to new messages: up to
+// Burst messages at once, refilled at RatePerMinute.
+type MessageRate struct {
+	RatePerMinute int `json:"ratePerMinute"`
+	Burst         int `json:"burst"`
+}
+
+type messageBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// messageBucketLocked returns the member's bucket refilled up to now. A member
+// without a bucket starts with a full one.
+func (s *State) messageBucketLocked(publicKey string, now time.Time) messageBucket {
+	burst := float64(s.cfg.MessageBurst)
+	bucket, ok := s.messageBuckets[publicKey]
+	if !ok {
+		return messageBucket{Tokens: burst, UpdatedAt: now}
+	}
+	refill := now.Sub(bucket.UpdatedAt).Minutes() * float64(s.cfg.MessageRatePerMinute)
+	bucket.Tokens = math.Min(burst, bucket.Tokens+math.Max(refill, 0))
+	bucket.UpdatedAt = now
+	return bucket
+}
+
+// enforceMessageRateLocked rejects a post once the member has used up their
+// burst until the bucket refills one message. MESSAGE_RATE=0 disables it.
+func (s *State) enforceMessageRateLocked(identity SessionIdentity, now time.Time) error {
+	if s.cfg.MessageRatePerMinute <= 0 {
+		return nil
+	}
+	bucket := s.messageBucketLocked(identity.PublicKey, now)
+	if bucket.Tokens >= 1 {
+		return nil
+	}
+
+	perToken := time.Minute / time.Duration(s.cfg.MessageRatePerMinute)
+	wait := time.Duration((1 - bucket.Tokens) * float64(perToken))
+	retryAfter := int((wait + time.Second - 1) / time.Second)
+	return &APIError{
+		Status:  429,
+		Code:    "rate_limited",
+		Message: fmt.Sprintf("sending messages too quickly, retry in %d seconds", retryAfter),
+		Details: map[string]any{"retryAfterSeconds": retryAfter},
+		RateLimit: &RateLimit{
+			Limit:     s.cfg.MessageBurst,
+			Remaining: 0,
+			Reset:     now.Add(wait),
+		},
+	}
+}
+
+func (s *State) consumeMessageTokenLocked(identity SessionIdentity, now time.Time) {
+	if s.cfg.MessageRatePerMinute <= 0 {
+		return
+	}
+	bucket := s.messageBucketLocked(identity.PublicKey, now)
+	bucket.Tokens = math.Max(bucket.Tokens-1, 0)
+	s.messageBuckets[identity.PublicKey] = bucket
+}
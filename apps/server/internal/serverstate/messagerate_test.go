@@ -0,0 +1,42 @@
+package serverstate
+
+import (
+	"testing"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestMessageBurstAllowsShortBurstsButLimitsSustainedRate(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) {
+		cfg.MessageRatePerMinute = 60
+		cfg.MessageBurst = 3
+	})
+	member := connectTestMember(t, s, "member")
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.CreateMessage(member.SessionToken, "general", "burst"); err != nil {
+			t.Fatalf("message %d within the burst failed: %v", i+1, err)
+		}
+	}
+	_, err := s.CreateMessage(member.SessionToken, "general", "over")
+	if apiErr := requireAPIErrorCode(t, err, "rate_limited"); apiErr.Status != 429 || apiErr.RateLimit == nil {
+		t.Fatalf("unexpected rate limit error: %+v", apiErr)
+	}
+
+	// One second at 60 per minute refills exactly one message.
+	s.mu.Lock()
+	bucket := s.messageBuckets[member.PublicKey]
+	bucket.UpdatedAt = bucket.UpdatedAt.Add(-time.Second)
+	s.messageBuckets[member.PublicKey] = bucket
+	s.mu.Unlock()
+	if _, err := s.CreateMessage(member.SessionToken, "general", "refilled"); err != nil {
+		t.Fatalf("message after refill failed: %v", err)
+	}
+	_, err = s.CreateMessage(member.SessionToken, "general", "over again")
+	requireAPIErrorCode(t, err, "rate_limited")
+
+	if limits := s.Limits(); limits.Messages == nil || limits.Messages.Burst != 3 || limits.Messages.RatePerMinute != 60 {
+		t.Fatalf("unexpected message limits: %+v", limits.Messages)
+	}
+}
@@ -0,0 +1,43 @@
+package serverstate
+
+import "strings"
+
+// Message types tell clients how to render a message. Members post normal
+// messages; channel managers may post announcements and system notices.
+const (
+	MessageTypeNormal       = "normal"
+	MessageTypeAnnouncement = "announcement"
+	MessageTypeSystem       = "system"
+)
+
+type MessageInput struct {
+	ContentMarkdown  string
+	ContentEncrypted string
+	MessageType      string
+}
+
+// PostMessage creates a message of the given type; an empty type is normal.
+func (s *State) PostMessage(sessionToken, channelID string, input MessageInput) (ChannelMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body := messageBody{markdown: input.ContentMarkdown, encrypted: input.ContentEncrypted}
+	return s.createMessageLocked(sessionToken, channelID, body, input.MessageType)
+}
+
+// ensureMessageTypeAllowedLocked normalizes messageType and rejects styled types
+// from members who cannot manage the channel.
+func (s *State) ensureMessageTypeAllowedLocked(identity SessionIdentity, channelID, messageType string) (string, error) {
+	messageType = strings.ToLower(strings.TrimSpace(messageType))
+	switch messageType {
+	case "", MessageTypeNormal:
+		return MessageTypeNormal, nil
+	case MessageTypeAnnouncement, MessageTypeSystem:
+		if !s.hasChannelPermissionLocked(identity.PublicKey, channelID, PermissionManageChannel) {
+			return "", newAPIError(403, "missing_permission", "only channel admins can post "+messageType+" messages")
+		}
+		return messageType, nil
+	default:
+		return "", newAPIError(400, "invalid_message_type", "messageType must be normal, announcement or system")
+	}
+}
@@ -0,0 +1,29 @@
+package serverstate
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+
+	"fosscord/apps/server/internal/webhook"
+)
+
+// notifyWebhookLocked forwards a channel event to MESSAGE_WEBHOOK_URL when its
+// type is listed in MESSAGE_WEBHOOK_EVENTS and, if MESSAGE_WEBHOOK_CHANNELS is
+// set, the channel is listed there. The event is encoded here so the queued body
+// never aliases a message that is modified later.
+func (s *State) notifyWebhookLocked(channelID string, event ChannelEvent) {
+	if s.messageWebhook == nil || !slices.Contains(s.cfg.MessageWebhookEvents, event.Type) {
+		return
+	}
+	if len(s.cfg.MessageWebhookChannels) > 0 && !slices.Contains(s.cfg.MessageWebhookChannels, channelID) {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Warn("failed to encode webhook event", "event_type", event.Type, "error", err)
+		return
+	}
+	s.messageWebhook.Enqueue(webhook.Event{Type: event.Type, ChannelID: channelID, Body: body})
+}
@@ -0,0 +1,49 @@
+package serverstate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestMessageWebhookReceivesFilteredEvents(t *testing.T) {
+	received := make(chan ChannelEvent, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event ChannelEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode webhook body failed: %v", err)
+		}
+		received <- event
+	}))
+	defer receiver.Close()
+
+	s := newTestState(t, func(cfg *config.Config) {
+		cfg.MessageWebhookURL = receiver.URL
+		cfg.MessageWebhookSecret = "secret"
+		cfg.MessageWebhookEvents = []string{"message.created"}
+		cfg.MessageWebhookChannels = []string{"general"}
+	})
+	member := connectTestMember(t, s, "member")
+
+	message, err := s.CreateMessage(member.SessionToken, "general", "hello hooks")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	if _, err := s.EditMessage(member.SessionToken, "general", message.ID, "edited"); err != nil {
+		t.Fatalf("edit message failed: %v", err)
+	}
+	// Close drains the queue, so everything that was enqueued has been delivered.
+	s.messageWebhook.Close()
+	close(received)
+
+	var events []ChannelEvent
+	for event := range received {
+		events = append(events, event)
+	}
+	if len(events) != 1 || events[0].Type != "message.created" || events[0].Message.ID != message.ID {
+		t.Fatalf("expected only the created event, got %+v", events)
+	}
+}
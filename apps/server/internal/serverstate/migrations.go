@@ -3,6 +3,7 @@ package serverstate
 import (
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
 	"sort"
@@ -13,7 +14,14 @@ import (
 //go:embed migrations/*.sql
 var migrationFS embed.FS
 
-func applyMigrations(db *sql.DB) error {
+// coreTables must exist once migrations have run. A build that embedded no
+// migrations would otherwise start against an empty schema and only fail on
+// the first query.
+var coreTables = []string{"server_identity", "invites", "members", "sessions", "messages"}
+
+var errSchemaMissing = errors.New("schema_missing")
+
+func applyMigrations(db *sql.DB, migrations fs.FS) error {
 	if _, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			name TEXT PRIMARY KEY,
@@ -23,7 +31,7 @@ func applyMigrations(db *sql.DB) error {
 		return fmt.Errorf("create schema_migrations table: %w", err)
 	}
 
-	entries, err := fs.ReadDir(migrationFS, "migrations")
+	entries, err := fs.ReadDir(migrations, "migrations")
 	if err != nil {
 		return fmt.Errorf("read migrations directory: %w", err)
 	}
@@ -63,7 +71,7 @@ func applyMigrations(db *sql.DB) error {
 			continue
 		}
 
-		script, err := migrationFS.ReadFile("migrations/" + name)
+		script, err := fs.ReadFile(migrations, "migrations/"+name)
 		if err != nil {
 			return fmt.Errorf("read migration %s: %w", name, err)
 		}
@@ -92,5 +100,19 @@ func applyMigrations(db *sql.DB) error {
 		}
 	}
 
+	return checkCoreTables(db, len(migrationNames))
+}
+
+func checkCoreTables(db *sql.DB, migrationCount int) error {
+	for _, table := range coreTables {
+		var name string
+		err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: table %q does not exist after %d migrations; check that migrations/*.sql is embedded in the build", errSchemaMissing, table, migrationCount)
+		}
+		if err != nil {
+			return fmt.Errorf("check table %s: %w", table, err)
+		}
+	}
 	return nil
 }
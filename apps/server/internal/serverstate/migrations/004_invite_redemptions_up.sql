@@ -0,0 +1,10 @@
+ALTER TABLE invites ADD COLUMN used_by_public_key TEXT;
+
+CREATE TABLE IF NOT EXISTS invite_redemptions (
+  invite_id TEXT NOT NULL,
+  client_public_key TEXT NOT NULL,
+  redeemed_at TEXT NOT NULL,
+  PRIMARY KEY (invite_id, client_public_key, redeemed_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invite_redemptions_client ON invite_redemptions(client_public_key);
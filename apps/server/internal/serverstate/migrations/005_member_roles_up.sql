@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS member_roles (
+  public_key TEXT NOT NULL,
+  role TEXT NOT NULL,
+  PRIMARY KEY (public_key, role)
+);
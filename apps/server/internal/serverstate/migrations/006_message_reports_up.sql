@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS message_reports (
+  message_id TEXT NOT NULL,
+  reporter_public_key TEXT NOT NULL,
+  channel_id TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  author_public_key TEXT NOT NULL,
+  author_name TEXT NOT NULL,
+  content_snapshot TEXT NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (message_id, reporter_public_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_reports_created_at ON message_reports(created_at);
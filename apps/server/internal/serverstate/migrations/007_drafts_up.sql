@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS drafts (
+  client_public_key TEXT NOT NULL,
+  channel_id TEXT NOT NULL,
+  content_markdown TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  PRIMARY KEY (client_public_key, channel_id)
+);
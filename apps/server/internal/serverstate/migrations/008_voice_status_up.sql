@@ -0,0 +1 @@
+ALTER TABLE voice_presence ADD COLUMN status TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS read_markers (
+  client_public_key TEXT NOT NULL,
+  channel_id TEXT NOT NULL,
+  message_id TEXT NOT NULL,
+  updated_at TEXT NOT NULL,
+  PRIMARY KEY (client_public_key, channel_id)
+);
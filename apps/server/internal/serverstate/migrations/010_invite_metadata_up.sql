@@ -0,0 +1 @@
+ALTER TABLE invites ADD COLUMN metadata TEXT;
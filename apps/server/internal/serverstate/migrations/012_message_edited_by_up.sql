@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN edited_by_public_key TEXT;
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN edit_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS motd_acks (
+  client_public_key TEXT PRIMARY KEY,
+  motd_updated_at TEXT NOT NULL,
+  acknowledged_at TEXT NOT NULL
+);
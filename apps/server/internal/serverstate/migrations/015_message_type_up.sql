@@ -0,0 +1,2 @@
+ALTER TABLE messages ADD COLUMN message_type TEXT NOT NULL DEFAULT 'normal';
+UPDATE messages SET message_type = 'system' WHERE author_public_key = 'system';
@@ -0,0 +1 @@
+ALTER TABLE invites ADD COLUMN admin_only INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,32 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestApplyMigrationsFailsOnEmptyMigrationSet(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "empty.db"))
+	if err != nil {
+		t.Fatalf("open database failed: %v", err)
+	}
+	defer db.Close()
+
+	empty := fstest.MapFS{"migrations": &fstest.MapFile{Mode: fs.ModeDir}}
+	err = applyMigrations(db, empty)
+	if !errors.Is(err, errSchemaMissing) {
+		t.Fatalf("expected schema_missing, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"server_identity"`) {
+		t.Fatalf("expected error to name the missing table, got %v", err)
+	}
+
+	if err := applyMigrations(db, migrationFS); err != nil {
+		t.Fatalf("embedded migrations should satisfy the schema check: %v", err)
+	}
+}
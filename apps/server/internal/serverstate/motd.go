@@ -0,0 +1,124 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxMOTDLength = 2000
+
+type MOTD struct {
+	Motd          string `json:"motd"`
+	MotdUpdatedAt string `json:"motdUpdatedAt"`
+}
+
+// MOTDStatus is the current MOTD as seen by one member.
+type MOTDStatus struct {
+	Motd          string `json:"motd"`
+	MotdUpdatedAt string `json:"motdUpdatedAt"`
+	MotdUnread    bool   `json:"motdUnread"`
+}
+
+type MOTDAck struct {
+	MotdUpdatedAt string `json:"motdUpdatedAt"`
+	MotdUnread    bool   `json:"motdUnread"`
+}
+
+// SetMOTD replaces the message of the day shown by clients on connect. An empty
+// motd clears it. motdUpdatedAt changes on every call so clients can show each
+// update once.
+func (s *State) SetMOTD(motd string) (MOTD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	motd = strings.TrimSpace(motd)
+	if len([]rune(motd)) > maxMOTDLength {
+		return MOTD{}, newAPIError(400, "invalid_motd", fmt.Sprintf("motd must be at most %d characters", maxMOTDLength))
+	}
+
+	updated := s.serverCfg
+	updated.Motd = motd
+	updated.MotdUpdatedAt = ""
+	if motd != "" {
+		// Sub-second precision keeps two updates within one second distinct.
+		updated.MotdUpdatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	if err := writeJSON(s.serverCfgPath, updated, 0o600); err != nil {
+		return MOTD{}, fmt.Errorf("persist server config: %w", err)
+	}
+	s.serverCfg = updated
+
+	return MOTD{Motd: updated.Motd, MotdUpdatedAt: updated.MotdUpdatedAt}, nil
+}
+
+// GetMOTD returns the current MOTD and whether the member has yet to
+// acknowledge it, so a client resuming a session can tell without reconnecting.
+func (s *State) GetMOTD(sessionToken string) (MOTDStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return MOTDStatus{}, err
+	}
+	unread, err := s.motdUnreadLocked(identity.PublicKey)
+	if err != nil {
+		return MOTDStatus{}, err
+	}
+	return MOTDStatus{Motd: s.serverCfg.Motd, MotdUpdatedAt: s.serverCfg.MotdUpdatedAt, MotdUnread: unread}, nil
+}
+
+// AcknowledgeMOTD records that the member has seen the MOTD version identified
+// by motdUpdatedAt, defaulting to the current one. Acknowledging an older version
+// leaves the current MOTD unread.
+func (s *State) AcknowledgeMOTD(sessionToken, motdUpdatedAt string) (MOTDAck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return MOTDAck{}, err
+	}
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return MOTDAck{}, err
+	}
+	if s.serverCfg.MotdUpdatedAt == "" {
+		return MOTDAck{}, newAPIError(404, "motd_not_set", "there is no message of the day")
+	}
+
+	motdUpdatedAt = strings.TrimSpace(motdUpdatedAt)
+	if motdUpdatedAt == "" {
+		motdUpdatedAt = s.serverCfg.MotdUpdatedAt
+	} else if _, err := time.Parse(time.RFC3339, motdUpdatedAt); err != nil {
+		return MOTDAck{}, newAPIError(400, "invalid_motd_updated_at", "motdUpdatedAt must be RFC3339")
+	}
+
+	if _, err := s.db.Exec(`
+		INSERT INTO motd_acks(client_public_key, motd_updated_at, acknowledged_at)
+		VALUES(?, ?, ?)
+		ON CONFLICT(client_public_key) DO UPDATE SET
+			motd_updated_at = excluded.motd_updated_at,
+			acknowledged_at = excluded.acknowledged_at
+	`, identity.PublicKey, motdUpdatedAt, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return MOTDAck{}, fmt.Errorf("upsert motd ack: %w", err)
+	}
+
+	return MOTDAck{MotdUpdatedAt: motdUpdatedAt, MotdUnread: motdUpdatedAt != s.serverCfg.MotdUpdatedAt}, nil
+}
+
+// motdUnreadLocked reports whether a MOTD is set that the member has not
+// acknowledged.
+func (s *State) motdUnreadLocked(publicKey string) (bool, error) {
+	if s.serverCfg.MotdUpdatedAt == "" {
+		return false, nil
+	}
+	var seen string
+	err := s.db.QueryRow(`SELECT motd_updated_at FROM motd_acks WHERE client_public_key = ?`, publicKey).Scan(&seen)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("query motd ack: %w", err)
+	}
+	return seen != s.serverCfg.MotdUpdatedAt, nil
+}
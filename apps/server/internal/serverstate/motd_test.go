@@ -0,0 +1,82 @@
+package serverstate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetMOTDPersistsAndClears(t *testing.T) {
+	s := newTestState(t, nil)
+
+	motd, err := s.SetMOTD("  Maintenance on Friday  ")
+	if err != nil {
+		t.Fatalf("set motd failed: %v", err)
+	}
+	if motd.Motd != "Maintenance on Friday" || motd.MotdUpdatedAt == "" {
+		t.Fatalf("unexpected motd: %+v", motd)
+	}
+	if info := s.ServerInfo(); info.Motd != motd.Motd || info.MotdUpdatedAt != motd.MotdUpdatedAt {
+		t.Fatalf("server info must expose the motd, got %+v", info)
+	}
+
+	reloaded, err := loadOrCreateServerConfig(s.serverCfgPath, "ignored")
+	if err != nil {
+		t.Fatalf("reload server config failed: %v", err)
+	}
+	if reloaded.Motd != motd.Motd {
+		t.Fatalf("motd must be persisted, got %q", reloaded.Motd)
+	}
+
+	_, err = s.SetMOTD(strings.Repeat("x", maxMOTDLength+1))
+	requireAPIErrorCode(t, err, "invalid_motd")
+
+	cleared, err := s.SetMOTD("")
+	if err != nil {
+		t.Fatalf("clear motd failed: %v", err)
+	}
+	if cleared.Motd != "" || cleared.MotdUpdatedAt != "" {
+		t.Fatalf("expected cleared motd, got %+v", cleared)
+	}
+}
+
+func TestAcknowledgeMOTDMarksCurrentVersionRead(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	_, err := s.AcknowledgeMOTD(member.SessionToken, "")
+	requireAPIErrorCode(t, err, "motd_not_set")
+
+	motd, err := s.SetMOTD("Welcome")
+	if err != nil {
+		t.Fatalf("set motd failed: %v", err)
+	}
+	if status, err := s.GetMOTD(member.SessionToken); err != nil || !status.MotdUnread || status.MotdUpdatedAt != motd.MotdUpdatedAt {
+		t.Fatalf("expected a new motd to be unread, got %+v, %v", status, err)
+	}
+
+	stale, err := s.AcknowledgeMOTD(member.SessionToken, "2000-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("ack failed: %v", err)
+	}
+	if !stale.MotdUnread {
+		t.Fatal("acknowledging an older motd must leave the current one unread")
+	}
+
+	ack, err := s.AcknowledgeMOTD(member.SessionToken, "")
+	if err != nil {
+		t.Fatalf("ack failed: %v", err)
+	}
+	if ack.MotdUpdatedAt != motd.MotdUpdatedAt || ack.MotdUnread {
+		t.Fatalf("unexpected ack: %+v", ack)
+	}
+	if status, err := s.GetMOTD(member.SessionToken); err != nil || status.MotdUnread || status.Motd != "Welcome" {
+		t.Fatalf("expected acknowledged motd to be read, got %+v, %v", status, err)
+	}
+
+	if _, err := s.SetMOTD("Maintenance tonight"); err != nil {
+		t.Fatalf("set motd failed: %v", err)
+	}
+	if status, err := s.GetMOTD(member.SessionToken); err != nil || !status.MotdUnread || status.Motd != "Maintenance tonight" {
+		t.Fatalf("expected an updated motd to be unread again, got %+v, %v", status, err)
+	}
+}
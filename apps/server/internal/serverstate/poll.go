@@ -0,0 +1,69 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ListMessagesSince returns messages posted to the channel after sinceMessageID,
+// oldest first. Insertion order (rowid) is used instead of created_at so messages
+// sharing a second are not skipped. When sinceMessageID no longer exists, for
+// example because it was deleted, reset is true and the caller must resync
+// from history instead.
+func (s *State) ListMessagesSince(sessionToken, channelID, sinceMessageID string) (messages []ChannelMessage, reset bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return nil, false, err
+	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return nil, false, err
+	}
+
+	sinceMessageID = strings.TrimSpace(sinceMessageID)
+	if sinceMessageID == "" {
+		return []ChannelMessage{}, false, nil
+	}
+
+	var sinceRowID int64
+	err = s.db.QueryRow(`SELECT rowid FROM messages WHERE id = ? AND channel_id = ?`, sinceMessageID, channelID).Scan(&sinceRowID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []ChannelMessage{}, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("query since message: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, channel_id, author_public_key, author_name, content_markdown, content_encrypted, edited_by_public_key, edit_count, message_type, created_at, updated_at
+		FROM messages
+		WHERE channel_id = ? AND rowid > ?
+		ORDER BY rowid ASC
+		LIMIT ?
+	`, channelID, sinceRowID, maxMessageHistoryLimit)
+	if err != nil {
+		return nil, false, fmt.Errorf("query messages since: %w", err)
+	}
+	defer rows.Close()
+
+	messages = make([]ChannelMessage, 0)
+	for rows.Next() {
+		message, err := scanMessageRow(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("iterate message rows: %w", err)
+	}
+
+	return messages, false, nil
+}
@@ -0,0 +1,33 @@
+package serverstate
+
+import "testing"
+
+func TestListMessagesSinceResetsForDeletedCursor(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	first, err := s.CreateMessage(member.SessionToken, "general", "first")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	second, err := s.CreateMessage(member.SessionToken, "general", "second")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	messages, reset, err := s.ListMessagesSince(member.SessionToken, "general", first.ID)
+	if err != nil || reset || len(messages) != 1 || messages[0].ID != second.ID {
+		t.Fatalf("unexpected messages since first: %v %v %+v", err, reset, messages)
+	}
+
+	if err := s.DeleteMessage(member.SessionToken, "general", first.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	messages, reset, err = s.ListMessagesSince(member.SessionToken, "general", first.ID)
+	if err != nil {
+		t.Fatalf("deleted cursor must not fail: %v", err)
+	}
+	if !reset || len(messages) != 0 {
+		t.Fatalf("expected a reset for the deleted cursor, got %v %+v", reset, messages)
+	}
+}
@@ -0,0 +1,35 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+)
+
+// ServerPublicKey returns the server's ed25519 public key in format: base64
+// (as in server info), hex, or a PEM "PUBLIC KEY" block holding the PKIX
+// encoding that OpenSSL and most verification tooling read.
+func (s *State) ServerPublicKey(format string) (string, error) {
+	publicKey := s.serverPrivateKey.Public().(ed25519.PublicKey)
+	return encodePublicKey(publicKey, format)
+}
+
+func encodePublicKey(publicKey ed25519.PublicKey, format string) (string, error) {
+	switch format {
+	case "", "base64":
+		return base64.StdEncoding.EncodeToString(publicKey), nil
+	case "hex":
+		return hex.EncodeToString(publicKey), nil
+	case "pem":
+		der, err := x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			return "", fmt.Errorf("marshal public key: %w", err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+	default:
+		return "", newAPIError(400, "invalid_format", "format must be base64, hex or pem")
+	}
+}
@@ -0,0 +1,49 @@
+package serverstate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+)
+
+func TestServerPublicKeyFormats(t *testing.T) {
+	s := newTestState(t, nil)
+	want, err := base64.StdEncoding.DecodeString(s.ServerInfo().ServerPublicKey)
+	if err != nil {
+		t.Fatalf("decode server info key failed: %v", err)
+	}
+
+	decoders := map[string]func(string) ([]byte, error){
+		"":       base64.StdEncoding.DecodeString,
+		"base64": base64.StdEncoding.DecodeString,
+		"hex":    hex.DecodeString,
+		"pem": func(encoded string) ([]byte, error) {
+			block, _ := pem.Decode([]byte(encoded))
+			if block == nil || block.Type != "PUBLIC KEY" {
+				t.Fatalf("expected a PUBLIC KEY block, got %q", encoded)
+			}
+			parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			return parsed.(ed25519.PublicKey), nil
+		},
+	}
+	for format, decode := range decoders {
+		encoded, err := s.ServerPublicKey(format)
+		if err != nil {
+			t.Fatalf("%q: encode failed: %v", format, err)
+		}
+		got, err := decode(encoded)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("%q: unexpected key %q: %v", format, encoded, err)
+		}
+	}
+
+	_, err = s.ServerPublicKey("der")
+	requireAPIErrorCode(t, err, "invalid_format")
+}
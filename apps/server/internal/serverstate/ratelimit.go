@@ -0,0 +1,25 @@
+package serverstate
+
+import "time"
+
+// RateLimit describes the throttle window that applied to a request. Throttled
+// routes return it so the HTTP layer can emit X-RateLimit-* headers on both
+// successful and rejected responses.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// ChannelRateLimit reports the member's slow mode window in the channel, or nil
+// when the channel is not throttled for them.
+func (s *State) ChannelRateLimit(sessionToken, channelID string) (*RateLimit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.slowModeRateLimitLocked(identity, channelID, time.Now().UTC()), nil
+}
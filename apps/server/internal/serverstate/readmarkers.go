@@ -0,0 +1,81 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type ReadMarker struct {
+	ChannelID string `json:"channelId"`
+	MessageID string `json:"messageId"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// MarkAllChannelsRead moves the member's read marker in every readable text
+// channel to its latest message. Each channel's latest message is read through
+// the per-channel created_at index and the markers are written in one
+// transaction; channels without messages are skipped.
+func (s *State) MarkAllChannelsRead(sessionToken string) ([]ReadMarker, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return nil, err
+	}
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := s.visibleChannelsLocked(identity.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.db.Prepare(`SELECT id FROM messages WHERE channel_id = ? ORDER BY created_at DESC, rowid DESC LIMIT 1`)
+	if err != nil {
+		return nil, fmt.Errorf("prepare latest message query: %w", err)
+	}
+	defer latest.Close()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	markers := make([]ReadMarker, 0, len(channels))
+	for _, channel := range channels {
+		if channel.Type != "text" {
+			continue
+		}
+		marker := ReadMarker{ChannelID: channel.ID, UpdatedAt: now}
+		err := latest.QueryRow(channel.ID).Scan(&marker.MessageID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query latest message: %w", err)
+		}
+		markers = append(markers, marker)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin read markers tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, marker := range markers {
+		if _, err := tx.Exec(`
+			INSERT INTO read_markers(client_public_key, channel_id, message_id, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(client_public_key, channel_id) DO UPDATE SET
+				message_id = excluded.message_id,
+				updated_at = excluded.updated_at
+		`, identity.PublicKey, marker.ChannelID, marker.MessageID, marker.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("upsert read marker: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit read markers tx: %w", err)
+	}
+
+	return markers, nil
+}
@@ -0,0 +1,36 @@
+package serverstate
+
+import "testing"
+
+func TestMarkAllChannelsReadUsesLatestReadableMessage(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	other := connectTestMember(t, s, "other")
+
+	if _, err := s.CreateMessage(other.SessionToken, "general", "first"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	latest, err := s.CreateMessage(other.SessionToken, "general", "second")
+	if err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+
+	markers, err := s.MarkAllChannelsRead(member.SessionToken)
+	if err != nil {
+		t.Fatalf("mark all read failed: %v", err)
+	}
+	if len(markers) != 1 || markers[0].ChannelID != "general" || markers[0].MessageID != latest.ID {
+		t.Fatalf("expected marker on latest general message, got %+v", markers)
+	}
+
+	if _, err := s.UpdateChannel("general", ChannelUpdate{ReadRoles: &[]string{"staff"}}); err != nil {
+		t.Fatalf("failed to restrict channel: %v", err)
+	}
+	markers, err = s.MarkAllChannelsRead(member.SessionToken)
+	if err != nil {
+		t.Fatalf("mark all read failed: %v", err)
+	}
+	if len(markers) != 0 {
+		t.Fatalf("unreadable channels must be skipped, got %+v", markers)
+	}
+}
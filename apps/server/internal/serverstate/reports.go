@@ -0,0 +1,144 @@
+package serverstate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	maxReportReasonLength  = 500
+	defaultReportListLimit = 50
+	maxReportListLimit     = 200
+)
+
+type MessageReport struct {
+	MessageID         string        `json:"messageId"`
+	ChannelID         string        `json:"channelId"`
+	ReporterPublicKey string        `json:"reporterPublicKey"`
+	Reason            string        `json:"reason"`
+	Author            MessageAuthor `json:"author"`
+	ContentSnapshot   string        `json:"contentSnapshot"`
+	CreatedAt         string        `json:"createdAt"`
+}
+
+type ReportMessageResult struct {
+	Status string `json:"status"`
+}
+
+type ListReportsResult struct {
+	Reports []MessageReport `json:"reports"`
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+}
+
+// ReportMessage flags a message for admin review. The content is snapshotted so
+// later edits do not hide what was reported; repeat reports by the same member
+// are ignored.
+func (s *State) ReportMessage(sessionToken, channelID, messageID, reason string) (ReportMessageResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return ReportMessageResult{}, err
+	}
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return ReportMessageResult{}, err
+	}
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return ReportMessageResult{}, err
+	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return ReportMessageResult{}, err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxReportReasonLength {
+		return ReportMessageResult{}, newAPIError(400, "invalid_reason", fmt.Sprintf("reason must be at most %d characters", maxReportReasonLength))
+	}
+
+	message, err := s.findMessageLocked(channelID, messageID)
+	if err != nil {
+		return ReportMessageResult{}, err
+	}
+
+	if _, err := s.db.Exec(`
+		INSERT OR IGNORE INTO message_reports(
+			message_id, reporter_public_key, channel_id, reason,
+			author_public_key, author_name, content_snapshot, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		message.ID,
+		identity.PublicKey,
+		message.ChannelID,
+		reason,
+		message.Author.PublicKey,
+		message.Author.DisplayName,
+		message.ContentMarkdown,
+		time.Now().UTC().Format(time.RFC3339),
+	); err != nil {
+		return ReportMessageResult{}, fmt.Errorf("insert message report: %w", err)
+	}
+
+	return ReportMessageResult{Status: "reported"}, nil
+}
+
+// ListReports returns a page of message reports, newest first.
+func (s *State) ListReports(limit, offset int) (ListReportsResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit <= 0 || limit > maxReportListLimit {
+		limit = defaultReportListLimit
+	}
+	if offset < 0 {
+		return ListReportsResult{}, newAPIError(400, "invalid_offset", "offset must not be negative")
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM message_reports`).Scan(&total); err != nil {
+		return ListReportsResult{}, fmt.Errorf("count message reports: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT message_id, channel_id, reporter_public_key, reason, author_public_key, author_name, content_snapshot, created_at
+		FROM message_reports
+		ORDER BY created_at DESC, message_id ASC, reporter_public_key ASC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return ListReportsResult{}, fmt.Errorf("query message reports: %w", err)
+	}
+	defer rows.Close()
+
+	result := ListReportsResult{
+		Reports: []MessageReport{},
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}
+	for rows.Next() {
+		var report MessageReport
+		if err := rows.Scan(
+			&report.MessageID,
+			&report.ChannelID,
+			&report.ReporterPublicKey,
+			&report.Reason,
+			&report.Author.PublicKey,
+			&report.Author.DisplayName,
+			&report.ContentSnapshot,
+			&report.CreatedAt,
+		); err != nil {
+			return ListReportsResult{}, fmt.Errorf("scan message report row: %w", err)
+		}
+		result.Reports = append(result.Reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return ListReportsResult{}, fmt.Errorf("iterate message report rows: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package serverstate
+
+import "fmt"
+
+// insertMessageLocked stores a new message and, with MAX_MESSAGES_PER_CHANNEL,
+// evicts the channel's oldest messages beyond the cap in the same transaction.
+// It returns the ids of evicted messages so callers can announce them.
+func (s *State) insertMessageLocked(message ChannelMessage) ([]string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin message insert: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	body := messageBody{markdown: message.ContentMarkdown, encrypted: message.ContentEncrypted}
+	if _, err := tx.Exec(`
+		INSERT INTO messages(id, channel_id, author_public_key, author_name, content_markdown, content_encrypted, message_type, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, message.ID, message.ChannelID, message.Author.PublicKey, message.Author.DisplayName, body.markdown, body.encryptedValue(), message.MessageType, message.CreatedAt, message.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("insert message: %w", err)
+	}
+
+	var evicted []string
+	if limit := s.cfg.MaxMessagesPerChannel; limit > 0 {
+		// Only the overflow is selected: normally the single message pushed out
+		// by this insert.
+		const overflow = `SELECT id FROM messages WHERE channel_id = ? ORDER BY created_at DESC, rowid DESC LIMIT -1 OFFSET ?`
+		rows, err := tx.Query(overflow, message.ChannelID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("query overflow messages: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan overflow message: %w", err)
+			}
+			evicted = append(evicted, id)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("iterate overflow messages: %w", err)
+		}
+		rows.Close()
+
+		if len(evicted) > 0 {
+			if _, err := deleteMessagesTx(tx, `id IN (`+overflow+`)`, message.ChannelID, limit); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit message insert: %w", err)
+	}
+	return evicted, nil
+}
+
+// broadcastEvictedLocked announces messages removed by the per-channel cap so
+// open clients can drop them without refetching.
+func (s *State) broadcastEvictedLocked(channelID string, messageIDs []string) {
+	for _, messageID := range messageIDs {
+		s.broadcastChannelEventLocked(channelID, messageDeletedEvent(channelID, messageID))
+	}
+}
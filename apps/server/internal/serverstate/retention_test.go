@@ -0,0 +1,45 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestMaxMessagesPerChannelEvictsOldest(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.MaxMessagesPerChannel = 2 })
+	member := connectTestMember(t, s, "member")
+
+	events, cancel, err := s.SubscribeChannelEvents(member.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	var ids []string
+	for _, content := range []string{"first", "second", "third"} {
+		message, err := s.CreateMessage(member.SessionToken, "general", content)
+		if err != nil {
+			t.Fatalf("create message failed: %v", err)
+		}
+		ids = append(ids, message.ID)
+	}
+
+	result, err := s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	if len(result.Messages) != 2 || result.Messages[0].ID != ids[1] || result.Messages[1].ID != ids[2] {
+		t.Fatalf("expected the two newest messages to remain, got %+v", result.Messages)
+	}
+
+	var deleted []string
+	for len(events) > 0 {
+		if event := <-events; event.Type == "message.deleted" {
+			deleted = append(deleted, event.MessageID)
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != ids[0] {
+		t.Fatalf("expected a deletion event for the oldest message, got %v", deleted)
+	}
+}
@@ -0,0 +1,207 @@
+package serverstate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxRolesPerList = 32
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// SetMemberRoles replaces the member's role set. Roles gate access to channels
+// that declare readRoles/writeRoles; channels without them are open to everyone.
+func (s *State) SetMemberRoles(publicKey string, roles []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return nil, err
+	}
+
+	publicKey = strings.TrimSpace(publicKey)
+	if _, err := s.findMemberLocked(publicKey); err != nil {
+		return nil, err
+	}
+
+	normalized, err := normalizeRoles(roles)
+	if err != nil {
+		return nil, newAPIError(400, "invalid_roles", err.Error())
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin member roles tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM member_roles WHERE public_key = ?`, publicKey); err != nil {
+		return nil, fmt.Errorf("clear member roles: %w", err)
+	}
+	for _, role := range normalized {
+		if _, err := tx.Exec(`INSERT INTO member_roles(public_key, role) VALUES (?, ?)`, publicKey, role); err != nil {
+			return nil, fmt.Errorf("insert member role: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit member roles tx: %w", err)
+	}
+
+	return normalized, nil
+}
+
+func (s *State) memberRolesLocked(publicKey string) (map[string]bool, error) {
+	rows, err := s.db.Query(`SELECT role FROM member_roles WHERE public_key = ?`, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("query member roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make(map[string]bool)
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("scan member role: %w", err)
+		}
+		roles[role] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate member roles: %w", err)
+	}
+	return roles, nil
+}
+
+// ChannelPermission names an action scoped to a single channel.
+type ChannelPermission string
+
+const (
+	PermissionManageChannel    ChannelPermission = "manage_channel"
+	PermissionModerateMessages ChannelPermission = "moderate_messages"
+)
+
+// hasChannelPermissionLocked reports whether the member may perform perm in the
+// channel. Server admins hold every permission everywhere; members holding one
+// of the channel's adminRoles hold them within that channel only. Failing to
+// load roles denies the permission.
+func (s *State) hasChannelPermissionLocked(publicKey, channelID string, perm ChannelPermission) bool {
+	if publicKey == "" {
+		return false
+	}
+	if s.isAdminPublicKeyLocked(publicKey) {
+		return true
+	}
+	switch perm {
+	case PermissionManageChannel, PermissionModerateMessages:
+	default:
+		return false
+	}
+
+	channel, ok := s.channelLocked(channelID)
+	if !ok || len(channel.AdminRoles) == 0 {
+		return false
+	}
+	roles, err := s.memberRolesLocked(publicKey)
+	if err != nil {
+		return false
+	}
+	return hasAnyRole(roles, channel.AdminRoles)
+}
+
+// ensureChannelAccessLocked returns channel_forbidden when the member lacks the
+// read (or, with write set, write) roles of the channel. Server and channel
+// admins bypass the check.
+func (s *State) ensureChannelAccessLocked(identity SessionIdentity, channelID string, write bool) error {
+	channel, ok := s.channelLocked(channelID)
+	if !ok {
+		return newAPIError(404, "channel_not_found", "channel does not exist")
+	}
+	if len(channel.ReadRoles) == 0 && (!write || len(channel.WriteRoles) == 0) {
+		return nil
+	}
+	if s.isAdminPublicKeyLocked(identity.PublicKey) {
+		return nil
+	}
+
+	roles, err := s.memberRolesLocked(identity.PublicKey)
+	if err != nil {
+		return err
+	}
+	if len(channel.AdminRoles) > 0 && hasAnyRole(roles, channel.AdminRoles) {
+		return nil
+	}
+	if !hasAnyRole(roles, channel.ReadRoles) {
+		return newAPIError(403, "channel_forbidden", "you do not have access to this channel")
+	}
+	if write && !hasAnyRole(roles, channel.WriteRoles) {
+		return newAPIError(403, "channel_forbidden", "you cannot post in this channel")
+	}
+	return nil
+}
+
+// visibleChannelsLocked lists the channels the member may read. An empty
+// publicKey stands for an anonymous client without roles.
+func (s *State) visibleChannelsLocked(publicKey string) ([]Channel, error) {
+	isAdmin := publicKey != "" && s.isAdminPublicKeyLocked(publicKey)
+
+	var roles map[string]bool
+	if publicKey != "" && !isAdmin {
+		var err error
+		if roles, err = s.memberRolesLocked(publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	channels := make([]Channel, 0, len(s.serverCfg.Channels))
+	for _, channel := range s.serverCfg.Channels {
+		channelAdmin := len(channel.AdminRoles) > 0 && hasAnyRole(roles, channel.AdminRoles)
+		if !isAdmin && !channelAdmin && !hasAnyRole(roles, channel.ReadRoles) {
+			continue
+		}
+		channel.PublicPreview = s.publicPreviewEnabledLocked(channel.ID)
+		if channel.Type == "text" {
+			channel.MaxMessageLength = messageLengthLimit(channel)
+			typing := typingIndicatorsEnabled(channel)
+			channel.TypingIndicators = &typing
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// hasAnyRole reports whether roles grant access to a channel role list; an
+// empty list grants access to everyone.
+func hasAnyRole(roles map[string]bool, required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	for _, role := range required {
+		if roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeRoles(roles []string) ([]string, error) {
+	if len(roles) > maxRolesPerList {
+		return nil, fmt.Errorf("at most %d roles are allowed", maxRolesPerList)
+	}
+
+	seen := make(map[string]struct{}, len(roles))
+	result := make([]string, 0, len(roles))
+	for _, role := range roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !roleNamePattern.MatchString(role) {
+			return nil, fmt.Errorf("invalid role name %q", role)
+		}
+		if _, exists := seen[role]; exists {
+			continue
+		}
+		seen[role] = struct{}{}
+		result = append(result, role)
+	}
+	sort.Strings(result)
+	return result, nil
+}
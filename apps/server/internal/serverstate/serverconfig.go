@@ -0,0 +1,262 @@
+package serverstate
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	configExportVersion = 1
+	maxServerNameLength = 100
+)
+
+// ConfigExport is the portable form of a server's configuration. It carries the
+// contents of server_config.json plus read-only settings describing the running
+// server; secrets such as the identity private key and admin token are never included.
+type ConfigExport struct {
+	Version         int            `json:"version"`
+	ExportedAt      string         `json:"exportedAt,omitempty"`
+	ServerName      string         `json:"serverName"`
+	Channels        []Channel      `json:"channels"`
+	AdminPublicKeys []string       `json:"adminPublicKeys"`
+	Settings        ConfigSettings `json:"settings"`
+}
+
+type ConfigSettings struct {
+	ServerID            string `json:"serverId"`
+	ServerFingerprint   string `json:"serverFingerprint"`
+	ServerPublicKey     string `json:"serverPublicKey"`
+	ServerPublicBaseURL string `json:"serverPublicBaseUrl"`
+	LiveKitURL          string `json:"livekitUrl"`
+	LiveKitPublicURL    string `json:"livekitPublicUrl"`
+}
+
+func (s *State) ExportConfig() ConfigExport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.configExportLocked()
+}
+
+// ImportConfig validates and applies the server name, channels and admin keys from
+// an exported document. Settings are informational and ignored on import.
+func (s *State) ImportConfig(doc ConfigExport) (ConfigExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc.Version != 0 && doc.Version != configExportVersion {
+		return ConfigExport{}, newAPIError(400, "invalid_config", fmt.Sprintf("unsupported config export version %d", doc.Version))
+	}
+
+	imported := s.serverCfg
+	imported.ServerName = doc.ServerName
+	imported.Channels = doc.Channels
+	imported.AdminPublicKeys = doc.AdminPublicKeys
+
+	normalized, err := normalizeServerConfig(imported)
+	if err != nil {
+		return ConfigExport{}, newAPIError(400, "invalid_config", err.Error())
+	}
+
+	if err := writeJSON(s.serverCfgPath, normalized, 0o600); err != nil {
+		return ConfigExport{}, fmt.Errorf("persist server config: %w", err)
+	}
+	s.serverCfg = normalized
+
+	return s.configExportLocked(), nil
+}
+
+func (s *State) configExportLocked() ConfigExport {
+	channels := make([]Channel, len(s.serverCfg.Channels))
+	copy(channels, s.serverCfg.Channels)
+
+	admins := make([]string, len(s.serverCfg.AdminPublicKeys))
+	copy(admins, s.serverCfg.AdminPublicKeys)
+
+	return ConfigExport{
+		Version:         configExportVersion,
+		ExportedAt:      time.Now().UTC().Format(time.RFC3339),
+		ServerName:      s.serverCfg.ServerName,
+		Channels:        channels,
+		AdminPublicKeys: admins,
+		Settings: ConfigSettings{
+			ServerID:            s.serverID,
+			ServerFingerprint:   s.serverFingerprint,
+			ServerPublicKey:     s.serverPublicKey,
+			ServerPublicBaseURL: strings.TrimRight(s.cfg.ServerPublicBaseURL, "/"),
+			LiveKitURL:          s.cfg.LiveKitURL,
+			LiveKitPublicURL:    s.cfg.LiveKitPublicURL,
+		},
+	}
+}
+
+// UpdateServerName renames the server. The new name is persisted to
+// server_config.json, shows up in server info and connect results right away,
+// and is pushed to every open channel stream as a server.updated event.
+func (s *State) UpdateServerName(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", newAPIError(400, "invalid_server_name", "name is required")
+	}
+	if utf8.RuneCountInString(name) > maxServerNameLength {
+		return "", newAPIError(400, "invalid_server_name", fmt.Sprintf("name must be at most %d characters", maxServerNameLength))
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", newAPIError(400, "invalid_server_name", "name must not contain control characters")
+	}
+
+	updated := s.serverCfg
+	updated.ServerName = name
+	if err := writeJSON(s.serverCfgPath, updated, 0o600); err != nil {
+		return "", fmt.Errorf("persist server config: %w", err)
+	}
+	s.serverCfg = updated
+
+	s.broadcastServerEventLocked(ChannelEvent{Type: "server.updated", ServerName: name})
+	return name, nil
+}
+
+// broadcastServerEventLocked delivers a server-wide event to every channel
+// stream. Clients subscribed to several channels receive it once per stream.
+func (s *State) broadcastServerEventLocked(event ChannelEvent) {
+	for channelID, channelStreams := range s.streams {
+		for _, stream := range channelStreams {
+			select {
+			case stream <- event:
+			default:
+				s.streamDrops[channelID]++
+				slog.Debug("dropped server event for slow subscriber", "channel_id", channelID, "event_type", event.Type)
+			}
+		}
+	}
+}
+
+func normalizeServerConfig(cfg serverConfigFile) (serverConfigFile, error) {
+	cfg.ServerName = strings.TrimSpace(cfg.ServerName)
+	if cfg.ServerName == "" {
+		return serverConfigFile{}, errors.New("serverName is required")
+	}
+
+	channels, err := normalizeChannels(cfg.Channels)
+	if err != nil {
+		return serverConfigFile{}, err
+	}
+	cfg.Channels = channels
+
+	admins, err := normalizePublicKeys(cfg.AdminPublicKeys)
+	if err != nil {
+		return serverConfigFile{}, fmt.Errorf("invalid adminPublicKeys: %w", err)
+	}
+	cfg.AdminPublicKeys = admins
+
+	return cfg, nil
+}
+
+// repairLegacyChannels fixes the channel values that servers before strict
+// validation accepted in server_config.json, so those files still load. Each
+// repair is logged; settings added since are left to normalizeChannels.
+func repairLegacyChannels(channels []Channel) []Channel {
+	seen := make(map[string]struct{}, len(channels))
+	result := make([]Channel, 0, len(channels))
+	for _, channel := range channels {
+		channel.ID = strings.TrimSpace(channel.ID)
+		if channel.ID == "" {
+			slog.Warn("ignoring server config channel without id", "name", channel.Name)
+			continue
+		}
+		if _, exists := seen[channel.ID]; exists {
+			slog.Warn("ignoring duplicate server config channel", "channel_id", channel.ID)
+			continue
+		}
+		if channel.Type != "text" && channel.Type != "voice" {
+			slog.Warn("ignoring server config channel with unknown type", "channel_id", channel.ID, "type", channel.Type)
+			continue
+		}
+		seen[channel.ID] = struct{}{}
+
+		if _, err := normalizeChannelName(channel.Name); err != nil {
+			repaired := strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) {
+					return -1
+				}
+				return r
+			}, strings.TrimSpace(channel.Name))
+			if repaired == "" {
+				repaired = channel.ID
+			}
+			if runes := []rune(repaired); len(runes) > maxChannelNameLength {
+				repaired = strings.TrimSpace(string(runes[:maxChannelNameLength]))
+			}
+			slog.Warn("repaired server config channel name", "channel_id", channel.ID, "reason", err.Error(), "name", repaired)
+			channel.Name = repaired
+		}
+		result = append(result, channel)
+	}
+	return result
+}
+
+func normalizeChannels(channels []Channel) ([]Channel, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("at least one channel is required")
+	}
+
+	seen := make(map[string]struct{}, len(channels))
+	rooms := make(map[string]string, len(channels))
+	result := make([]Channel, 0, len(channels))
+	for _, channel := range channels {
+		channel.ID = strings.TrimSpace(channel.ID)
+		if channel.ID == "" {
+			return nil, errors.New("channel id is required")
+		}
+		if _, exists := seen[channel.ID]; exists {
+			return nil, fmt.Errorf("duplicate channel id %q", channel.ID)
+		}
+		seen[channel.ID] = struct{}{}
+
+		if channel.Type != "text" && channel.Type != "voice" {
+			return nil, fmt.Errorf("channel %q has invalid type %q", channel.ID, channel.Type)
+		}
+		if channel.Type == "voice" {
+			room := sanitizeRoomNamePart(channel.ID)
+			if other, exists := rooms[room]; exists {
+				return nil, fmt.Errorf("voice channels %q and %q would share LiveKit room %q", other, channel.ID, room)
+			}
+			rooms[room] = channel.ID
+		}
+		var err error
+		if channel.Name, err = normalizeChannelName(channel.Name); err != nil {
+			return nil, fmt.Errorf("channel %q: %w", channel.ID, err)
+		}
+		if channel.Topic, err = normalizeChannelTopic(channel.Topic); err != nil {
+			return nil, fmt.Errorf("channel %q: %w", channel.ID, err)
+		}
+		if err := validateSlowModeSeconds(channel.SlowModeSeconds); err != nil {
+			return nil, fmt.Errorf("channel %q: %w", channel.ID, err)
+		}
+		if err := validateMaxMessageLength(channel.MaxMessageLength); err != nil {
+			return nil, fmt.Errorf("channel %q: %w", channel.ID, err)
+		}
+		if channel.ReadRoles, err = normalizeRoles(channel.ReadRoles); err != nil {
+			return nil, fmt.Errorf("channel %q readRoles: %w", channel.ID, err)
+		}
+		if channel.WriteRoles, err = normalizeRoles(channel.WriteRoles); err != nil {
+			return nil, fmt.Errorf("channel %q writeRoles: %w", channel.ID, err)
+		}
+		if channel.AdminRoles, err = normalizeRoles(channel.AdminRoles); err != nil {
+			return nil, fmt.Errorf("channel %q adminRoles: %w", channel.ID, err)
+		}
+		if err := validateChannelFlags(channel); err != nil {
+			return nil, fmt.Errorf("channel %q: %w", channel.ID, err)
+		}
+		result = append(result, channel)
+	}
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package serverstate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportConfigRoundTripsExport(t *testing.T) {
+	s := newTestState(t, nil)
+	admin := connectTestMember(t, s, "admin")
+
+	doc := s.ExportConfig()
+	doc.ServerName = "Imported Server"
+	doc.AdminPublicKeys = []string{admin.PublicKey, admin.PublicKey}
+	doc.Channels = append(doc.Channels, Channel{ID: "random", Type: "text", Name: "random"})
+
+	imported, err := s.ImportConfig(doc)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if imported.ServerName != "Imported Server" {
+		t.Fatalf("unexpected server name: %q", imported.ServerName)
+	}
+	if len(imported.AdminPublicKeys) != 1 || imported.AdminPublicKeys[0] != admin.PublicKey {
+		t.Fatalf("expected deduplicated admin keys, got %v", imported.AdminPublicKeys)
+	}
+
+	var persisted serverConfigFile
+	if err := readJSON(s.serverCfgPath, &persisted); err != nil {
+		t.Fatalf("failed to read persisted config: %v", err)
+	}
+	if len(persisted.Channels) != len(doc.Channels) {
+		t.Fatalf("expected %d persisted channels, got %d", len(doc.Channels), len(persisted.Channels))
+	}
+}
+
+func TestLoadServerConfigRepairsLegacyChannels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server_config.json")
+	legacy := `{
+		"serverName": "Legacy",
+		"channels": [
+			{"id": " general ", "type": "text", "name": ""},
+			{"id": "general", "type": "text", "name": "duplicate"},
+			{"id": "stage", "type": "stage", "name": "stage"},
+			{"id": "voice", "type": "voice", "name": "` + strings.Repeat("v", maxChannelNameLength+10) + `"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("write server config failed: %v", err)
+	}
+
+	cfg, err := loadOrCreateServerConfig(path, "")
+	if err != nil {
+		t.Fatalf("legacy server config must still load: %v", err)
+	}
+	if len(cfg.Channels) != 2 {
+		t.Fatalf("expected duplicate and unknown channels to be dropped, got %+v", cfg.Channels)
+	}
+	if cfg.Channels[0].ID != "general" || cfg.Channels[0].Name != "general" {
+		t.Fatalf("expected trimmed id and name defaulting to it, got %+v", cfg.Channels[0])
+	}
+	if len([]rune(cfg.Channels[1].Name)) != maxChannelNameLength {
+		t.Fatalf("expected long name to be truncated, got %q", cfg.Channels[1].Name)
+	}
+}
+
+func TestImportConfigRejectsInvalidDocuments(t *testing.T) {
+	s := newTestState(t, nil)
+	original := s.ExportConfig()
+
+	cases := map[string]func(*ConfigExport){
+		"duplicate channel": func(doc *ConfigExport) {
+			doc.Channels = append(doc.Channels, doc.Channels[0])
+		},
+		"no channels": func(doc *ConfigExport) {
+			doc.Channels = nil
+		},
+		"bad channel type": func(doc *ConfigExport) {
+			doc.Channels = []Channel{{ID: "x", Type: "video", Name: "x"}}
+		},
+		"colliding voice rooms": func(doc *ConfigExport) {
+			doc.Channels = append(doc.Channels, Channel{ID: "lobby.a", Type: "voice", Name: "a"}, Channel{ID: "lobby-a", Type: "voice", Name: "b"})
+		},
+		"bad admin key": func(doc *ConfigExport) {
+			doc.AdminPublicKeys = []string{"not-a-key"}
+		},
+		"empty name": func(doc *ConfigExport) {
+			doc.ServerName = "  "
+		},
+		"control character in channel name": func(doc *ConfigExport) {
+			doc.Channels[0].Name = "gen\x00eral"
+		},
+		"overlong channel topic": func(doc *ConfigExport) {
+			doc.Channels[0].Topic = strings.Repeat("t", maxChannelTopicLength+1)
+		},
+	}
+
+	for name, mutate := range cases {
+		doc := s.ExportConfig()
+		mutate(&doc)
+		_, err := s.ImportConfig(doc)
+		requireAPIErrorCode(t, err, "invalid_config")
+		if got := s.ExportConfig(); got.ServerName != original.ServerName || len(got.Channels) != len(original.Channels) {
+			t.Fatalf("%s: rejected import must not modify config", name)
+		}
+	}
+}
+
+func TestUpdateServerNamePersistsAndBroadcasts(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+
+	events, cancel, err := s.SubscribeChannelEvents(member.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	name, err := s.UpdateServerName("  Renamed Server ")
+	if err != nil || name != "Renamed Server" {
+		t.Fatalf("rename failed: %q, %v", name, err)
+	}
+	if event := <-events; event.Type != "server.updated" || event.ServerName != "Renamed Server" {
+		t.Fatalf("expected server.updated event, got %+v", event)
+	}
+	if info := s.ServerInfo(); info.Name != "Renamed Server" {
+		t.Fatalf("server info not updated: %q", info.Name)
+	}
+
+	reloaded, err := loadOrCreateServerConfig(s.serverCfgPath, "fallback")
+	if err != nil || reloaded.ServerName != "Renamed Server" {
+		t.Fatalf("rename not persisted: %+v, %v", reloaded, err)
+	}
+
+	_, err = s.UpdateServerName(" ")
+	requireAPIErrorCode(t, err, "invalid_server_name")
+	_, err = s.UpdateServerName(strings.Repeat("n", maxServerNameLength+1))
+	requireAPIErrorCode(t, err, "invalid_server_name")
+}
@@ -0,0 +1,55 @@
+package serverstate
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// startSessionSweeper deletes expired sessions every interval, so an idle server
+// does not keep dead rows until the next authenticated request cleans them up.
+func (s *State) startSessionSweeper(interval time.Duration) {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	s.stopSessionSweep = func() {
+		close(stop)
+		<-done
+	}
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if removed, err := s.sweepExpiredSessions(); err != nil {
+					slog.Warn("session sweep failed", "error", err)
+				} else if removed > 0 {
+					slog.Info("expired sessions removed", "count", removed)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *State) stopSessionSweeper() {
+	if s.stopSessionSweep != nil {
+		s.stopSessionSweep()
+		s.stopSessionSweep = nil
+	}
+}
+
+func (s *State) sweepExpiredSessions() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	result, err := s.db.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("sweep expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,40 @@
+package serverstate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionSweeperRemovesExpiredSessionsWithoutRequests(t *testing.T) {
+	s := newTestState(t, nil)
+	expired := connectTestMember(t, s, "expired")
+	active := connectTestMember(t, s, "active")
+
+	past := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	if _, err := s.db.Exec(`UPDATE sessions SET expires_at = ? WHERE token = ?`, past, expired.SessionToken); err != nil {
+		t.Fatalf("expire session failed: %v", err)
+	}
+
+	s.startSessionSweeper(10 * time.Millisecond)
+	t.Cleanup(s.stopSessionSweeper)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var count int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE token = ?`, expired.SessionToken).Scan(&count); err != nil {
+			t.Fatalf("count sessions failed: %v", err)
+		}
+		if count == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired session was not swept")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.stopSessionSweeper()
+	if _, err := s.AuthenticateSession(active.SessionToken); err != nil {
+		t.Fatalf("active session must survive the sweep: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package serverstate
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const defaultSessionTokenBytes = 32
+
+// newSessionToken returns SESSION_TOKEN_PREFIX followed by SESSION_TOKEN_BYTES
+// random bytes in SESSION_TOKEN_FORMAT. Tokens are looked up verbatim, so
+// changing the format does not invalidate sessions issued before.
+func (s *State) newSessionToken() (string, error) {
+	size := s.cfg.SessionTokenBytes
+	if size < defaultSessionTokenBytes {
+		size = defaultSessionTokenBytes
+	}
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	if s.cfg.SessionTokenFormat == "base64url" {
+		return s.cfg.SessionTokenPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+	}
+	return s.cfg.SessionTokenPrefix + hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,30 @@
+package serverstate
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestSessionTokenPrefixAndFormat(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) {
+		cfg.SessionTokenPrefix = "fsc_"
+		cfg.SessionTokenFormat = "base64url"
+		cfg.SessionTokenBytes = 32
+	})
+	member := connectTestMember(t, s, "member")
+
+	secret, ok := strings.CutPrefix(member.SessionToken, "fsc_")
+	if !ok {
+		t.Fatalf("expected fsc_ prefix, got %q", member.SessionToken)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(secret)
+	if err != nil || len(raw) != 32 {
+		t.Fatalf("expected 32 random bytes in base64url, got %d bytes, %v", len(raw), err)
+	}
+	if _, err := s.AuthenticateSession(member.SessionToken); err != nil {
+		t.Fatalf("prefixed token should authenticate: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	maxSignChallengeBytes = 1024
+	signRequestsPerWindow = 10
+	signRateWindow        = time.Minute
+	signPayloadPrefix     = "fosscord-server-sign:"
+)
+
+type ServerSignature struct {
+	Signature         string `json:"signature"`
+	ServerPublicKey   string `json:"serverPublicKey"`
+	ServerFingerprint string `json:"serverFingerprint"`
+}
+
+type signWindow struct {
+	Start time.Time
+	Count int
+}
+
+// ServerSignPayloadHash is what the server signs for POST /api/server/sign. The
+// prefix keeps these signatures distinct from any other payload the server key
+// may sign.
+func ServerSignPayloadHash(challenge []byte, serverFingerprint string) [32]byte {
+	payload := make([]byte, 0, len(signPayloadPrefix)+len(challenge)+len(serverFingerprint))
+	payload = append(payload, []byte(signPayloadPrefix)...)
+	payload = append(payload, challenge...)
+	payload = append(payload, []byte(serverFingerprint)...)
+	return sha256.Sum256(payload)
+}
+
+// SignChallenge signs a client-chosen challenge with the server identity so the
+// client can check it is talking to the key it expects. Requests are limited per
+// clientKey (the caller's address) with a fixed window.
+func (s *State) SignChallenge(clientKey, challenge string) (ServerSignature, *RateLimit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := s.consumeSignWindowLocked(clientKey, time.Now().UTC())
+	if limit.Remaining < 0 {
+		limit.Remaining = 0
+		retryAfter := int((time.Until(limit.Reset) + time.Second - 1) / time.Second)
+		return ServerSignature{}, limit, &APIError{
+			Status:    429,
+			Code:      "rate_limited",
+			Message:   fmt.Sprintf("too many signing requests, retry in %d seconds", retryAfter),
+			Details:   map[string]any{"retryAfterSeconds": retryAfter},
+			RateLimit: limit,
+		}
+	}
+
+	challenge = strings.TrimSpace(challenge)
+	if challenge == "" {
+		return ServerSignature{}, limit, newAPIError(400, "invalid_challenge", "challenge is required")
+	}
+	raw, err := base64.StdEncoding.DecodeString(challenge)
+	if err != nil {
+		return ServerSignature{}, limit, newAPIError(400, "invalid_challenge", "challenge must be base64")
+	}
+	if len(raw) > maxSignChallengeBytes {
+		return ServerSignature{}, limit, newAPIError(400, "invalid_challenge", fmt.Sprintf("challenge must be at most %d bytes", maxSignChallengeBytes))
+	}
+
+	hash := ServerSignPayloadHash(raw, s.serverFingerprint)
+	return ServerSignature{
+		Signature:         base64.StdEncoding.EncodeToString(ed25519.Sign(s.serverPrivateKey, hash[:])),
+		ServerPublicKey:   s.serverPublicKey,
+		ServerFingerprint: s.serverFingerprint,
+	}, limit, nil
+}
+
+// consumeSignWindowLocked counts a request against clientKey's window. Remaining
+// goes negative once the window is exhausted.
+func (s *State) consumeSignWindowLocked(clientKey string, now time.Time) *RateLimit {
+	for key, window := range s.signWindows {
+		if !now.Before(window.Start.Add(signRateWindow)) {
+			delete(s.signWindows, key)
+		}
+	}
+
+	window, ok := s.signWindows[clientKey]
+	if !ok {
+		window = signWindow{Start: now}
+	}
+	window.Count++
+	s.signWindows[clientKey] = window
+
+	return &RateLimit{
+		Limit:     signRequestsPerWindow,
+		Remaining: signRequestsPerWindow - window.Count,
+		Reset:     window.Start.Add(signRateWindow),
+	}
+}
@@ -0,0 +1,54 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestSignChallengeVerifiesWithServerKey(t *testing.T) {
+	s := newTestState(t, nil)
+	challenge := []byte("prove it")
+
+	result, _, err := s.SignChallenge("10.0.0.1", base64.StdEncoding.EncodeToString(challenge))
+	if err != nil {
+		t.Fatalf("sign failed: %v", err)
+	}
+	publicKey, err := decodePublicKey(result.ServerPublicKey)
+	if err != nil {
+		t.Fatalf("invalid server public key: %v", err)
+	}
+	signature, err := decodeSignature(result.Signature)
+	if err != nil {
+		t.Fatalf("invalid signature encoding: %v", err)
+	}
+	hash := ServerSignPayloadHash(challenge, s.ServerInfo().ServerFingerprint)
+	if !ed25519.Verify(publicKey, hash[:], signature) {
+		t.Fatal("signature must verify against the server public key")
+	}
+
+	oversized := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", maxSignChallengeBytes+1)))
+	_, _, err = s.SignChallenge("10.0.0.1", oversized)
+	requireAPIErrorCode(t, err, "invalid_challenge")
+}
+
+func TestSignChallengeIsRateLimitedPerClient(t *testing.T) {
+	s := newTestState(t, nil)
+	challenge := base64.StdEncoding.EncodeToString([]byte("hi"))
+
+	for i := 0; i < signRequestsPerWindow; i++ {
+		if _, _, err := s.SignChallenge("10.0.0.1", challenge); err != nil {
+			t.Fatalf("request %d should be allowed: %v", i, err)
+		}
+	}
+	_, limit, err := s.SignChallenge("10.0.0.1", challenge)
+	requireAPIErrorCode(t, err, "rate_limited")
+	if limit == nil || limit.Remaining != 0 {
+		t.Fatalf("expected exhausted rate limit, got %+v", limit)
+	}
+
+	if _, _, err := s.SignChallenge("10.0.0.2", challenge); err != nil {
+		t.Fatalf("other clients must not share the window: %v", err)
+	}
+}
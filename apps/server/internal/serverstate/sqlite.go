@@ -0,0 +1,79 @@
+package serverstate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// serviceBusyRetryAfter is suggested to clients when SQLite stayed locked past
+// busy_timeout; contention on the single connection usually clears quickly.
+const serviceBusyRetryAfter = time.Second
+
+// SQLiteSettings are the pragma values reported by SQLite after configuration,
+// which may differ from the requested ones (e.g. mmap_size is capped at compile time).
+type SQLiteSettings struct {
+	BusyTimeoutMS int
+	CacheSize     int
+	MMapSize      int64
+}
+
+func applySQLitePragmas(db *sql.DB, cfg config.Config) (SQLiteSettings, error) {
+	pragmas := []struct {
+		name  string
+		value int64
+	}{
+		{name: "busy_timeout", value: int64(cfg.SQLiteBusyTimeoutMS)},
+		{name: "cache_size", value: int64(cfg.SQLiteCacheSize)},
+		{name: "mmap_size", value: cfg.SQLiteMMapSize},
+	}
+	for _, pragma := range pragmas {
+		if _, err := db.Exec(fmt.Sprintf(`PRAGMA %s = %d;`, pragma.name, pragma.value)); err != nil {
+			return SQLiteSettings{}, fmt.Errorf("set sqlite %s: %w", pragma.name, err)
+		}
+	}
+
+	var settings SQLiteSettings
+	if err := db.QueryRow(`PRAGMA busy_timeout;`).Scan(&settings.BusyTimeoutMS); err != nil {
+		return SQLiteSettings{}, fmt.Errorf("read sqlite busy_timeout: %w", err)
+	}
+	if err := db.QueryRow(`PRAGMA cache_size;`).Scan(&settings.CacheSize); err != nil {
+		return SQLiteSettings{}, fmt.Errorf("read sqlite cache_size: %w", err)
+	}
+	// mmap_size returns no row when memory mapping is unavailable in this build.
+	if err := db.QueryRow(`PRAGMA mmap_size;`).Scan(&settings.MMapSize); err != nil && err != sql.ErrNoRows {
+		return SQLiteSettings{}, fmt.Errorf("read sqlite mmap_size: %w", err)
+	}
+
+	return settings, nil
+}
+
+func (s *State) SQLiteSettings() SQLiteSettings {
+	return s.sqliteSettings
+}
+
+// MapStorageError turns SQLITE_BUSY and SQLITE_LOCKED, which surface when a write
+// waits longer than busy_timeout, into a retryable 503 service_busy. Other errors
+// are returned unchanged.
+func MapStorageError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	// Extended result codes keep the primary code in the low byte.
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return &APIError{
+			Status:     503,
+			Code:       "service_busy",
+			Message:    "database is busy, retry shortly",
+			RetryAfter: serviceBusyRetryAfter,
+		}
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package serverstate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestMapStorageErrorReportsLockedDatabaseAsBusy(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.SQLiteBusyTimeoutMS = 0 })
+	member := connectTestMember(t, s, "member")
+
+	other, err := sql.Open("sqlite", resolveDatabasePath(s.cfg))
+	if err != nil {
+		t.Fatalf("open second connection failed: %v", err)
+	}
+	defer other.Close()
+	conn, err := other.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("acquire connection failed: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), `BEGIN IMMEDIATE`); err != nil {
+		t.Fatalf("lock database failed: %v", err)
+	}
+
+	_, err = s.CreateMessage(member.SessionToken, "general", "blocked")
+	if err == nil {
+		t.Fatal("expected write to fail while another connection holds the lock")
+	}
+	var apiErr *APIError
+	if !errors.As(MapStorageError(err), &apiErr) || apiErr.Code != "service_busy" || apiErr.Status != 503 || apiErr.RetryAfter != time.Second {
+		t.Fatalf("expected service_busy, got %v", MapStorageError(err))
+	}
+
+	if _, err := conn.ExecContext(context.Background(), `ROLLBACK`); err != nil {
+		t.Fatalf("unlock database failed: %v", err)
+	}
+	if _, err := s.CreateMessage(member.SessionToken, "general", "unblocked"); err != nil {
+		t.Fatalf("write should succeed once the lock is released: %v", err)
+	}
+
+	plain := errors.New("boom")
+	if MapStorageError(plain) != plain {
+		t.Fatal("unrelated errors must pass through unchanged")
+	}
+}
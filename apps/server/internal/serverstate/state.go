@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -19,19 +20,28 @@ import (
 	"time"
 
 	"fosscord/apps/server/internal/config"
+	"fosscord/apps/server/internal/webhook"
 	_ "modernc.org/sqlite"
 )
 
 const (
 	challengeTTL        = 2 * time.Minute
+	finishRetryWindow   = 2 * time.Minute
 	adminRequestMaxSkew = 2 * time.Minute
 	sessionTTL          = 30 * 24 * time.Hour
+
+	adminInviteV2PayloadPrefix = "fosscord-admin-invite-v2:"
 )
 
 type APIError struct {
 	Status  int
 	Code    string
 	Message string
+	Details map[string]any
+	// RateLimit is set by throttled operations and surfaced as response headers.
+	RateLimit *RateLimit
+	// RetryAfter is sent as Retry-After on transient errors the client should retry.
+	RetryAfter time.Duration
 }
 
 func (e *APIError) Error() string {
@@ -42,19 +52,45 @@ func newAPIError(status int, code, message string) *APIError {
 	return &APIError{Status: status, Code: code, Message: message}
 }
 
+func isAPIErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
 type Channel struct {
-	ID   string `json:"id"`
-	Type string `json:"type"`
-	Name string `json:"name"`
+	ID               string   `json:"id"`
+	Type             string   `json:"type"`
+	Name             string   `json:"name"`
+	Topic            string   `json:"topic,omitempty"`
+	SlowModeSeconds  int      `json:"slowModeSeconds"`
+	MaxMessageLength int      `json:"maxMessageLength,omitempty"`
+	PublicPreview    bool     `json:"publicPreview,omitempty"`
+	E2EE             bool     `json:"e2ee,omitempty"`
+	TypingIndicators *bool    `json:"typingIndicators,omitempty"`
+	ReadRoles        []string `json:"readRoles,omitempty"`
+	WriteRoles       []string `json:"writeRoles,omitempty"`
+	AdminRoles       []string `json:"adminRoles,omitempty"`
 }
 
 type ServerInfo struct {
-	ServerID          string   `json:"serverId"`
-	Name              string   `json:"name"`
-	ServerFingerprint string   `json:"serverFingerprint"`
-	ServerPublicKey   string   `json:"serverPublicKey"`
-	LiveKitURL        string   `json:"livekitUrl"`
-	AdminPublicKeys   []string `json:"adminPublicKeys"`
+	ServerID          string          `json:"serverId"`
+	Name              string          `json:"name"`
+	ServerFingerprint string          `json:"serverFingerprint"`
+	ServerPublicKey   string          `json:"serverPublicKey"`
+	LiveKitURL        string          `json:"livekitUrl"`
+	AdminPublicKeys   []string        `json:"adminPublicKeys"`
+	Maintenance       bool            `json:"maintenance"`
+	VoiceLimits       VoiceLimits     `json:"voiceLimits"`
+	Motd              string          `json:"motd,omitempty"`
+	MotdUpdatedAt     string          `json:"motdUpdatedAt,omitempty"`
+	DefaultChannelID  string          `json:"defaultChannelId,omitempty"`
+	Capabilities      map[string]bool `json:"capabilities"`
+}
+
+// VoiceLimits are the caps applied to voice presence stream counters.
+type VoiceLimits struct {
+	MaxAudioStreams int `json:"maxAudioStreams"`
+	MaxVideoStreams int `json:"maxVideoStreams"`
 }
 
 type CreateInviteResult struct {
@@ -68,6 +104,8 @@ type CreateInviteByAdminClientRequest struct {
 	AdminPublicKey  string
 	ClientPublicKey string
 	Label           string
+	Metadata        json.RawMessage
+	AdminOnly       bool
 	IssuedAt        string
 	Signature       string
 }
@@ -86,12 +124,15 @@ type AdminConnectRequest struct {
 }
 
 type InviteSummary struct {
-	InviteID               string  `json:"inviteId"`
-	AllowedClientPublicKey string  `json:"allowedClientPublicKey"`
-	Label                  string  `json:"label"`
-	CreatedAt              string  `json:"createdAt"`
-	UsedAt                 *string `json:"usedAt,omitempty"`
-	Status                 string  `json:"status"`
+	InviteID               string          `json:"inviteId"`
+	AllowedClientPublicKey string          `json:"allowedClientPublicKey"`
+	Label                  string          `json:"label"`
+	CreatedAt              string          `json:"createdAt"`
+	UsedAt                 *string         `json:"usedAt,omitempty"`
+	UsedByPublicKey        *string         `json:"usedByPublicKey,omitempty"`
+	Metadata               json.RawMessage `json:"metadata,omitempty"`
+	AdminOnly              bool            `json:"adminOnly,omitempty"`
+	Status                 string          `json:"status"`
 }
 
 type ListInvitesResult struct {
@@ -103,10 +144,13 @@ type BeginResult struct {
 	ServerFingerprint string    `json:"serverFingerprint"`
 	Challenge         string    `json:"challenge"`
 	ExpiresAt         time.Time `json:"expiresAt"`
+	// InviteBound is false for invites any client key may redeem.
+	InviteBound bool `json:"inviteBound"`
 }
 
 type ClientInfo struct {
 	DisplayName string `json:"displayName"`
+	AppVersion  string `json:"appVersion,omitempty"`
 }
 
 type FinishRequest struct {
@@ -123,22 +167,44 @@ type FinishResult struct {
 	ServerFingerprint string    `json:"serverFingerprint"`
 	LiveKitURL        string    `json:"livekitUrl"`
 	Channels          []Channel `json:"channels"`
+	DefaultChannelID  string    `json:"defaultChannelId,omitempty"`
 	SessionToken      string    `json:"sessionToken,omitempty"`
+	MotdUnread        bool      `json:"motdUnread"`
 }
 
 type State struct {
 	cfg config.Config
 
-	mu         sync.Mutex
-	db         *sql.DB
-	serverCfg  serverConfigFile
-	challenges map[string]pendingChallenge
-	streams    map[string]map[int]chan ChannelEvent
-	nextStream int
+	mu            sync.Mutex
+	db            *sql.DB
+	serverCfg     serverConfigFile
+	serverCfgPath string
+	challenges    map[string]pendingChallenge
+	streams       map[string]map[int]chan ChannelEvent
+	streamMembers map[string]int
+	streamOwners  map[int]string
+	streamDrops   map[string]uint64
+	nextStream    int
+	lastPostAt    map[slowModeKey]time.Time
+	maintenance   bool
+	wsConnections int
+	streamsClosed bool
+	openStreams   sync.WaitGroup
+
+	channelActivity   []ChannelActivity
+	channelActivityAt time.Time
+	voiceRosters      map[string]*voiceRoster
+	signWindows       map[string]signWindow
+	messageBuckets    map[string]messageBucket
+	messageWebhook    *webhook.Dispatcher
+	voiceCleanupAt    time.Time
+	stopSessionSweep  func()
 
 	serverID          string
 	serverFingerprint string
 	serverPublicKey   string
+	serverPrivateKey  ed25519.PrivateKey
+	sqliteSettings    SQLiteSettings
 }
 
 type identityRecord struct {
@@ -150,6 +216,8 @@ type serverConfigFile struct {
 	ServerName      string    `json:"serverName"`
 	Channels        []Channel `json:"channels"`
 	AdminPublicKeys []string  `json:"adminPublicKeys"`
+	Motd            string    `json:"motd,omitempty"`
+	MotdUpdatedAt   string    `json:"motdUpdatedAt,omitempty"`
 }
 
 type inviteRecord struct {
@@ -158,11 +226,15 @@ type inviteRecord struct {
 	Label                  string
 	CreatedAt              string
 	UsedAt                 *string
+	AdminOnly              bool
 }
 
 type pendingChallenge struct {
 	Challenge string
 	ExpiresAt time.Time
+	// RedeemedBy is set once the challenge completed a connect. The entry is kept
+	// for finishRetryWindow so a client that lost the response can retry.
+	RedeemedBy string
 }
 
 func New(cfg config.Config) (*State, error) {
@@ -181,23 +253,29 @@ func New(cfg config.Config) (*State, error) {
 	}
 	db.SetMaxOpenConns(1)
 
-	if _, err := db.Exec(`PRAGMA busy_timeout = 5000;`); err != nil {
+	sqliteSettings, err := applySQLitePragmas(db, cfg)
+	if err != nil {
 		_ = db.Close()
-		return nil, fmt.Errorf("set sqlite busy_timeout: %w", err)
+		return nil, err
 	}
 
-	if err := applyMigrations(db); err != nil {
+	if err := applyMigrations(db, migrationFS); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("apply migrations: %w", err)
 	}
 
-	serverCfg, err := loadOrCreateServerConfig(filepath.Join(cfg.DataDir, "server_config.json"), cfg.ServerName)
+	serverCfgPath := filepath.Join(cfg.DataDir, "server_config.json")
+	serverCfg, err := loadOrCreateServerConfig(serverCfgPath, cfg.ServerName)
 	if err != nil {
 		_ = db.Close()
 		return nil, err
 	}
+	if err := validateDefaultChannel(serverCfg.Channels, cfg.DefaultChannelID); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
-	identity, err := loadOrCreateIdentity(db)
+	identity, err := loadOrCreateIdentity(db, cfg)
 	if err != nil {
 		_ = db.Close()
 		return nil, err
@@ -208,17 +286,43 @@ func New(cfg config.Config) (*State, error) {
 		_ = db.Close()
 		return nil, err
 	}
+	priv, err := decodePrivateKey(identity.PrivateKey)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
-	return &State{
+	var messageWebhook *webhook.Dispatcher
+	if cfg.MessageWebhookURL != "" {
+		messageWebhook = webhook.NewDispatcher(cfg.MessageWebhookURL, cfg.MessageWebhookSecret)
+	}
+
+	s := &State{
 		cfg:               cfg,
 		db:                db,
 		serverCfg:         serverCfg,
+		serverCfgPath:     serverCfgPath,
 		challenges:        make(map[string]pendingChallenge),
 		streams:           make(map[string]map[int]chan ChannelEvent),
+		streamMembers:     make(map[string]int),
+		streamOwners:      make(map[int]string),
+		streamDrops:       make(map[string]uint64),
+		lastPostAt:        make(map[slowModeKey]time.Time),
+		voiceRosters:      make(map[string]*voiceRoster),
+		signWindows:       make(map[string]signWindow),
+		messageBuckets:    make(map[string]messageBucket),
+		messageWebhook:    messageWebhook,
+		maintenance:       cfg.Maintenance,
 		serverID:          stableServerID(pub),
 		serverFingerprint: FingerprintFromPublicKey(pub),
 		serverPublicKey:   base64.StdEncoding.EncodeToString(pub),
-	}, nil
+		serverPrivateKey:  priv,
+		sqliteSettings:    sqliteSettings,
+	}
+	if cfg.SessionSweepIntervalSec > 0 {
+		s.startSessionSweeper(time.Duration(cfg.SessionSweepIntervalSec) * time.Second)
+	}
+	return s, nil
 }
 
 func (s *State) ServerInfo() ServerInfo {
@@ -235,33 +339,74 @@ func (s *State) ServerInfo() ServerInfo {
 		ServerPublicKey:   s.serverPublicKey,
 		LiveKitURL:        s.cfg.LiveKitPublicURL,
 		AdminPublicKeys:   admins,
+		Maintenance:       s.maintenance,
+		VoiceLimits: VoiceLimits{
+			MaxAudioStreams: s.cfg.MaxAudioStreams,
+			MaxVideoStreams: s.cfg.MaxVideoStreams,
+		},
+		Motd:             s.serverCfg.Motd,
+		MotdUpdatedAt:    s.serverCfg.MotdUpdatedAt,
+		DefaultChannelID: s.defaultChannelIDLocked(),
+		Capabilities:     s.capabilitiesLocked(),
 	}
 }
 
-func (s *State) Channels() []Channel {
+// Channels lists the channels visible to the session; an empty token lists
+// only channels without read roles.
+func (s *State) Channels(sessionToken string) ([]Channel, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	channels := make([]Channel, len(s.serverCfg.Channels))
-	copy(channels, s.serverCfg.Channels)
-	return channels
+	var publicKey string
+	if strings.TrimSpace(sessionToken) != "" {
+		identity, err := s.authenticateSessionLocked(sessionToken)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = identity.PublicKey
+	}
+	return s.visibleChannelsLocked(publicKey)
 }
 
-func (s *State) CreateInvite(clientPublicKeyB64, label string) (CreateInviteResult, error) {
+// CreateInvite issues a single-use invite bound to clientPublicKeyB64.
+// metadata is an optional opaque JSON object returned when listing invites.
+// adminOnly invites are only listed to admins.
+func (s *State) CreateInvite(clientPublicKeyB64, label string, metadata json.RawMessage, adminOnly bool) (CreateInviteResult, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.ensureWritableLocked(); err != nil {
+		return CreateInviteResult{}, err
+	}
+
 	if _, err := decodePublicKey(clientPublicKeyB64); err != nil {
 		return CreateInviteResult{}, newAPIError(400, "invalid_client_public_key", "clientPublicKey must be base64(ed25519 public key)")
 	}
 
-	return s.createInviteLocked(clientPublicKeyB64, label)
+	return s.createInviteLocked(clientPublicKeyB64, label, metadata, adminOnly)
+}
+
+// CreateUnboundInvite issues an invite any client key may finish the connect
+// with; the redemption binds it to whoever does. It is still single use.
+func (s *State) CreateUnboundInvite(label string, metadata json.RawMessage, adminOnly bool) (CreateInviteResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ensureWritableLocked(); err != nil {
+		return CreateInviteResult{}, err
+	}
+
+	return s.createInviteLocked("", label, metadata, adminOnly)
 }
 
 func (s *State) CreateInviteByAdminClient(req CreateInviteByAdminClientRequest) (CreateInviteResult, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.ensureWritableLocked(); err != nil {
+		return CreateInviteResult{}, err
+	}
+
 	req.AdminPublicKey = strings.TrimSpace(req.AdminPublicKey)
 	req.ClientPublicKey = strings.TrimSpace(req.ClientPublicKey)
 	req.IssuedAt = strings.TrimSpace(req.IssuedAt)
@@ -279,8 +424,8 @@ func (s *State) CreateInviteByAdminClient(req CreateInviteByAdminClientRequest)
 		return CreateInviteResult{}, newAPIError(400, "invalid_client_public_key", "clientPublicKey must be base64(ed25519 public key)")
 	}
 
-	if !s.isAdminPublicKeyLocked(req.AdminPublicKey) {
-		return CreateInviteResult{}, newAPIError(403, "admin_forbidden", "client is not an administrator")
+	if err := s.ensureAdminClientLocked(req.AdminPublicKey); err != nil {
+		return CreateInviteResult{}, err
 	}
 
 	issuedAt, err := time.Parse(time.RFC3339, req.IssuedAt)
@@ -296,12 +441,19 @@ func (s *State) CreateInviteByAdminClient(req CreateInviteByAdminClientRequest)
 		return CreateInviteResult{}, newAPIError(400, "invalid_signature", "signature must be base64(ed25519 signature)")
 	}
 
-	hash := AdminInvitePayloadHash(req.AdminPublicKey, req.ClientPublicKey, req.IssuedAt)
-	if !ed25519.Verify(adminKey, hash[:], signature) {
+	// The original payload covers neither adminOnly nor metadata, so a request
+	// setting either is only accepted with the v2 payload.
+	hash := AdminInviteV2PayloadHash(req.AdminPublicKey, req.ClientPublicKey, req.IssuedAt, req.AdminOnly, req.Metadata, s.serverFingerprint)
+	verified := ed25519.Verify(adminKey, hash[:], signature)
+	if !verified && !req.AdminOnly && len(req.Metadata) == 0 {
+		hash = AdminInvitePayloadHash(req.AdminPublicKey, req.ClientPublicKey, req.IssuedAt)
+		verified = ed25519.Verify(adminKey, hash[:], signature)
+	}
+	if !verified {
 		return CreateInviteResult{}, newAPIError(401, "invalid_signature", "signature verification failed")
 	}
 
-	return s.createInviteLocked(req.ClientPublicKey, req.Label)
+	return s.createInviteLocked(req.ClientPublicKey, req.Label, req.Metadata, req.AdminOnly)
 }
 
 func (s *State) ListInvitesByAdminClient(req ListInvitesByAdminClientRequest) (ListInvitesResult, error) {
@@ -320,8 +472,8 @@ func (s *State) ListInvitesByAdminClient(req ListInvitesByAdminClientRequest) (L
 	if err != nil {
 		return ListInvitesResult{}, newAPIError(400, "invalid_admin_public_key", "adminPublicKey must be base64(ed25519 public key)")
 	}
-	if !s.isAdminPublicKeyLocked(req.AdminPublicKey) {
-		return ListInvitesResult{}, newAPIError(403, "admin_forbidden", "client is not an administrator")
+	if err := s.ensureAdminClientLocked(req.AdminPublicKey); err != nil {
+		return ListInvitesResult{}, err
 	}
 
 	issuedAt, err := time.Parse(time.RFC3339, req.IssuedAt)
@@ -342,7 +494,7 @@ func (s *State) ListInvitesByAdminClient(req ListInvitesByAdminClientRequest) (L
 		return ListInvitesResult{}, newAPIError(401, "invalid_signature", "signature verification failed")
 	}
 
-	rows, err := s.db.Query(`SELECT id, allowed_client_public_key, label, created_at, used_at FROM invites ORDER BY created_at DESC`)
+	rows, err := s.db.Query(`SELECT ` + inviteSummaryColumns + ` FROM invites ORDER BY created_at DESC`)
 	if err != nil {
 		return ListInvitesResult{}, fmt.Errorf("query invites list: %w", err)
 	}
@@ -353,34 +505,11 @@ func (s *State) ListInvitesByAdminClient(req ListInvitesByAdminClientRequest) (L
 	}
 
 	for rows.Next() {
-		var (
-			inviteID         string
-			allowedClientKey string
-			label            string
-			createdAt        string
-			usedAt           sql.NullString
-			usedAtPointer    *string
-			status           = "active"
-		)
-
-		if err := rows.Scan(&inviteID, &allowedClientKey, &label, &createdAt, &usedAt); err != nil {
+		summary, err := scanInviteSummary(rows)
+		if err != nil {
 			return ListInvitesResult{}, fmt.Errorf("scan invites list row: %w", err)
 		}
-
-		if usedAt.Valid {
-			usedAtCopy := usedAt.String
-			usedAtPointer = &usedAtCopy
-			status = "used"
-		}
-
-		result.Invites = append(result.Invites, InviteSummary{
-			InviteID:               inviteID,
-			AllowedClientPublicKey: allowedClientKey,
-			Label:                  label,
-			CreatedAt:              createdAt,
-			UsedAt:                 usedAtPointer,
-			Status:                 status,
-		})
+		result.Invites = append(result.Invites, summary)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -401,13 +530,16 @@ func (s *State) AdminConnect(req AdminConnectRequest) (FinishResult, error) {
 	if req.AdminPublicKey == "" || req.IssuedAt == "" || req.Signature == "" {
 		return FinishResult{}, newAPIError(400, "invalid_request", "adminPublicKey, issuedAt and signature are required")
 	}
+	if err := s.ensureClientVersionLocked(req.ClientInfo); err != nil {
+		return FinishResult{}, err
+	}
 
 	adminKey, err := decodePublicKey(req.AdminPublicKey)
 	if err != nil {
 		return FinishResult{}, newAPIError(400, "invalid_admin_public_key", "adminPublicKey must be base64(ed25519 public key)")
 	}
-	if !s.isAdminPublicKeyLocked(req.AdminPublicKey) {
-		return FinishResult{}, newAPIError(403, "admin_forbidden", "client is not an administrator")
+	if err := s.ensureAdminClientLocked(req.AdminPublicKey); err != nil {
+		return FinishResult{}, err
 	}
 
 	issuedAt, err := time.Parse(time.RFC3339, req.IssuedAt)
@@ -433,25 +565,23 @@ func (s *State) AdminConnect(req AdminConnectRequest) (FinishResult, error) {
 		return FinishResult{}, err
 	}
 
-	sessionToken, err := s.issueSessionTokenLocked(req.AdminPublicKey)
+	return s.sessionResultLocked(req.AdminPublicKey)
+}
+
+func (s *State) createInviteLocked(clientPublicKeyB64, label string, metadata json.RawMessage, adminOnly bool) (CreateInviteResult, error) {
+	label, err := normalizeInviteLabel(label, s.cfg.RequireInviteLabel)
 	if err != nil {
-		return FinishResult{}, err
+		return CreateInviteResult{}, err
+	}
+	metadata, err = normalizeInviteMetadata(metadata)
+	if err != nil {
+		return CreateInviteResult{}, err
+	}
+	var storedMetadata sql.NullString
+	if metadata != nil {
+		storedMetadata = sql.NullString{String: string(metadata), Valid: true}
 	}
 
-	channels := make([]Channel, len(s.serverCfg.Channels))
-	copy(channels, s.serverCfg.Channels)
-
-	return FinishResult{
-		ServerID:          s.serverID,
-		ServerName:        s.serverCfg.ServerName,
-		ServerFingerprint: s.serverFingerprint,
-		LiveKitURL:        s.cfg.LiveKitPublicURL,
-		Channels:          channels,
-		SessionToken:      sessionToken,
-	}, nil
-}
-
-func (s *State) createInviteLocked(clientPublicKeyB64, label string) (CreateInviteResult, error) {
 	inviteID, err := randomHex(16)
 	if err != nil {
 		return CreateInviteResult{}, fmt.Errorf("generate invite id: %w", err)
@@ -459,11 +589,13 @@ func (s *State) createInviteLocked(clientPublicKeyB64, label string) (CreateInvi
 
 	createdAt := time.Now().UTC().Format(time.RFC3339)
 	if _, err := s.db.Exec(
-		`INSERT INTO invites(id, allowed_client_public_key, label, created_at) VALUES (?, ?, ?, ?)`,
+		`INSERT INTO invites(id, allowed_client_public_key, label, created_at, metadata, admin_only) VALUES (?, ?, ?, ?, ?, ?)`,
 		inviteID,
 		clientPublicKeyB64,
-		strings.TrimSpace(label),
+		label,
 		createdAt,
+		storedMetadata,
+		adminOnly,
 	); err != nil {
 		return CreateInviteResult{}, fmt.Errorf("persist invite: %w", err)
 	}
@@ -495,6 +627,11 @@ func (s *State) BeginConnect(inviteID string) (BeginResult, error) {
 	if err != nil {
 		return BeginResult{}, err
 	}
+	// An admin-only invite bound to a regular key does not exist for it. Unbound
+	// ones are checked once finish names the key.
+	if invite.AdminOnly && invite.AllowedClientPublicKey != "" && !s.isAdminPublicKeyLocked(invite.AllowedClientPublicKey) {
+		return BeginResult{}, newAPIError(404, "invite_not_found", "invite does not exist")
+	}
 	if invite.UsedAt != nil {
 		return BeginResult{}, newAPIError(403, "invite_used", "invite has already been used")
 	}
@@ -516,6 +653,7 @@ func (s *State) BeginConnect(inviteID string) (BeginResult, error) {
 		ServerFingerprint: s.serverFingerprint,
 		Challenge:         challenge,
 		ExpiresAt:         expiresAt,
+		InviteBound:       invite.AllowedClientPublicKey != "",
 	}, nil
 }
 
@@ -523,22 +661,48 @@ func (s *State) FinishConnect(req FinishRequest) (FinishResult, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Each missing field has its own code so client authors can tell which part
+	// of the handshake they got wrong; fields are checked in request order.
 	req.InviteID = strings.TrimSpace(req.InviteID)
-	if req.InviteID == "" {
-		return FinishResult{}, newAPIError(400, "invalid_request", "inviteId is required")
+	switch {
+	case req.InviteID == "":
+		return FinishResult{}, newAPIError(400, "missing_invite_id", "inviteId is required")
+	case strings.TrimSpace(req.ClientPublicKey) == "":
+		return FinishResult{}, newAPIError(400, "missing_client_public_key", "clientPublicKey is required")
+	case strings.TrimSpace(req.Challenge) == "":
+		return FinishResult{}, newAPIError(400, "missing_challenge", "challenge is required")
+	case strings.TrimSpace(req.Signature) == "":
+		return FinishResult{}, newAPIError(400, "missing_signature", "signature is required")
+	}
+	if err := s.ensureClientVersionLocked(req.ClientInfo); err != nil {
+		return FinishResult{}, err
 	}
-	if strings.TrimSpace(req.ClientPublicKey) == "" || strings.TrimSpace(req.Challenge) == "" || strings.TrimSpace(req.Signature) == "" {
-		return FinishResult{}, newAPIError(400, "invalid_request", "clientPublicKey, challenge and signature are required")
+
+	// A retry of a finish that already redeemed the invite gets a fresh session
+	// instead of invite_used, as long as it repeats the same key and challenge.
+	if pending, ok := s.challenges[req.InviteID]; ok && pending.RedeemedBy != "" {
+		switch {
+		case time.Now().UTC().After(pending.ExpiresAt):
+			delete(s.challenges, req.InviteID)
+		case pending.RedeemedBy == req.ClientPublicKey && pending.Challenge == req.Challenge:
+			if err := verifyFinishSignature(req, s.serverFingerprint); err != nil {
+				return FinishResult{}, err
+			}
+			return s.sessionResultLocked(req.ClientPublicKey)
+		}
 	}
 
 	invite, err := s.lookupInvite(req.InviteID)
 	if err != nil {
 		return FinishResult{}, err
 	}
+	if invite.AdminOnly && !s.isAdminPublicKeyLocked(req.ClientPublicKey) {
+		return FinishResult{}, newAPIError(404, "invite_not_found", "invite does not exist")
+	}
 	if invite.UsedAt != nil {
 		return FinishResult{}, newAPIError(403, "invite_used", "invite has already been used")
 	}
-	if req.ClientPublicKey != invite.AllowedClientPublicKey {
+	if invite.AllowedClientPublicKey != "" && req.ClientPublicKey != invite.AllowedClientPublicKey {
 		return FinishResult{}, newAPIError(403, "client_not_allowed", "client public key is not allowed for this invite")
 	}
 
@@ -554,50 +718,74 @@ func (s *State) FinishConnect(req FinishRequest) (FinishResult, error) {
 		return FinishResult{}, newAPIError(401, "challenge_mismatch", "challenge mismatch")
 	}
 
+	if err := verifyFinishSignature(req, s.serverFingerprint); err != nil {
+		return FinishResult{}, err
+	}
+
+	if err := s.redeemInviteLocked(req.InviteID, req.ClientPublicKey); err != nil {
+		return FinishResult{}, err
+	}
+
+	s.challenges[req.InviteID] = pendingChallenge{
+		Challenge:  req.Challenge,
+		ExpiresAt:  time.Now().UTC().Add(finishRetryWindow),
+		RedeemedBy: req.ClientPublicKey,
+	}
+
+	_, memberErr := s.findMemberLocked(req.ClientPublicKey)
+	if memberErr != nil && !isAPIErrorCode(memberErr, "member_not_found") {
+		return FinishResult{}, memberErr
+	}
+
+	displayName := normalizeDisplayName(req.ClientInfo.DisplayName, req.ClientPublicKey)
+	if err := s.upsertMemberLocked(req.ClientPublicKey, displayName); err != nil {
+		return FinishResult{}, err
+	}
+	if memberErr != nil {
+		s.postWelcomeMessageLocked(displayName)
+	}
+
+	return s.sessionResultLocked(req.ClientPublicKey)
+}
+
+// verifyFinishSignature checks the client's signature over the invite challenge.
+func verifyFinishSignature(req FinishRequest, serverFingerprint string) error {
 	clientPublicKey, err := decodePublicKey(req.ClientPublicKey)
 	if err != nil {
-		return FinishResult{}, newAPIError(400, "invalid_client_public_key", "clientPublicKey must be base64(ed25519 public key)")
+		return newAPIError(400, "invalid_client_public_key", "clientPublicKey must be base64(ed25519 public key)")
 	}
 
 	signature, err := decodeSignature(req.Signature)
 	if err != nil {
-		return FinishResult{}, newAPIError(400, "invalid_signature", "signature must be base64(ed25519 signature)")
+		return newAPIError(400, "invalid_signature", "signature must be base64(ed25519 signature)")
 	}
 
 	challengeBytes, err := base64.StdEncoding.DecodeString(req.Challenge)
 	if err != nil {
-		return FinishResult{}, newAPIError(400, "invalid_challenge", "challenge must be base64")
+		return newAPIError(400, "invalid_challenge", "challenge must be base64")
 	}
 
-	hash := SignaturePayloadHash(challengeBytes, req.InviteID, s.serverFingerprint)
+	hash := SignaturePayloadHash(challengeBytes, req.InviteID, serverFingerprint)
 	if !ed25519.Verify(clientPublicKey, hash[:], signature) {
-		return FinishResult{}, newAPIError(401, "invalid_signature", "signature verification failed")
+		return newAPIError(401, "invalid_signature", "signature verification failed")
 	}
+	return nil
+}
 
-	usedAt := time.Now().UTC().Format(time.RFC3339)
-	result, err := s.db.Exec(`UPDATE invites SET used_at = ? WHERE id = ? AND used_at IS NULL`, usedAt, req.InviteID)
-	if err != nil {
-		return FinishResult{}, fmt.Errorf("mark invite as used: %w", err)
-	}
-	rowsAffected, err := result.RowsAffected()
+// sessionResultLocked issues a session token for a member that just connected
+// and describes the server for it.
+func (s *State) sessionResultLocked(publicKey string) (FinishResult, error) {
+	channels, err := s.visibleChannelsLocked(publicKey)
 	if err != nil {
-		return FinishResult{}, fmt.Errorf("check invite update result: %w", err)
-	}
-	if rowsAffected == 0 {
-		return FinishResult{}, newAPIError(403, "invite_used", "invite has already been used")
+		return FinishResult{}, err
 	}
 
-	delete(s.challenges, req.InviteID)
-
-	channels := make([]Channel, len(s.serverCfg.Channels))
-	copy(channels, s.serverCfg.Channels)
-
-	displayName := normalizeDisplayName(req.ClientInfo.DisplayName, req.ClientPublicKey)
-	if err := s.upsertMemberLocked(req.ClientPublicKey, displayName); err != nil {
+	sessionToken, err := s.issueSessionTokenLocked(publicKey)
+	if err != nil {
 		return FinishResult{}, err
 	}
 
-	sessionToken, err := s.issueSessionTokenLocked(req.ClientPublicKey)
+	motdUnread, err := s.motdUnreadLocked(publicKey)
 	if err != nil {
 		return FinishResult{}, err
 	}
@@ -608,16 +796,67 @@ func (s *State) FinishConnect(req FinishRequest) (FinishResult, error) {
 		ServerFingerprint: s.serverFingerprint,
 		LiveKitURL:        s.cfg.LiveKitPublicURL,
 		Channels:          channels,
+		DefaultChannelID:  s.defaultChannelIDLocked(),
 		SessionToken:      sessionToken,
+		MotdUnread:        motdUnread,
 	}, nil
 }
 
+// redeemInviteLocked marks the invite used by clientPublicKey, or deletes it with
+// AUTO_DELETE_USED_INVITES, and appends an audit row to invite_redemptions in the
+// same transaction.
+func (s *State) redeemInviteLocked(inviteID, clientPublicKey string) error {
+	usedAt := time.Now().UTC().Format(time.RFC3339)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin invite redemption: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var result sql.Result
+	if s.cfg.AutoDeleteUsedInvites {
+		result, err = tx.Exec(`DELETE FROM invites WHERE id = ? AND used_at IS NULL`, inviteID)
+	} else {
+		result, err = tx.Exec(
+			`UPDATE invites SET used_at = ?, used_by_public_key = ? WHERE id = ? AND used_at IS NULL`,
+			usedAt,
+			clientPublicKey,
+			inviteID,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("mark invite as used: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check invite update result: %w", err)
+	}
+	if rowsAffected == 0 {
+		return newAPIError(403, "invite_used", "invite has already been used")
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO invite_redemptions(invite_id, client_public_key, redeemed_at) VALUES (?, ?, ?)`,
+		inviteID,
+		clientPublicKey,
+		usedAt,
+	); err != nil {
+		return fmt.Errorf("record invite redemption: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit invite redemption: %w", err)
+	}
+	return nil
+}
+
 func (s *State) lookupInvite(inviteID string) (inviteRecord, error) {
 	var invite inviteRecord
 	var usedAt sql.NullString
 
 	err := s.db.QueryRow(
-		`SELECT id, allowed_client_public_key, label, created_at, used_at FROM invites WHERE id = ?`,
+		`SELECT id, allowed_client_public_key, label, created_at, used_at, admin_only FROM invites WHERE id = ?`,
 		inviteID,
 	).Scan(
 		&invite.ID,
@@ -625,6 +864,7 @@ func (s *State) lookupInvite(inviteID string) (inviteRecord, error) {
 		&invite.Label,
 		&invite.CreatedAt,
 		&usedAt,
+		&invite.AdminOnly,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return inviteRecord{}, newAPIError(404, "invite_not_found", "invite does not exist")
@@ -639,6 +879,19 @@ func (s *State) lookupInvite(inviteID string) (inviteRecord, error) {
 	return invite, nil
 }
 
+// ensureAdminClientLocked authorizes the client-signed admin endpoints. They
+// depend only on adminPublicKeys, never on ADMIN_TOKEN: with no admin keys
+// they are disabled, mirroring ADMIN_TOKEN being unset for the token paths.
+func (s *State) ensureAdminClientLocked(publicKey string) error {
+	if len(s.serverCfg.AdminPublicKeys) == 0 {
+		return newAPIError(503, "admin_disabled", "no adminPublicKeys are configured")
+	}
+	if !s.isAdminPublicKeyLocked(publicKey) {
+		return newAPIError(403, "admin_forbidden", "client is not an administrator")
+	}
+	return nil
+}
+
 func (s *State) isAdminPublicKeyLocked(publicKey string) bool {
 	for _, admin := range s.serverCfg.AdminPublicKeys {
 		if admin == publicKey {
@@ -664,6 +917,27 @@ func AdminInvitePayloadHash(adminPublicKey, clientPublicKey, issuedAt string) [3
 	return sha256.Sum256(payload)
 }
 
+// AdminInviteV2PayloadHash extends AdminInvitePayloadHash with the invite's
+// adminOnly flag ("1" or "0"), the SHA-256 of its raw metadata JSON (of no
+// bytes when absent) and the server fingerprint.
+func AdminInviteV2PayloadHash(adminPublicKey, clientPublicKey, issuedAt string, adminOnly bool, metadata []byte, serverFingerprint string) [32]byte {
+	metadataHash := sha256.Sum256(metadata)
+	flag := "0"
+	if adminOnly {
+		flag = "1"
+	}
+
+	payload := make([]byte, 0, len(adminInviteV2PayloadPrefix)+len(adminPublicKey)+len(clientPublicKey)+len(issuedAt)+len(flag)+len(metadataHash)+len(serverFingerprint))
+	payload = append(payload, []byte(adminInviteV2PayloadPrefix)...)
+	payload = append(payload, []byte(adminPublicKey)...)
+	payload = append(payload, []byte(clientPublicKey)...)
+	payload = append(payload, []byte(issuedAt)...)
+	payload = append(payload, []byte(flag)...)
+	payload = append(payload, metadataHash[:]...)
+	payload = append(payload, []byte(serverFingerprint)...)
+	return sha256.Sum256(payload)
+}
+
 func AdminListInvitesPayloadHash(adminPublicKey, issuedAt string) [32]byte {
 	payload := make([]byte, 0, len(adminPublicKey)+len(issuedAt))
 	payload = append(payload, []byte(adminPublicKey)...)
@@ -679,15 +953,30 @@ func AdminConnectPayloadHash(adminPublicKey, issuedAt, serverFingerprint string)
 	return sha256.Sum256(payload)
 }
 
+// FingerprintFromPublicKey maps SHA-256(publicKey) to fingerprintLength emojis.
+// Each emoji is reduced from its own 8-byte slice of the hash, so every hash byte
+// contributes and the modulo bias is at most len(fingerprintEmojis)/2^64.
 func FingerprintFromPublicKey(publicKey []byte) string {
-	hash := sha256.Sum256(publicKey)
-	parts := make([]string, 4)
-	for i := 0; i < 4; i++ {
-		parts[i] = fingerprintEmojis[int(hash[i])%len(fingerprintEmojis)]
+	indexes := fingerprintIndexes(publicKey)
+	parts := make([]string, fingerprintLength)
+	for i, index := range indexes {
+		parts[i] = fingerprintEmojis[index]
 	}
 	return strings.Join(parts, "")
 }
 
+func fingerprintIndexes(publicKey []byte) [fingerprintLength]int {
+	hash := sha256.Sum256(publicKey)
+	chunk := len(hash) / fingerprintLength
+
+	var indexes [fingerprintLength]int
+	for i := range indexes {
+		value := binary.BigEndian.Uint64(hash[i*chunk : (i+1)*chunk])
+		indexes[i] = int(value % uint64(len(fingerprintEmojis)))
+	}
+	return indexes
+}
+
 func stableServerID(publicKey []byte) string {
 	hash := sha256.Sum256(publicKey)
 	return "srv-" + hex.EncodeToString(hash[:8])
@@ -704,7 +993,11 @@ func resolveDatabasePath(cfg config.Config) string {
 	return filepath.Join(cfg.DataDir, raw)
 }
 
-func loadOrCreateIdentity(db *sql.DB) (identityRecord, error) {
+func loadOrCreateIdentity(db *sql.DB, cfg config.Config) (identityRecord, error) {
+	if cfg.IdentityKeyFile != "" || cfg.IdentityPrivateKey != "" {
+		return loadExternalIdentity(db, cfg)
+	}
+
 	var identity identityRecord
 
 	err := db.QueryRow(`SELECT public_key, private_key FROM server_identity WHERE id = 1`).Scan(&identity.PublicKey, &identity.PrivateKey)
@@ -737,6 +1030,9 @@ func loadOrCreateIdentity(db *sql.DB) (identityRecord, error) {
 	if _, err := decodePublicKey(identity.PublicKey); err != nil {
 		return identityRecord{}, fmt.Errorf("invalid persisted server public key: %w", err)
 	}
+	if identity.PrivateKey == "" {
+		return identityRecord{}, errors.New("server private key is stored externally; set IDENTITY_KEY_FILE or IDENTITY_PRIVATE_KEY")
+	}
 	privateKey, err := decodePrivateKey(identity.PrivateKey)
 	if err != nil {
 		return identityRecord{}, fmt.Errorf("invalid persisted server private key: %w", err)
@@ -754,18 +1050,12 @@ func loadOrCreateServerConfig(path, defaultServerName string) (serverConfigFile,
 		if err := readJSON(path, &cfg); err != nil {
 			return serverConfigFile{}, fmt.Errorf("load server config: %w", err)
 		}
-		if strings.TrimSpace(cfg.ServerName) == "" {
-			return serverConfigFile{}, errors.New("server config has empty serverName")
-		}
-		if len(cfg.Channels) == 0 {
-			return serverConfigFile{}, errors.New("server config has no channels")
-		}
-		admins, err := normalizePublicKeys(cfg.AdminPublicKeys)
+		cfg.Channels = repairLegacyChannels(cfg.Channels)
+		normalized, err := normalizeServerConfig(cfg)
 		if err != nil {
-			return serverConfigFile{}, fmt.Errorf("invalid adminPublicKeys in server config: %w", err)
+			return serverConfigFile{}, fmt.Errorf("invalid server config: %w", err)
 		}
-		cfg.AdminPublicKeys = admins
-		return cfg, nil
+		return normalized, nil
 	}
 
 	cfg := serverConfigFile{
@@ -885,6 +1175,8 @@ func randomHex(size int) (string, error) {
 	return hex.EncodeToString(raw), nil
 }
 
+const fingerprintLength = 4
+
 var fingerprintEmojis = []string{
 	"😀", "😎", "🚀", "🌈", "🔥", "🧩", "🎯", "🎧",
 	"🛰️", "🛡️", "🌊", "🍀", "🧠", "🌙", "⚡", "🧭",
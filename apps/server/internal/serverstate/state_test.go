@@ -0,0 +1,116 @@
+package serverstate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+type testMember struct {
+	PublicKey    string
+	PrivateKey   ed25519.PrivateKey
+	SessionToken string
+}
+
+func newTestState(t *testing.T, configure func(*config.Config)) *State {
+	t.Helper()
+
+	cfg := config.Config{
+		ServerName:          "Test Server",
+		DataDir:             t.TempDir(),
+		ServerPublicBaseURL: "http://localhost:8080",
+		SQLiteBusyTimeoutMS: 5000,
+		SQLiteCacheSize:     -2000,
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	state, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	t.Cleanup(func() { _ = state.db.Close() })
+	return state
+}
+
+func connectTestMember(t *testing.T, s *State, displayName string) testMember {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate client keypair: %v", err)
+	}
+
+	return reconnectTestMember(t, s, testMember{PublicKey: base64.StdEncoding.EncodeToString(pub), PrivateKey: priv}, displayName)
+}
+
+// reconnectTestMember runs a fresh invite and handshake for member's key.
+func reconnectTestMember(t *testing.T, s *State, member testMember, displayName string) testMember {
+	t.Helper()
+
+	publicKey, priv := member.PublicKey, member.PrivateKey
+	invite, err := s.CreateInvite(publicKey, "test", nil, false)
+	if err != nil {
+		t.Fatalf("failed to create invite: %v", err)
+	}
+	begin, err := s.BeginConnect(invite.InviteID)
+	if err != nil {
+		t.Fatalf("failed to begin connect: %v", err)
+	}
+	challenge, err := base64.StdEncoding.DecodeString(begin.Challenge)
+	if err != nil {
+		t.Fatalf("invalid challenge encoding: %v", err)
+	}
+	hash := SignaturePayloadHash(challenge, invite.InviteID, begin.ServerFingerprint)
+
+	finish, err := s.FinishConnect(FinishRequest{
+		InviteID:        invite.InviteID,
+		ClientPublicKey: publicKey,
+		Challenge:       begin.Challenge,
+		Signature:       base64.StdEncoding.EncodeToString(ed25519.Sign(priv, hash[:])),
+		ClientInfo:      ClientInfo{DisplayName: displayName},
+	})
+	if err != nil {
+		t.Fatalf("failed to finish connect: %v", err)
+	}
+
+	return testMember{PublicKey: publicKey, PrivateKey: priv, SessionToken: finish.SessionToken}
+}
+
+func makeTestAdmin(t *testing.T, s *State, member testMember) {
+	t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serverCfg.AdminPublicKeys = append(s.serverCfg.AdminPublicKeys, member.PublicKey)
+}
+
+func requireAPIErrorCode(t *testing.T, err error, code string) *APIError {
+	t.Helper()
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected API error %q, got %v", code, err)
+	}
+	if apiErr.Code != code {
+		t.Fatalf("unexpected error code: got=%q want=%q", apiErr.Code, code)
+	}
+	return apiErr
+}
+
+func intPointer(value int) *int {
+	return &value
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+func boolPointer(value bool) *bool {
+	return &value
+}
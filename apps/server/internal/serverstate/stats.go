@@ -0,0 +1,179 @@
+package serverstate
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	defaultChannelStreamBuffer = 32
+	channelActivityCacheTTL    = 5 * time.Second
+)
+
+type Stats struct {
+	Streams    StreamStats    `json:"streams"`
+	Websockets WebsocketStats `json:"websockets"`
+}
+
+type WebsocketStats struct {
+	Active int `json:"active"`
+	Max    int `json:"max"`
+}
+
+type StreamStats struct {
+	BufferSize  int                  `json:"bufferSize"`
+	Subscribers int                  `json:"subscribers"`
+	Channels    []ChannelStreamStats `json:"channels"`
+}
+
+type ChannelStreamStats struct {
+	ChannelID     string `json:"channelId"`
+	Subscribers   int    `json:"subscribers"`
+	DroppedEvents uint64 `json:"droppedEvents"`
+}
+
+// Stats reports in-memory runtime counters. Dropped events count broadcasts that
+// were discarded because a subscriber's buffer was full.
+func (s *State) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byChannel := make(map[string]*ChannelStreamStats)
+	channelStats := func(channelID string) *ChannelStreamStats {
+		stats, ok := byChannel[channelID]
+		if !ok {
+			stats = &ChannelStreamStats{ChannelID: channelID}
+			byChannel[channelID] = stats
+		}
+		return stats
+	}
+
+	total := 0
+	for channelID, streams := range s.streams {
+		channelStats(channelID).Subscribers = len(streams)
+		total += len(streams)
+	}
+	for channelID, dropped := range s.streamDrops {
+		channelStats(channelID).DroppedEvents = dropped
+	}
+
+	channels := make([]ChannelStreamStats, 0, len(byChannel))
+	for _, stats := range byChannel {
+		channels = append(channels, *stats)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].ChannelID < channels[j].ChannelID })
+
+	return Stats{
+		Streams: StreamStats{
+			BufferSize:  s.streamBufferSize(),
+			Subscribers: total,
+			Channels:    channels,
+		},
+		Websockets: WebsocketStats{
+			Active: s.wsConnections,
+			Max:    s.cfg.MaxWSConnections,
+		},
+	}
+}
+
+type ChannelActivity struct {
+	ChannelID      string  `json:"channelId"`
+	Type           string  `json:"type"`
+	Name           string  `json:"name"`
+	MessageCount   int     `json:"messageCount"`
+	LastActivityAt *string `json:"lastActivityAt"`
+	Participants   *int    `json:"participants,omitempty"`
+}
+
+// ChannelActivity lists every configured channel with its message count, last
+// activity and, for voice channels, current participants, most recently active
+// first. Results are cached for a few seconds since the dashboard polls it.
+func (s *State) ChannelActivity() ([]ChannelActivity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.channelActivity != nil && now.Sub(s.channelActivityAt) < channelActivityCacheTTL {
+		return append([]ChannelActivity(nil), s.channelActivity...), nil
+	}
+
+	if err := s.cleanupVoicePresenceLocked(); err != nil {
+		return nil, err
+	}
+
+	type aggregate struct {
+		count int
+		last  sql.NullString
+	}
+	queryAggregates := func(query string) (map[string]aggregate, error) {
+		rows, err := s.db.Query(query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		result := make(map[string]aggregate)
+		for rows.Next() {
+			var channelID string
+			var row aggregate
+			if err := rows.Scan(&channelID, &row.count, &row.last); err != nil {
+				return nil, err
+			}
+			result[channelID] = row
+		}
+		return result, rows.Err()
+	}
+
+	messages, err := queryAggregates(`SELECT channel_id, COUNT(*), MAX(created_at) FROM messages GROUP BY channel_id`)
+	if err != nil {
+		return nil, fmt.Errorf("query message activity: %w", err)
+	}
+	voice, err := queryAggregates(`SELECT channel_id, COUNT(*), MAX(last_seen_at) FROM voice_presence GROUP BY channel_id`)
+	if err != nil {
+		return nil, fmt.Errorf("query voice activity: %w", err)
+	}
+
+	activity := make([]ChannelActivity, 0, len(s.serverCfg.Channels))
+	for _, channel := range s.serverCfg.Channels {
+		entry := ChannelActivity{
+			ChannelID:    channel.ID,
+			Type:         channel.Type,
+			Name:         channel.Name,
+			MessageCount: messages[channel.ID].count,
+		}
+		last := messages[channel.ID].last
+		if channel.Type == "voice" {
+			participants := voice[channel.ID].count
+			entry.Participants = &participants
+			if presence := voice[channel.ID].last; presence.Valid && (!last.Valid || presence.String > last.String) {
+				last = presence
+			}
+		}
+		if last.Valid {
+			lastActivityAt := last.String
+			entry.LastActivityAt = &lastActivityAt
+		}
+		activity = append(activity, entry)
+	}
+
+	sort.SliceStable(activity, func(i, j int) bool {
+		a, b := activity[i].LastActivityAt, activity[j].LastActivityAt
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a > *b
+	})
+
+	s.channelActivity = activity
+	s.channelActivityAt = now
+	return append([]ChannelActivity(nil), activity...), nil
+}
+
+func (s *State) streamBufferSize() int {
+	if s.cfg.ChannelStreamBuffer <= 0 {
+		return defaultChannelStreamBuffer
+	}
+	return s.cfg.ChannelStreamBuffer
+}
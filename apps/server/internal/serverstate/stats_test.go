@@ -0,0 +1,92 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestStatsCountsDroppedEventsPerChannel(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.ChannelStreamBuffer = 1 })
+	member := connectTestMember(t, s, "member")
+
+	_, cancel, err := s.SubscribeChannelEvents(member.SessionToken, "general")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.CreateMessage(member.SessionToken, "general", "hello"); err != nil {
+			t.Fatalf("create message failed: %v", err)
+		}
+	}
+
+	stats := s.Stats()
+	if stats.Streams.BufferSize != 1 || stats.Streams.Subscribers != 1 {
+		t.Fatalf("unexpected stream stats: %+v", stats.Streams)
+	}
+	if len(stats.Streams.Channels) != 1 || stats.Streams.Channels[0].DroppedEvents != 2 {
+		t.Fatalf("expected 2 dropped events on general, got %+v", stats.Streams.Channels)
+	}
+}
+
+func TestChannelActivityIncludesEmptyChannels(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	for i := 0; i < 2; i++ {
+		if _, err := s.CreateMessage(member.SessionToken, "general", "hello"); err != nil {
+			t.Fatalf("create message failed: %v", err)
+		}
+	}
+
+	activity, err := s.ChannelActivity()
+	if err != nil {
+		t.Fatalf("channel activity failed: %v", err)
+	}
+	if len(activity) != len(s.serverCfg.Channels) {
+		t.Fatalf("expected every configured channel, got %+v", activity)
+	}
+	if activity[0].ChannelID != "general" || activity[0].MessageCount != 2 || activity[0].LastActivityAt == nil {
+		t.Fatalf("expected general first with 2 messages, got %+v", activity[0])
+	}
+	for _, entry := range activity[1:] {
+		if entry.LastActivityAt != nil {
+			t.Fatalf("expected idle channel without activity, got %+v", entry)
+		}
+		if entry.Type == "voice" && (entry.Participants == nil || *entry.Participants != 0) {
+			t.Fatalf("expected voice channel participant count, got %+v", entry)
+		}
+	}
+}
+
+func TestWebsocketSlotsRejectPastLimit(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.MaxWSConnections = 2 })
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := s.AcquireWebsocketSlot()
+		if err != nil {
+			t.Fatalf("slot %d should be granted: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+
+	_, err := s.AcquireWebsocketSlot()
+	apiErr := requireAPIErrorCode(t, err, "too_many_connections")
+	if apiErr.Status != 503 {
+		t.Fatalf("unexpected status: got=%d want=503", apiErr.Status)
+	}
+	if stats := s.Stats(); stats.Websockets.Active != 2 || stats.Websockets.Max != 2 {
+		t.Fatalf("unexpected websocket stats: %+v", stats.Websockets)
+	}
+
+	releases[0]()
+	releases[0]()
+	if stats := s.Stats(); stats.Websockets.Active != 1 {
+		t.Fatalf("double release must not free two slots: %+v", stats.Websockets)
+	}
+	if _, err := s.AcquireWebsocketSlot(); err != nil {
+		t.Fatalf("released slot should be reusable: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package serverstate
+
+// TypingIndicator tells channel subscribers that a member is composing a message.
+type TypingIndicator struct {
+	PublicKey   string `json:"publicKey"`
+	DisplayName string `json:"displayName"`
+}
+
+// typingIndicatorsEnabled reports whether a text channel broadcasts typing
+// indicators; they are on unless the channel turns them off.
+func typingIndicatorsEnabled(channel Channel) bool {
+	return channel.TypingIndicators == nil || *channel.TypingIndicators
+}
+
+// NotifyTyping broadcasts a typing indicator to the channel's stream
+// subscribers. Indicators are not stored. In channels with typingIndicators
+// turned off the call still succeeds but nothing is broadcast, so clients do
+// not need to special-case the error.
+func (s *State) NotifyTyping(sessionToken, channelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureTextChannelLocked(channelID); err != nil {
+		return err
+	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, true); err != nil {
+		return err
+	}
+
+	channel, _ := s.channelLocked(channelID)
+	if !typingIndicatorsEnabled(channel) {
+		return nil
+	}
+	s.broadcastChannelEventLocked(channel.ID, ChannelEvent{
+		Type:   "typing",
+		Typing: &TypingIndicator{PublicKey: identity.PublicKey, DisplayName: identity.DisplayName},
+	})
+	return nil
+}
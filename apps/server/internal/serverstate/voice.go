@@ -11,6 +11,9 @@ import (
 const (
 	voicePresenceTTL    = 30 * time.Second
 	voicePresenceMaxLag = 5 * time.Second
+	// voiceCleanupInterval bounds how often heartbeats sweep stale presence.
+	voiceCleanupInterval = 5 * time.Second
+	maxVoiceStatusLength = 64
 )
 
 type VoiceParticipant struct {
@@ -24,19 +27,61 @@ type VoiceParticipant struct {
 	CameraEnabled      bool   `json:"cameraEnabled"`
 	ScreenEnabled      bool   `json:"screenEnabled"`
 	ScreenAudioEnabled bool   `json:"screenAudioEnabled"`
+	Status             string `json:"status"`
 }
 
+// VoiceChannelState is either the full roster (Full) or, when requested with a
+// since cursor, the participants seen at or after it plus the keys that Departed.
+// Clients pass ServerTime back as the next cursor.
 type VoiceChannelState struct {
 	ChannelID    string             `json:"channelId"`
 	Participants []VoiceParticipant `json:"participants"`
+	Full         bool               `json:"full"`
+	Departed     []string           `json:"departed,omitempty"`
+	ServerTime   string             `json:"serverTime"`
+}
+
+// VoiceParticipantSummary is the reduced participant shape for clients that only
+// render a roster; a participant is muted when it publishes no audio streams.
+type VoiceParticipantSummary struct {
+	PublicKey   string `json:"publicKey"`
+	DisplayName string `json:"displayName"`
+	Muted       bool   `json:"muted"`
+}
+
+type VoiceChannelSummary struct {
+	ChannelID    string                    `json:"channelId"`
+	Participants []VoiceParticipantSummary `json:"participants"`
+	Full         bool                      `json:"full"`
+	Departed     []string                  `json:"departed,omitempty"`
+	ServerTime   string                    `json:"serverTime"`
+}
+
+func (state VoiceChannelState) Summary() VoiceChannelSummary {
+	participants := make([]VoiceParticipantSummary, 0, len(state.Participants))
+	for _, participant := range state.Participants {
+		participants = append(participants, VoiceParticipantSummary{
+			PublicKey:   participant.PublicKey,
+			DisplayName: participant.DisplayName,
+			Muted:       participant.AudioStreams == 0,
+		})
+	}
+	return VoiceChannelSummary{
+		ChannelID:    state.ChannelID,
+		Participants: participants,
+		Full:         state.Full,
+		Departed:     state.Departed,
+		ServerTime:   state.ServerTime,
+	}
 }
 
 type VoicePresenceUpdate struct {
-	AudioStreams       int  `json:"audioStreams"`
-	VideoStreams       int  `json:"videoStreams"`
-	CameraEnabled      bool `json:"cameraEnabled"`
-	ScreenEnabled      bool `json:"screenEnabled"`
-	ScreenAudioEnabled bool `json:"screenAudioEnabled"`
+	AudioStreams       int    `json:"audioStreams"`
+	VideoStreams       int    `json:"videoStreams"`
+	CameraEnabled      bool   `json:"cameraEnabled"`
+	ScreenEnabled      bool   `json:"screenEnabled"`
+	ScreenAudioEnabled bool   `json:"screenAudioEnabled"`
+	Status             string `json:"status"`
 }
 
 type VoiceJoinContext struct {
@@ -49,6 +94,10 @@ func (s *State) BeginVoiceJoin(sessionToken, channelID string) (VoiceJoinContext
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.ensureWritableLocked(); err != nil {
+		return VoiceJoinContext{}, err
+	}
+
 	identity, err := s.authenticateSessionLocked(sessionToken)
 	if err != nil {
 		return VoiceJoinContext{}, err
@@ -56,6 +105,9 @@ func (s *State) BeginVoiceJoin(sessionToken, channelID string) (VoiceJoinContext
 	if err := s.ensureVoiceChannelLocked(channelID); err != nil {
 		return VoiceJoinContext{}, err
 	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return VoiceJoinContext{}, err
+	}
 
 	if err := s.cleanupVoicePresenceLocked(); err != nil {
 		return VoiceJoinContext{}, err
@@ -75,7 +127,7 @@ func (s *State) BeginVoiceJoin(sessionToken, channelID string) (VoiceJoinContext
 	return VoiceJoinContext{
 		Identity:  identity,
 		ChannelID: channelID,
-		RoomName:  VoiceRoomName(s.serverID, channelID),
+		RoomName:  VoiceRoomName(s.cfg.LiveKitRoomPrefix, s.serverID, channelID),
 	}, nil
 }
 
@@ -90,12 +142,15 @@ func (s *State) TouchVoicePresence(sessionToken, channelID string, update VoiceP
 	if err := s.ensureVoiceChannelLocked(channelID); err != nil {
 		return err
 	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return err
+	}
 
-	if err := s.cleanupVoicePresenceLocked(); err != nil {
+	if err := s.sweepVoicePresenceLocked(); err != nil {
 		return err
 	}
 
-	if err := s.upsertVoicePresenceLocked(identity, channelID, clampVoicePresenceUpdate(update)); err != nil {
+	if err := s.upsertVoicePresenceLocked(identity, channelID, clampVoicePresenceUpdate(update, s.cfg.MaxAudioStreams, s.cfg.MaxVideoStreams)); err != nil {
 		return err
 	}
 	return nil
@@ -120,21 +175,33 @@ func (s *State) LeaveVoiceChannel(sessionToken string) error {
 	return nil
 }
 
-func (s *State) GetVoiceChannelState(sessionToken, channelID string) (VoiceChannelState, error) {
+// GetVoiceChannelState returns the channel roster. A zero since returns the full
+// roster; otherwise see applyVoiceDeltaLocked for the delta contract. With
+// VOICE_JOIN_GRACE set, participants who joined less than that long ago are
+// left out, so brief misclicks do not flicker in rosters. The caller still sees
+// itself, but only in its own response: the shared roster that departures are
+// derived from must not depend on who asked.
+func (s *State) GetVoiceChannelState(sessionToken, channelID string, since time.Time) (VoiceChannelState, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, err := s.authenticateSessionLocked(sessionToken); err != nil {
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
 		return VoiceChannelState{}, err
 	}
 	if err := s.ensureVoiceChannelLocked(channelID); err != nil {
 		return VoiceChannelState{}, err
 	}
+	if err := s.ensureChannelAccessLocked(identity, channelID, false); err != nil {
+		return VoiceChannelState{}, err
+	}
 
 	if err := s.cleanupVoicePresenceLocked(); err != nil {
 		return VoiceChannelState{}, err
 	}
 
+	now := time.Now()
+	cutoff := s.voiceJoinGraceCutoff(now)
 	rows, err := s.db.Query(`
 		SELECT
 			client_public_key,
@@ -146,32 +213,195 @@ func (s *State) GetVoiceChannelState(sessionToken, channelID string) (VoiceChann
 			video_streams,
 			camera_enabled,
 			screen_enabled,
-			screen_audio_enabled
+			screen_audio_enabled,
+			status
 		FROM voice_presence
-		WHERE channel_id = ?
+		WHERE channel_id = ? AND (joined_at <= ? OR client_public_key = ?)
 		ORDER BY joined_at ASC
-	`, channelID)
+	`, channelID, cutoff, identity.PublicKey)
 	if err != nil {
 		return VoiceChannelState{}, fmt.Errorf("query voice presence: %w", err)
 	}
 	defer rows.Close()
 
+	var own *VoiceParticipant
 	participants := make([]VoiceParticipant, 0, 8)
 	for rows.Next() {
 		participant, err := scanVoiceParticipant(rows)
 		if err != nil {
 			return VoiceChannelState{}, err
 		}
+		// Only the caller's row can still be within the grace period here.
+		if participant.JoinedAt > cutoff {
+			own = &participant
+			continue
+		}
 		participants = append(participants, participant)
 	}
 	if err := rows.Err(); err != nil {
 		return VoiceChannelState{}, fmt.Errorf("iterate voice presence rows: %w", err)
 	}
 
-	return VoiceChannelState{
+	state := VoiceChannelState{
 		ChannelID:    channelID,
 		Participants: participants,
-	}, nil
+	}
+	s.applyVoiceDeltaLocked(&state, since, now)
+	appendOwnVoiceParticipant(&state, own, since)
+	return state, nil
+}
+
+// appendOwnVoiceParticipant adds the caller's row, kept out of the shared roster
+// during VOICE_JOIN_GRACE, to a response already reduced by
+// applyVoiceDeltaLocked. Deltas only include it when it changed since the cursor.
+func appendOwnVoiceParticipant(state *VoiceChannelState, own *VoiceParticipant, since time.Time) {
+	if own == nil {
+		return
+	}
+	if !state.Full {
+		lastSeenAt, err := time.Parse(time.RFC3339, own.LastSeenAt)
+		if err == nil && lastSeenAt.Before(since.UTC().Truncate(time.Second)) {
+			return
+		}
+	}
+	state.Participants = append(state.Participants, *own)
+}
+
+// ListVoiceChannelStates returns the full roster of every voice channel the
+// caller can access, keyed by channel ID, from a single presence query. The
+// VOICE_JOIN_GRACE rule matches GetVoiceChannelState. Channels the caller may
+// not read are left out; any other failure fails the whole request.
+func (s *State) ListVoiceChannelStates(sessionToken string) (map[string]VoiceChannelState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cleanupVoicePresenceLocked(); err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]VoiceChannelState)
+	for _, channel := range s.serverCfg.Channels {
+		if channel.Type != "voice" {
+			continue
+		}
+		if err := s.ensureChannelAccessLocked(identity, channel.ID, false); err != nil {
+			if isAPIErrorCode(err, "channel_forbidden") {
+				continue
+			}
+			return nil, err
+		}
+		states[channel.ID] = VoiceChannelState{
+			ChannelID:    channel.ID,
+			Participants: make([]VoiceParticipant, 0),
+		}
+	}
+
+	now := time.Now()
+	cutoff := s.voiceJoinGraceCutoff(now)
+	rows, err := s.db.Query(`
+		SELECT
+			client_public_key,
+			channel_id,
+			display_name,
+			joined_at,
+			last_seen_at,
+			audio_streams,
+			video_streams,
+			camera_enabled,
+			screen_enabled,
+			screen_audio_enabled,
+			status
+		FROM voice_presence
+		WHERE joined_at <= ? OR client_public_key = ?
+		ORDER BY channel_id ASC, joined_at ASC
+	`, cutoff, identity.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("query voice presence: %w", err)
+	}
+	defer rows.Close()
+
+	var own *VoiceParticipant
+	for rows.Next() {
+		participant, err := scanVoiceParticipant(rows)
+		if err != nil {
+			return nil, err
+		}
+		state, ok := states[participant.ChannelID]
+		if !ok {
+			continue
+		}
+		// As in GetVoiceChannelState, the caller's row within the grace period
+		// stays out of the shared roster.
+		if participant.JoinedAt > cutoff {
+			own = &participant
+			continue
+		}
+		state.Participants = append(state.Participants, participant)
+		states[participant.ChannelID] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate voice presence rows: %w", err)
+	}
+
+	for channelID, state := range states {
+		s.applyVoiceDeltaLocked(&state, time.Time{}, now)
+		if own != nil && own.ChannelID == channelID {
+			appendOwnVoiceParticipant(&state, own, time.Time{})
+		}
+		states[channelID] = state
+	}
+	return states, nil
+}
+
+func (s *State) GetOwnVoiceState(sessionToken string) (VoiceParticipant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.authenticateSessionLocked(sessionToken)
+	if err != nil {
+		return VoiceParticipant{}, err
+	}
+
+	if err := s.cleanupVoicePresenceLocked(); err != nil {
+		return VoiceParticipant{}, err
+	}
+
+	row := s.db.QueryRow(`
+		SELECT
+			client_public_key,
+			channel_id,
+			display_name,
+			joined_at,
+			last_seen_at,
+			audio_streams,
+			video_streams,
+			camera_enabled,
+			screen_enabled,
+			screen_audio_enabled,
+			status
+		FROM voice_presence
+		WHERE client_public_key = ?
+	`, identity.PublicKey)
+
+	participant, err := scanVoiceParticipant(row)
+	if isAPIErrorCode(err, "voice_state_not_found") {
+		return VoiceParticipant{}, newAPIError(404, "not_in_voice", "member is not connected to a voice channel")
+	}
+	if err != nil {
+		return VoiceParticipant{}, err
+	}
+	return participant, nil
+}
+
+// voiceJoinGraceCutoff is the latest joined_at still visible to others.
+func (s *State) voiceJoinGraceCutoff(now time.Time) string {
+	grace := time.Duration(s.cfg.VoiceJoinGraceSec) * time.Second
+	return now.UTC().Add(-grace).Format(time.RFC3339)
 }
 
 func (s *State) ensureVoiceChannelLocked(channelID string) error {
@@ -194,13 +424,25 @@ func (s *State) ensureVoiceChannelLocked(channelID string) error {
 }
 
 func (s *State) cleanupVoicePresenceLocked() error {
-	cutoff := time.Now().UTC().Add(-(voicePresenceTTL + voicePresenceMaxLag)).Format(time.RFC3339)
+	now := time.Now().UTC()
+	cutoff := now.Add(-(voicePresenceTTL + voicePresenceMaxLag)).Format(time.RFC3339)
 	if _, err := s.db.Exec(`DELETE FROM voice_presence WHERE last_seen_at < ?`, cutoff); err != nil {
 		return fmt.Errorf("cleanup stale voice presence: %w", err)
 	}
+	s.voiceCleanupAt = now
 	return nil
 }
 
+// sweepVoicePresenceLocked is the heartbeat's cleanup: it runs the sweep at most
+// once per voiceCleanupInterval server-wide. Paths that read presence still call
+// cleanupVoicePresenceLocked directly, so they never see expired entries.
+func (s *State) sweepVoicePresenceLocked() error {
+	if time.Since(s.voiceCleanupAt) < voiceCleanupInterval {
+		return nil
+	}
+	return s.cleanupVoicePresenceLocked()
+}
+
 func (s *State) upsertVoicePresenceLocked(identity SessionIdentity, channelID string, update VoicePresenceUpdate) error {
 	now := time.Now().UTC().Format(time.RFC3339)
 
@@ -215,9 +457,10 @@ func (s *State) upsertVoicePresenceLocked(identity SessionIdentity, channelID st
 			video_streams,
 			camera_enabled,
 			screen_enabled,
-			screen_audio_enabled
+			screen_audio_enabled,
+			status
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(client_public_key) DO UPDATE SET
 			channel_id = excluded.channel_id,
 			display_name = excluded.display_name,
@@ -227,6 +470,7 @@ func (s *State) upsertVoicePresenceLocked(identity SessionIdentity, channelID st
 			camera_enabled = excluded.camera_enabled,
 			screen_enabled = excluded.screen_enabled,
 			screen_audio_enabled = excluded.screen_audio_enabled,
+			status = excluded.status,
 			joined_at = CASE
 				WHEN voice_presence.channel_id = excluded.channel_id THEN voice_presence.joined_at
 				ELSE excluded.joined_at
@@ -242,6 +486,7 @@ func (s *State) upsertVoicePresenceLocked(identity SessionIdentity, channelID st
 		boolToInt(update.CameraEnabled),
 		boolToInt(update.ScreenEnabled),
 		boolToInt(update.ScreenAudioEnabled),
+		update.Status,
 	); err != nil {
 		return fmt.Errorf("upsert voice presence: %w", err)
 	}
@@ -249,24 +494,37 @@ func (s *State) upsertVoicePresenceLocked(identity SessionIdentity, channelID st
 	return nil
 }
 
-func clampVoicePresenceUpdate(update VoicePresenceUpdate) VoicePresenceUpdate {
-	if update.AudioStreams < 0 {
-		update.AudioStreams = 0
-	}
-	if update.VideoStreams < 0 {
-		update.VideoStreams = 0
-	}
-	if update.AudioStreams > 16 {
-		update.AudioStreams = 16
-	}
-	if update.VideoStreams > 16 {
-		update.VideoStreams = 16
+// clampVoicePresenceUpdate bounds the stream counters to MAX_AUDIO_STREAMS and
+// MAX_VIDEO_STREAMS, which clients read from voiceLimits in server info.
+func clampVoicePresenceUpdate(update VoicePresenceUpdate, maxAudioStreams, maxVideoStreams int) VoicePresenceUpdate {
+	update.AudioStreams = min(max(update.AudioStreams, 0), maxAudioStreams)
+	update.VideoStreams = min(max(update.VideoStreams, 0), maxVideoStreams)
+	update.Status = strings.TrimSpace(update.Status)
+	if status := []rune(update.Status); len(status) > maxVoiceStatusLength {
+		update.Status = strings.TrimSpace(string(status[:maxVoiceStatusLength]))
 	}
 	return update
 }
 
-func VoiceRoomName(serverID, channelID string) string {
-	return fmt.Sprintf("%s:%s", strings.TrimSpace(serverID), strings.TrimSpace(channelID))
+// VoiceRoomName is the LiveKit room of a voice channel: prefix, server ID and
+// channel ID joined with dashes, e.g. "team-srv-0123abcd-voice-main". Anything
+// outside LiveKit-safe characters becomes a dash, so normalizeChannels rejects
+// voice channel IDs that would share a room.
+func VoiceRoomName(prefix, serverID, channelID string) string {
+	room := sanitizeRoomNamePart(serverID) + "-" + sanitizeRoomNamePart(channelID)
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		room = sanitizeRoomNamePart(prefix) + "-" + room
+	}
+	return room
+}
+
+func sanitizeRoomNamePart(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(value))
 }
 
 func scanVoiceParticipant(scanner messageScanner) (VoiceParticipant, error) {
@@ -287,6 +545,7 @@ func scanVoiceParticipant(scanner messageScanner) (VoiceParticipant, error) {
 		&cameraEnabled,
 		&screenEnabled,
 		&screenAudioEnabled,
+		&participant.Status,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return VoiceParticipant{}, newAPIError(404, "voice_state_not_found", "voice channel state is not available")
@@ -306,3 +565,28 @@ func boolToInt(value bool) int {
 	}
 	return 0
 }
+
+// ClearVoiceChannel removes every participant's presence from a voice channel and
+// returns how many were removed. Pollers see them as departures in the next
+// delta. Participants keep their LiveKit connection until their client reacts,
+// since the server holds no room-service credentials to evict them.
+func (s *State) ClearVoiceChannel(channelID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channelID = strings.TrimSpace(channelID)
+	if err := s.ensureVoiceChannelLocked(channelID); err != nil {
+		return 0, err
+	}
+
+	result, err := s.db.Exec(`DELETE FROM voice_presence WHERE channel_id = ?`, channelID)
+	if err != nil {
+		return 0, fmt.Errorf("clear voice presence: %w", err)
+	}
+	cleared, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check voice presence delete result: %w", err)
+	}
+	s.channelActivity = nil
+	return int(cleared), nil
+}
@@ -0,0 +1,286 @@
+package serverstate
+
+import (
+	"testing"
+	"time"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestClampVoicePresenceUpdateUsesConfiguredCaps(t *testing.T) {
+	update := clampVoicePresenceUpdate(VoicePresenceUpdate{AudioStreams: 9, VideoStreams: -3}, 4, 2)
+	if update.AudioStreams != 4 || update.VideoStreams != 0 {
+		t.Fatalf("unexpected clamp result: %+v", update)
+	}
+
+	update = clampVoicePresenceUpdate(VoicePresenceUpdate{AudioStreams: 3, VideoStreams: 2}, 4, 2)
+	if update.AudioStreams != 3 || update.VideoStreams != 2 {
+		t.Fatalf("values within the caps must be kept: %+v", update)
+	}
+}
+
+func TestTouchVoicePresenceDebouncesCleanup(t *testing.T) {
+	s := newTestState(t, nil)
+	stale := connectTestMember(t, s, "stale")
+	active := connectTestMember(t, s, "active")
+
+	if err := s.TouchVoicePresence(stale.SessionToken, "voice-main", VoicePresenceUpdate{}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+	expired := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	if _, err := s.db.Exec(`UPDATE voice_presence SET last_seen_at = ? WHERE client_public_key = ?`, expired, stale.PublicKey); err != nil {
+		t.Fatalf("expire presence failed: %v", err)
+	}
+
+	countStale := func() int {
+		var count int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM voice_presence WHERE client_public_key = ?`, stale.PublicKey).Scan(&count); err != nil {
+			t.Fatalf("count presence failed: %v", err)
+		}
+		return count
+	}
+
+	if err := s.TouchVoicePresence(active.SessionToken, "voice-main", VoicePresenceUpdate{AudioStreams: 1}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+	if countStale() != 1 {
+		t.Fatal("a touch right after a sweep must not sweep again")
+	}
+
+	s.voiceCleanupAt = time.Now().Add(-voiceCleanupInterval)
+	if err := s.TouchVoicePresence(active.SessionToken, "voice-main", VoicePresenceUpdate{AudioStreams: 1}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+	if countStale() != 0 {
+		t.Fatal("expected the sweep to run once the interval elapsed")
+	}
+}
+
+func TestClearVoiceChannelRemovesEveryParticipant(t *testing.T) {
+	s := newTestState(t, nil)
+	first := connectTestMember(t, s, "first")
+	second := connectTestMember(t, s, "second")
+	elsewhere := connectTestMember(t, s, "elsewhere")
+
+	for _, member := range []testMember{first, second} {
+		if err := s.TouchVoicePresence(member.SessionToken, "voice-main", VoicePresenceUpdate{AudioStreams: 1}); err != nil {
+			t.Fatalf("touch failed: %v", err)
+		}
+	}
+	if err := s.TouchVoicePresence(elsewhere.SessionToken, "voice-afk", VoicePresenceUpdate{}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+	before, err := s.GetVoiceChannelState(first.SessionToken, "voice-main", time.Time{})
+	if err != nil {
+		t.Fatalf("get voice state failed: %v", err)
+	}
+
+	cleared, err := s.ClearVoiceChannel("voice-main")
+	if err != nil {
+		t.Fatalf("clear voice channel failed: %v", err)
+	}
+	if cleared != 2 {
+		t.Fatalf("expected 2 participants cleared, got %d", cleared)
+	}
+
+	since, err := time.Parse(time.RFC3339, before.ServerTime)
+	if err != nil {
+		t.Fatalf("parse server time failed: %v", err)
+	}
+	delta, err := s.GetVoiceChannelState(first.SessionToken, "voice-main", since)
+	if err != nil {
+		t.Fatalf("get voice state failed: %v", err)
+	}
+	if len(delta.Participants) != 0 || len(delta.Departed) != 2 {
+		t.Fatalf("expected both participants to show as departed, got %+v", delta)
+	}
+	if _, err := s.GetOwnVoiceState(elsewhere.SessionToken); err != nil {
+		t.Fatalf("other voice channels must be untouched: %v", err)
+	}
+
+	_, err = s.ClearVoiceChannel("missing")
+	requireAPIErrorCode(t, err, "channel_not_found")
+}
+
+func TestVoiceJoinGraceHidesNewParticipantsFromOthers(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.VoiceJoinGraceSec = 10 })
+	joiner := connectTestMember(t, s, "joiner")
+	watcher := connectTestMember(t, s, "watcher")
+
+	if err := s.TouchVoicePresence(joiner.SessionToken, "voice-main", VoicePresenceUpdate{}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+
+	state, err := s.GetVoiceChannelState(watcher.SessionToken, "voice-main", time.Time{})
+	if err != nil || len(state.Participants) != 0 {
+		t.Fatalf("new participant must be hidden during the grace period, got %+v, %v", state.Participants, err)
+	}
+	state, err = s.GetVoiceChannelState(joiner.SessionToken, "voice-main", time.Time{})
+	if err != nil || len(state.Participants) != 1 {
+		t.Fatalf("participants must always see themselves, got %+v, %v", state.Participants, err)
+	}
+
+	joinedAt := time.Now().UTC().Add(-11 * time.Second).Format(time.RFC3339)
+	if _, err := s.db.Exec(`UPDATE voice_presence SET joined_at = ? WHERE client_public_key = ?`, joinedAt, joiner.PublicKey); err != nil {
+		t.Fatalf("backdate join failed: %v", err)
+	}
+	state, err = s.GetVoiceChannelState(watcher.SessionToken, "voice-main", time.Time{})
+	if err != nil || len(state.Participants) != 1 || state.Participants[0].PublicKey != joiner.PublicKey {
+		t.Fatalf("participant must appear once the grace period passed, got %+v, %v", state.Participants, err)
+	}
+}
+
+func TestVoiceJoinGraceKeepsDeltasFreeOfFalseDepartures(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.VoiceJoinGraceSec = 10 })
+	joiner := connectTestMember(t, s, "joiner")
+	watcher := connectTestMember(t, s, "watcher")
+
+	initial, err := s.GetVoiceChannelState(watcher.SessionToken, "voice-main", time.Time{})
+	if err != nil {
+		t.Fatalf("get voice state failed: %v", err)
+	}
+	since, err := time.Parse(time.RFC3339, initial.ServerTime)
+	if err != nil {
+		t.Fatalf("invalid server time: %v", err)
+	}
+
+	if err := s.TouchVoicePresence(joiner.SessionToken, "voice-main", VoicePresenceUpdate{}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+	own, err := s.GetVoiceChannelState(joiner.SessionToken, "voice-main", since)
+	if err != nil || len(own.Participants) != 1 || own.Participants[0].PublicKey != joiner.PublicKey {
+		t.Fatalf("joiner must see itself in its delta, got %+v, %v", own, err)
+	}
+
+	delta, err := s.GetVoiceChannelState(watcher.SessionToken, "voice-main", since)
+	if err != nil {
+		t.Fatalf("get voice delta failed: %v", err)
+	}
+	if delta.Full || len(delta.Participants) != 0 || len(delta.Departed) != 0 {
+		t.Fatalf("joiner within the grace period must neither appear nor depart, got %+v", delta)
+	}
+}
+
+func TestListVoiceChannelStatesGroupsEveryChannel(t *testing.T) {
+	s := newTestState(t, nil)
+	first := connectTestMember(t, s, "first")
+	second := connectTestMember(t, s, "second")
+	afk := connectTestMember(t, s, "afk")
+
+	for _, member := range []testMember{first, second} {
+		if err := s.TouchVoicePresence(member.SessionToken, "voice-main", VoicePresenceUpdate{AudioStreams: 1}); err != nil {
+			t.Fatalf("touch failed: %v", err)
+		}
+	}
+	if err := s.TouchVoicePresence(afk.SessionToken, "voice-afk", VoicePresenceUpdate{}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+
+	states, err := s.ListVoiceChannelStates(first.SessionToken)
+	if err != nil {
+		t.Fatalf("list voice states failed: %v", err)
+	}
+	main, afkState := states["voice-main"], states["voice-afk"]
+	if len(main.Participants) != 2 || main.Participants[0].PublicKey != first.PublicKey || main.Participants[1].PublicKey != second.PublicKey {
+		t.Fatalf("unexpected voice-main roster: %+v", main)
+	}
+	if len(afkState.Participants) != 1 || afkState.Participants[0].PublicKey != afk.PublicKey || afkState.ChannelID != "voice-afk" {
+		t.Fatalf("unexpected voice-afk roster: %+v", afkState)
+	}
+	if _, ok := states["general"]; ok {
+		t.Fatal("text channels must not be listed")
+	}
+
+	_, err = s.ListVoiceChannelStates("missing")
+	requireAPIErrorCode(t, err, "invalid_session_token")
+}
+
+func TestListVoiceChannelStatesSkipsOnlyForbiddenChannels(t *testing.T) {
+	s := newTestState(t, nil)
+	member := connectTestMember(t, s, "member")
+	if _, err := s.UpdateChannel("voice-afk", ChannelUpdate{ReadRoles: &[]string{"staff"}}); err != nil {
+		t.Fatalf("restrict channel failed: %v", err)
+	}
+
+	states, err := s.ListVoiceChannelStates(member.SessionToken)
+	if err != nil {
+		t.Fatalf("list voice states failed: %v", err)
+	}
+	if _, ok := states["voice-afk"]; ok {
+		t.Fatal("forbidden channels must be left out")
+	}
+	if _, ok := states["voice-main"]; !ok {
+		t.Fatal("accessible channels must be listed")
+	}
+
+	if _, err := s.db.Exec(`DROP TABLE member_roles`); err != nil {
+		t.Fatalf("drop roles failed: %v", err)
+	}
+	if _, err := s.ListVoiceChannelStates(member.SessionToken); err == nil {
+		t.Fatal("a failed role lookup must fail the request instead of hiding the channel")
+	}
+}
+
+func TestListVoiceChannelStatesKeepsDeltasFreeOfFalseDepartures(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.VoiceJoinGraceSec = 10 })
+	joiner := connectTestMember(t, s, "joiner")
+	watcher := connectTestMember(t, s, "watcher")
+
+	initial, err := s.GetVoiceChannelState(watcher.SessionToken, "voice-main", time.Time{})
+	if err != nil {
+		t.Fatalf("get voice state failed: %v", err)
+	}
+	since, err := time.Parse(time.RFC3339, initial.ServerTime)
+	if err != nil {
+		t.Fatalf("invalid server time: %v", err)
+	}
+
+	if err := s.TouchVoicePresence(joiner.SessionToken, "voice-main", VoicePresenceUpdate{}); err != nil {
+		t.Fatalf("touch failed: %v", err)
+	}
+	states, err := s.ListVoiceChannelStates(joiner.SessionToken)
+	if err != nil {
+		t.Fatalf("list voice states failed: %v", err)
+	}
+	if own := states["voice-main"]; len(own.Participants) != 1 || own.Participants[0].PublicKey != joiner.PublicKey {
+		t.Fatalf("joiner must see itself, got %+v", own)
+	}
+
+	delta, err := s.GetVoiceChannelState(watcher.SessionToken, "voice-main", since)
+	if err != nil {
+		t.Fatalf("get voice delta failed: %v", err)
+	}
+	if len(delta.Participants) != 0 || len(delta.Departed) != 0 {
+		t.Fatalf("joiner within the grace period must neither appear nor depart, got %+v", delta)
+	}
+}
+
+func TestVoiceRoomNameIsLiveKitSafe(t *testing.T) {
+	if room := VoiceRoomName("", "srv-0a1b", "voice:main"); room != "srv-0a1b-voice-main" {
+		t.Fatalf("unexpected room name: %q", room)
+	}
+	if room := VoiceRoomName("team_a", "srv-0a1b", "voice-main"); room != "team_a-srv-0a1b-voice-main" {
+		t.Fatalf("unexpected prefixed room name: %q", room)
+	}
+
+	s := newTestState(t, func(cfg *config.Config) { cfg.LiveKitRoomPrefix = "eu" })
+	member := connectTestMember(t, s, "member")
+	join, err := s.BeginVoiceJoin(member.SessionToken, "voice-main")
+	if err != nil {
+		t.Fatalf("begin voice join failed: %v", err)
+	}
+	if want := "eu-" + s.ServerInfo().ServerID + "-voice-main"; join.RoomName != want {
+		t.Fatalf("expected room %q, got %q", want, join.RoomName)
+	}
+
+	doc := s.ExportConfig()
+	doc.Channels = append(doc.Channels, Channel{ID: "lobby.a", Type: "voice", Name: "lobby"})
+	if _, err := s.ImportConfig(doc); err != nil {
+		t.Fatalf("import config failed: %v", err)
+	}
+	_, err = s.CreateChannel(Channel{ID: "lobby-a", Type: "voice", Name: "lobby"})
+	requireAPIErrorCode(t, err, "channel_exists")
+	if _, err := s.CreateChannel(Channel{ID: "lobby-a", Type: "text", Name: "lobby"}); err != nil {
+		t.Fatalf("text channels have no room and must not conflict: %v", err)
+	}
+}
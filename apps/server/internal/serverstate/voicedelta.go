@@ -0,0 +1,86 @@
+package serverstate
+
+import "time"
+
+// voiceDepartureRetention bounds how far back a `since` cursor can reach before the
+// server falls back to a full roster.
+const voiceDepartureRetention = 2 * time.Minute
+
+// voiceRoster is the last roster computed for a voice channel together with the
+// keys that dropped out of it. Departures are detected lazily by diffing each
+// freshly computed roster against the previous one.
+type voiceRoster struct {
+	trackedSince time.Time
+	members      map[string]struct{}
+	departures   []voiceDeparture
+}
+
+type voiceDeparture struct {
+	PublicKey string
+	At        time.Time
+}
+
+// applyVoiceDeltaLocked records departures for the channel and, when since is set
+// and still covered by the departure log, reduces state to a delta: participants
+// whose lastSeenAt is at or after since plus the keys that left since then.
+func (s *State) applyVoiceDeltaLocked(state *VoiceChannelState, since, now time.Time) {
+	now = now.UTC().Truncate(time.Second)
+	state.ServerTime = now.Format(time.RFC3339)
+
+	current := make(map[string]struct{}, len(state.Participants))
+	for _, participant := range state.Participants {
+		current[participant.PublicKey] = struct{}{}
+	}
+
+	roster, ok := s.voiceRosters[state.ChannelID]
+	if !ok {
+		roster = &voiceRoster{trackedSince: now}
+		s.voiceRosters[state.ChannelID] = roster
+	}
+	for publicKey := range roster.members {
+		if _, present := current[publicKey]; !present {
+			roster.departures = append(roster.departures, voiceDeparture{PublicKey: publicKey, At: now})
+		}
+	}
+	roster.members = current
+
+	cutoff := now.Add(-voiceDepartureRetention)
+	kept := roster.departures[:0]
+	for _, departure := range roster.departures {
+		if !departure.At.Before(cutoff) {
+			kept = append(kept, departure)
+		}
+	}
+	roster.departures = kept
+
+	if since.IsZero() || since.Before(cutoff) || since.Before(roster.trackedSince) {
+		state.Full = true
+		return
+	}
+
+	since = since.UTC().Truncate(time.Second)
+	changed := make([]VoiceParticipant, 0, len(state.Participants))
+	for _, participant := range state.Participants {
+		lastSeenAt, err := time.Parse(time.RFC3339, participant.LastSeenAt)
+		if err != nil || !lastSeenAt.Before(since) {
+			changed = append(changed, participant)
+		}
+	}
+	state.Participants = changed
+
+	departed := make(map[string]struct{})
+	state.Departed = make([]string, 0)
+	for _, departure := range roster.departures {
+		if departure.At.Before(since) {
+			continue
+		}
+		if _, present := current[departure.PublicKey]; present {
+			continue
+		}
+		if _, seen := departed[departure.PublicKey]; seen {
+			continue
+		}
+		departed[departure.PublicKey] = struct{}{}
+		state.Departed = append(state.Departed, departure.PublicKey)
+	}
+}
@@ -0,0 +1,62 @@
+package serverstate
+
+import (
+	"testing"
+	"time"
+)
+
+func voiceStateWith(keys ...string) *VoiceChannelState {
+	state := &VoiceChannelState{ChannelID: "voice-main"}
+	for _, key := range keys {
+		state.Participants = append(state.Participants, VoiceParticipant{PublicKey: key, LastSeenAt: "2026-01-01T00:00:00Z"})
+	}
+	return state
+}
+
+func TestVoiceDeltaReportsDeparturesSinceCursor(t *testing.T) {
+	s := newTestState(t, nil)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first := voiceStateWith("a", "b")
+	s.applyVoiceDeltaLocked(first, time.Time{}, start)
+	if !first.Full || len(first.Participants) != 2 {
+		t.Fatalf("expected full roster without since, got %+v", first)
+	}
+	cursor, err := time.Parse(time.RFC3339, first.ServerTime)
+	if err != nil {
+		t.Fatalf("invalid serverTime: %v", err)
+	}
+
+	second := voiceStateWith("a")
+	second.Participants[0].LastSeenAt = start.Add(10 * time.Second).Format(time.RFC3339)
+	s.applyVoiceDeltaLocked(second, cursor.Add(5*time.Second), start.Add(10*time.Second))
+	if second.Full {
+		t.Fatal("expected a delta response")
+	}
+	if len(second.Participants) != 1 || second.Participants[0].PublicKey != "a" {
+		t.Fatalf("expected only the refreshed participant, got %+v", second.Participants)
+	}
+	if len(second.Departed) != 1 || second.Departed[0] != "b" {
+		t.Fatalf("expected b to be departed, got %v", second.Departed)
+	}
+
+	stale := voiceStateWith("a")
+	s.applyVoiceDeltaLocked(stale, start, start.Add(voiceDepartureRetention+time.Minute))
+	if !stale.Full || len(stale.Departed) != 0 {
+		t.Fatalf("expected full roster for an expired cursor, got %+v", stale)
+	}
+}
+
+func TestVoiceDeltaOmitsRejoinedParticipants(t *testing.T) {
+	s := newTestState(t, nil)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s.applyVoiceDeltaLocked(voiceStateWith("a"), time.Time{}, start)
+	s.applyVoiceDeltaLocked(voiceStateWith(), time.Time{}, start.Add(time.Second))
+
+	rejoined := voiceStateWith("a")
+	s.applyVoiceDeltaLocked(rejoined, start, start.Add(2*time.Second))
+	if len(rejoined.Departed) != 0 {
+		t.Fatalf("rejoined participant must not be reported as departed, got %v", rejoined.Departed)
+	}
+}
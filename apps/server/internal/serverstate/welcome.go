@@ -0,0 +1,76 @@
+package serverstate
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// systemAuthorPublicKey marks server-authored messages. It cannot collide with a
+// member key, which is always base64 of 32 bytes.
+const systemAuthorPublicKey = "system"
+
+const defaultWelcomeTemplate = "Welcome, {displayName}!"
+
+// postWelcomeMessageLocked greets a newly joined member in WELCOME_CHANNEL_ID.
+// Failures are logged rather than returned so they never block the connect flow.
+func (s *State) postWelcomeMessageLocked(displayName string) {
+	channelID := strings.TrimSpace(s.cfg.WelcomeChannelID)
+	if channelID == "" {
+		return
+	}
+	channel, ok := s.channelLocked(channelID)
+	if !ok || channel.Type != "text" {
+		slog.Warn("skipping welcome message: WELCOME_CHANNEL_ID is not a text channel", "channel_id", channelID)
+		return
+	}
+
+	template := s.cfg.WelcomeTemplate
+	if strings.TrimSpace(template) == "" {
+		template = defaultWelcomeTemplate
+	}
+	if _, err := s.postSystemMessageLocked(channel.ID, strings.ReplaceAll(template, "{displayName}", displayName)); err != nil {
+		slog.Warn("failed to post welcome message", "channel_id", channel.ID, "error", err)
+	}
+}
+
+// postSystemMessageLocked stores and broadcasts a message authored by the server.
+func (s *State) postSystemMessageLocked(channelID, contentMarkdown string) (ChannelMessage, error) {
+	content, err := normalizeMessageContent(contentMarkdown, s.messageLengthLimitLocked(channelID))
+	if err != nil {
+		return ChannelMessage{}, err
+	}
+
+	messageID, err := randomHex(16)
+	if err != nil {
+		return ChannelMessage{}, fmt.Errorf("generate message id: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	authorName := s.serverCfg.ServerName
+	message := ChannelMessage{
+		ID:        messageID,
+		ChannelID: channelID,
+		Author: MessageAuthor{
+			DisplayName: authorName,
+			PublicKey:   systemAuthorPublicKey,
+		},
+		ContentMarkdown: content,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		System:          true,
+		MessageType:     MessageTypeSystem,
+	}
+	evicted, err := s.insertMessageLocked(message)
+	if err != nil {
+		return ChannelMessage{}, err
+	}
+
+	s.broadcastChannelEventLocked(channelID, ChannelEvent{
+		Type:    "message.created",
+		Message: &message,
+	})
+	s.broadcastEvictedLocked(channelID, evicted)
+	return message, nil
+}
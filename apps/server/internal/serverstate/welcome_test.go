@@ -0,0 +1,64 @@
+package serverstate
+
+import (
+	"testing"
+
+	"fosscord/apps/server/internal/config"
+)
+
+func TestWelcomeMessagePostedOnceForNewMembers(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) {
+		cfg.WelcomeChannelID = "general"
+		cfg.WelcomeTemplate = "Hi {displayName}, read the rules"
+	})
+	member := connectTestMember(t, s, "alice")
+
+	result, err := s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	if len(result.Messages) != 1 {
+		t.Fatalf("expected 1 welcome message, got %d", len(result.Messages))
+	}
+	welcome := result.Messages[0]
+	if !welcome.System || welcome.Author.PublicKey != systemAuthorPublicKey {
+		t.Fatalf("welcome message must be system-authored, got %+v", welcome)
+	}
+	if welcome.ContentMarkdown != "Hi alice, read the rules" {
+		t.Fatalf("unexpected welcome content: %q", welcome.ContentMarkdown)
+	}
+
+	if _, err := s.CreateMessage(member.SessionToken, "general", "hello"); err != nil {
+		t.Fatalf("create message failed: %v", err)
+	}
+	member = reconnectTestMember(t, s, member, "alice")
+	result, err = s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	welcomes := 0
+	for _, message := range result.Messages {
+		if message.Author.PublicKey == member.PublicKey && message.System {
+			t.Fatal("member messages must not be flagged as system")
+		}
+		if message.System {
+			welcomes++
+		}
+	}
+	if welcomes != 1 {
+		t.Fatalf("reconnecting must not post another welcome, got %d", welcomes)
+	}
+}
+
+func TestWelcomeMessageSkippedForVoiceChannel(t *testing.T) {
+	s := newTestState(t, func(cfg *config.Config) { cfg.WelcomeChannelID = "voice-main" })
+	member := connectTestMember(t, s, "bob")
+
+	result, err := s.ListMessages(member.SessionToken, "general", 10)
+	if err != nil {
+		t.Fatalf("list messages failed: %v", err)
+	}
+	if len(result.Messages) != 0 {
+		t.Fatalf("expected no messages, got %d", len(result.Messages))
+	}
+}
@@ -0,0 +1,177 @@
+// Package webhook delivers channel events to an operator-configured HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Fosscord-Signature"
+	TimestampHeader = "X-Fosscord-Timestamp"
+	EventHeader     = "X-Fosscord-Event"
+	ChannelHeader   = "X-Fosscord-Channel"
+
+	queueSize       = 256
+	maxAttempts     = 4
+	deliveryTimeout = 5 * time.Second
+	shutdownGrace   = 5 * time.Second
+)
+
+// Event is one delivery: Body is sent verbatim as the request body.
+type Event struct {
+	Type      string
+	ChannelID string
+	Body      []byte
+}
+
+// Dispatcher delivers events from a bounded queue on a single goroutine, so a
+// slow or failing receiver never blocks the caller. Events that arrive while the
+// queue is full are dropped.
+type Dispatcher struct {
+	url     string
+	secret  []byte
+	client  *http.Client
+	backoff time.Duration
+
+	queue  chan Event
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewDispatcher(url, secret string) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		url:     url,
+		secret:  []byte(secret),
+		client:  &http.Client{Timeout: deliveryTimeout},
+		backoff: time.Second,
+		queue:   make(chan Event, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Enqueue schedules an event for delivery and reports whether it was accepted.
+func (d *Dispatcher) Enqueue(event Event) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return false
+	}
+	select {
+	case d.queue <- event:
+		return true
+	default:
+		slog.Warn("webhook queue full, dropping event", "event_type", event.Type, "channel_id", event.ChannelID)
+		return false
+	}
+}
+
+// Close stops accepting events and gives queued ones a short grace period to be
+// delivered before abandoning them.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.queue)
+	d.mu.Unlock()
+
+	timer := time.AfterFunc(shutdownGrace, d.cancel)
+	defer timer.Stop()
+	<-d.done
+	d.cancel()
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for event := range d.queue {
+		if d.ctx.Err() != nil {
+			continue
+		}
+		if err := d.deliver(event); err != nil {
+			slog.Warn("webhook delivery failed", "event_type", event.Type, "channel_id", event.ChannelID, "error", err)
+		}
+	}
+}
+
+// deliver retries network errors and 5xx/429 responses with exponential backoff;
+// other 4xx responses mean the receiver rejected the event and are not retried.
+func (d *Dispatcher) deliver(event Event) error {
+	backoff := d.backoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = d.post(event); !retry {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-d.ctx.Done():
+			return d.ctx.Err()
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", maxAttempts, err)
+}
+
+func (d *Dispatcher) post(event Event) (bool, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, d.url, bytes.NewReader(event.Body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(ChannelHeader, event.ChannelID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, timestamp, event.Body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver returned %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("receiver rejected event with %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers recompute
+// it with the shared secret and should reject stale timestamps to stop replays.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
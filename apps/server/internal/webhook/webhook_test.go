@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDispatcherSignsAndRetriesDeliveries(t *testing.T) {
+	var attempts atomic.Int32
+	delivered := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if Sign([]byte("secret"), r.Header.Get(TimestampHeader), body) != r.Header.Get(SignatureHeader)[len("sha256="):] {
+			t.Errorf("signature mismatch for body %s", body)
+		}
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		delivered <- r
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(receiver.URL, "secret")
+	d.backoff = time.Millisecond
+	defer d.Close()
+
+	if !d.Enqueue(Event{Type: "message.created", ChannelID: "general", Body: []byte(`{"type":"message.created"}`)}) {
+		t.Fatal("event was not queued")
+	}
+
+	select {
+	case r := <-delivered:
+		if r.Header.Get(EventHeader) != "message.created" || r.Header.Get(ChannelHeader) != "general" {
+			t.Fatalf("unexpected headers: %v", r.Header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestDispatcherDoesNotRetryRejectedEvents(t *testing.T) {
+	var attempts atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+
+	d := NewDispatcher(receiver.URL, "secret")
+	d.backoff = time.Millisecond
+	d.Enqueue(Event{Type: "message.created", Body: []byte(`{}`)})
+	d.Close()
+
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("expected a single attempt, got %d", got)
+	}
+	if d.Enqueue(Event{Type: "message.created", Body: []byte(`{}`)}) {
+		t.Fatal("closed dispatcher must not accept events")
+	}
+}